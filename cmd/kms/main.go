@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	"fliqt/config"
+	"fliqt/internal/repository"
+	"fliqt/internal/service"
+	"fliqt/internal/util"
+)
+
+var (
+	// tenant to rotate or re-wrap, all tenants are re-wrapped when it's empty.
+	tenantID string
+	// rotate the key-encryption key of the tenant.
+	rotate bool
+	// rewrap data keys which aren't wrapped by the current key-encryption key.
+	rewrap bool
+)
+
+func init() {
+	flag.StringVar(&tenantID, "tenant", "", "tenant ID, eg: -tenant cqanbg8cvavjpljmh7pg")
+	flag.BoolVar(&rotate, "rotate", false, "rotate the key-encryption key of the tenant, eg: -rotate -tenant cqanbg8cvavjpljmh7pg")
+	flag.BoolVar(&rewrap, "rewrap", false, "re-wrap data keys with the current key-encryption keys, eg: -rewrap")
+}
+
+func main() {
+	flag.Parse()
+	cfg := config.NewConfig()
+	logger := util.NewLogger(cfg)
+	ctx := context.Background()
+
+	if !rotate && !rewrap {
+		flag.Usage()
+		return
+	}
+
+	if rotate && tenantID == "" {
+		log.Fatalf("The flag rotate requires the flag tenant")
+	}
+
+	db, err := util.NewGormDB(cfg)
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+
+	kms, err := service.NewKMS(cfg)
+	if err != nil {
+		panic(err)
+	}
+
+	encryptedObjectRepo := repository.NewEncryptedObjectRepository(db, logger)
+	encryptionService := service.NewEncryptionService(kms, encryptedObjectRepo)
+
+	if rotate {
+		keyID, err := kms.RotateKey(ctx, tenantID)
+		if err != nil {
+			log.Fatalf("Could not rotate key: %v", err)
+		}
+		log.Printf("Key of tenant %s is rotated to %s", tenantID, keyID)
+	}
+
+	if rewrap {
+		tenantIDs := []string{tenantID}
+		if tenantID == "" {
+			tenantIDs, err = encryptedObjectRepo.ListTenantIDs(ctx)
+			if err != nil {
+				log.Fatalf("Could not list tenants: %v", err)
+			}
+		}
+
+		// Objects are untouched, only their wrapped data keys are replaced.
+		for _, ID := range tenantIDs {
+			count, err := encryptionService.RewrapDataKeys(ctx, ID)
+			if err != nil {
+				log.Fatalf("Could not re-wrap data keys of tenant %s: %v", ID, err)
+			}
+			log.Printf("%d data keys of tenant %s are re-wrapped", count, ID)
+		}
+	}
+}
@@ -1,13 +1,25 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/gin-gonic/gin"
 
 	"fliqt/config"
+	"fliqt/internal/event"
 	"fliqt/internal/handler"
+	"fliqt/internal/jobs"
+	"fliqt/internal/model"
+	"fliqt/internal/notification"
+	"fliqt/internal/ratelimit"
 	"fliqt/internal/repository"
+	"fliqt/internal/search"
 	"fliqt/internal/service"
 	"fliqt/internal/util"
 )
@@ -15,6 +27,12 @@ import (
 func main() {
 	cfg := config.NewConfig()
 	logger := util.NewLogger(cfg)
+	if err := cfg.Validate(); err != nil {
+		logger.Fatal().Err(err).Msg("invalid config")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
 	if !cfg.Debug {
 		gin.SetMode(gin.ReleaseMode)
@@ -23,35 +41,165 @@ func main() {
 	}
 	app := gin.Default()
 
-	s3PresignClient, err := util.NewS3PresignClient(cfg)
+	s3Client, err := util.NewS3Client(cfg)
 	if err != nil {
-		panic(err)
+		logger.Fatal().Err(err).Msg("failed to create S3 client")
 	}
+	s3PresignClient := util.NewS3PresignClient(s3Client)
 
 	db, err := util.NewGormDB(cfg)
 	if err != nil {
-		panic(err)
+		logger.Fatal().Err(err).Msg("failed to connect to the database")
 	}
 
 	redisClient, err := util.NewClient(cfg)
 	if err != nil {
-		panic(err)
+		logger.Fatal().Err(err).Msg("failed to create Redis client")
+	}
+
+	// Pagination cursors are signed, so clients can't forge positions
+	model.SetCursorSecret(cfg.CursorSecret)
+
+	// Jobs are searched with MySQL unless Bleve is configured, every replica keeps a Bleve index of its own.
+	var searchIndex search.JobSearchIndex = search.NewMySQLJobSearchIndex()
+	var bleveIndex *search.BleveJobSearchIndex
+	// The index is kept in sync from job events by a consumer group of this replica, which outlives restarts so
+	// the index catches up on what it missed meanwhile. The group is created before the index is built, so jobs
+	// written meanwhile are indexed again. An index left behind by a replica whose group is new missed events,
+	// so it's built again.
+	searchIndexGroup := service.SearchIndexGroup(cfg.SearchIndexReplica)
+	if cfg.SearchBackend == search.BackendBleve {
+		created, err := event.CreateGroup(ctx, redisClient, cfg.EventStream, searchIndexGroup)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("failed to create search index consumer group")
+		}
+		if created && cfg.SearchIndexPath != "" {
+			if err := os.RemoveAll(cfg.SearchIndexPath); err != nil {
+				logger.Fatal().Err(err).Msg("failed to remove outdated search index")
+			}
+		}
+
+		bleveIndex, err = search.OpenBleveJobSearchIndex(cfg)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("failed to open search index")
+		}
+		searchIndex = bleveIndex
 	}
 
 	// Initialize repositories
-	jobRepo := repository.NewJobRepository(db, logger)
+	jobRepo := repository.NewJobRepository(db, logger, searchIndex)
 	applicationRepo := repository.NewApplicationRepository(db, logger)
 
+	encryptedObjectRepo := repository.NewEncryptedObjectRepository(db, logger)
+	downloadLogRepo := repository.NewDownloadLogRepository(db, logger)
+	userRepo := repository.NewUserRepository(db, logger)
+	retentionRepo := repository.NewRetentionRepository(db, logger)
+	bulkOperationRepo := repository.NewBulkOperationRepository(db, logger)
+	privacyRepo := repository.NewPrivacyRepository(db, logger)
+	webhookRepo := repository.NewWebhookRepository(db, logger)
+	notificationRepo := repository.NewNotificationRepository(db, logger)
+	metricsRepo := repository.NewMetricsRepository(db, logger)
+
 	// Initialize services
 	authService := service.NewAuthService(db)
-	s3Service := service.NewS3Service(cfg, redisClient, s3PresignClient)
+
+	// Uploaded files are encrypted with SSE-C, their data keys are wrapped by the KMS.
+	var encryptionService service.EncryptionServiceInterface
+	if cfg.EncryptionEnabled {
+		kms, err := service.NewKMS(cfg)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("failed to create KMS")
+		}
+		encryptionService = service.NewEncryptionService(kms, encryptedObjectRepo)
+	}
+
+	s3Service := service.NewS3Service(cfg, redisClient, s3Client, s3PresignClient, encryptionService)
+	watermarkService := service.NewWatermarkService(cfg)
+	retentionService := service.NewRetentionService(cfg, logger, retentionRepo, s3Service)
+	webhookService := service.NewWebhookService(cfg, logger, webhookRepo)
+
+	renderer, err := notification.NewRenderer()
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to load notification templates")
+	}
+	notificationProvider, err := notification.NewProvider(cfg, logger)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to create notification provider")
+	}
+	notificationService := service.NewNotificationService(cfg, logger, userRepo, jobRepo, notificationRepo, renderer, notificationProvider)
+
+	hub := event.NewHub(cfg, redisClient, logger)
+	streamService := service.NewStreamService(cfg, logger, hub)
+
+	privacyService := service.NewPrivacyService(cfg, logger, userRepo, applicationRepo, privacyRepo, retentionRepo, s3Service)
+	healthService := service.NewHealthService(cfg, logger, db, redisClient, s3Client)
 
 	// OpenTelemetry tracing, can be ignored when there's no setup for tracing when developing locally.
-	if err := util.InitTracer(cfg); err != nil {
+	tracerProvider, err := util.InitTracer(cfg)
+	if err != nil {
 		logger.Info().Msgf("Failed to initialize tracer: %v", err)
 	}
 
+	meterProvider, metricsHandler, err := util.InitMeter(cfg)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to initialize meter")
+	}
+
+	// Business KPIs are counted periodically, every replica exposes the same values.
+	businessMetricsService, err := service.NewBusinessMetricsService(cfg, logger, metricsRepo)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to register business metrics")
+	}
+	go businessMetricsService.Run(ctx)
+
+	// Background work runs on cmd/worker, the API only enqueues tasks.
+	queue := jobs.NewQueue(cfg, redisClient)
+	bulkApplicationService := service.NewBulkApplicationService(cfg, logger, userRepo, applicationRepo, bulkOperationRepo, s3Service, queue)
+	jobFacetService := service.NewJobFacetService(cfg, logger, redisClient, jobRepo)
+
+	// Events are pushed to SSE clients of this replica, cmd/worker publishes them to every replica.
+	go func() {
+		if err := hub.Run(ctx); err != nil {
+			logger.Error().Err(err).Msg("event hub stopped")
+		}
+	}()
+
+	// consumerDone is closed once the search index consumer stopped, or right away without one.
+	consumerDone := make(chan struct{})
+	if bleveIndex == nil {
+		close(consumerDone)
+	} else {
+		searchIndexService := service.NewSearchIndexService(logger, jobRepo, bleveIndex)
+		consumer := event.NewConsumer(cfg, redisClient, logger, searchIndexGroup, searchIndexService.Handle)
+		go func() {
+			defer close(consumerDone)
+			if err := consumer.Run(ctx); err != nil {
+				logger.Error().Err(err).Msg("search index consumer stopped")
+			}
+		}()
+
+		documents, err := bleveIndex.DocCount()
+		if err != nil {
+			logger.Fatal().Err(err).Msg("failed to count indexed jobs")
+		}
+		if documents == 0 {
+			count, err := searchIndexService.Rebuild(ctx)
+			if err != nil {
+				logger.Fatal().Err(err).Msg("failed to build search index")
+			}
+			logger.Info().Int("count", count).Msg("search index built")
+		}
+	}
+
+	rateLimiter, err := handler.NewRateLimiter(cfg, logger, ratelimit.NewLimiter(redisClient), authService)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to configure rate limits")
+	}
+
+	app.Use(handler.Tracing())
 	app.Use(handler.Logger(logger))
+	app.Use(handler.Metrics())
+	app.Use(handler.Localization())
 	app.Use(handler.ErrorHandler(logger))
 	app.NoRoute(handler.NotFoundHandler())
 
@@ -60,12 +208,78 @@ func main() {
 		app,
 		logger,
 		jobRepo,
+		jobFacetService,
 		applicationRepo,
+		bulkApplicationService,
+		downloadLogRepo,
+		userRepo,
 		authService,
 		s3Service,
+		watermarkService,
+		retentionService,
+		privacyRepo,
+		privacyService,
+		webhookRepo,
+		webhookService,
+		notificationRepo,
+		notificationService,
+		streamService,
+		queue,
+		healthService,
+		metricsHandler,
+		rateLimiter,
+		redisClient,
 	)
 
-	if err := app.Run(); err != nil {
-		panic(err)
+	srv := &http.Server{
+		Addr:    ":" + cfg.Port,
+		Handler: app,
+	}
+	// Streams never finish on their own, they're ended so clients reconnect to another replica.
+	srv.RegisterOnShutdown(hub.Close)
+
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Fatal().Err(err).Msg("failed to serve")
+		}
+	}()
+
+	logger.Info().Str("addr", srv.Addr).Msg("server started")
+
+	<-ctx.Done()
+	stop()
+	logger.Info().Msg("server stopping")
+
+	// In-flight requests are drained, then everything they could still use is closed.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Error().Err(err).Msg("failed to drain requests")
+	}
+	if tracerProvider != nil {
+		if err := tracerProvider.Shutdown(shutdownCtx); err != nil {
+			logger.Error().Err(err).Msg("failed to flush spans")
+		}
+	}
+	if err := meterProvider.Shutdown(shutdownCtx); err != nil {
+		logger.Error().Err(err).Msg("failed to flush metrics")
+	}
+	// The consumer may still be indexing an event, the index is closed once it's stopped.
+	select {
+	case <-consumerDone:
+	case <-shutdownCtx.Done():
+		logger.Error().Err(shutdownCtx.Err()).Msg("search index consumer didn't stop")
+	}
+	if err := searchIndex.Close(); err != nil {
+		logger.Error().Err(err).Msg("failed to close search index")
+	}
+	if err := redisClient.Close(); err != nil {
+		logger.Error().Err(err).Msg("failed to close Redis client")
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		if err := sqlDB.Close(); err != nil {
+			logger.Error().Err(err).Msg("failed to close database")
+		}
 	}
 }
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+
+	"fliqt/config"
+	"fliqt/internal/event"
+	"fliqt/internal/repository"
+	"fliqt/internal/search"
+	"fliqt/internal/service"
+	"fliqt/internal/util"
+)
+
+var (
+	// path of the index to rebuild, SEARCH_INDEX_PATH when it's empty.
+	path string
+	// replica names the API replica which uses the index, SEARCH_INDEX_REPLICA when it's empty.
+	replica string
+)
+
+func init() {
+	flag.StringVar(&path, "path", "", "path of the Bleve index, eg: -path /tmp/fliqt/jobs.bleve")
+	flag.StringVar(&replica, "replica", "", "SEARCH_INDEX_REPLICA of the API replica which uses the index, eg: -replica api-0")
+}
+
+func main() {
+	flag.Parse()
+	cfg := config.NewConfig()
+	logger := util.NewLogger(cfg)
+	ctx := context.Background()
+
+	if cfg.SearchBackend != search.BackendBleve {
+		log.Printf("Jobs are searched with %s, there's no index to rebuild", cfg.SearchBackend)
+		return
+	}
+	if path != "" {
+		cfg.SearchIndexPath = path
+	}
+	if replica != "" {
+		cfg.SearchIndexReplica = replica
+	}
+
+	db, err := util.NewGormDB(cfg)
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	}()
+
+	// The current index is kept open while the new one is built, so an API replica can't open it meanwhile.
+	// It fails when the index is in use.
+	current, err := search.OpenBleveJobSearchIndex(cfg)
+	if err != nil {
+		log.Fatalf("Could not open the index at %s, stop the API replica which uses it: %v", cfg.SearchIndexPath, err)
+	}
+
+	rebuildCfg := *cfg
+	rebuildCfg.SearchIndexPath = cfg.SearchIndexPath + ".rebuild"
+	if err := os.RemoveAll(rebuildCfg.SearchIndexPath); err != nil {
+		log.Fatalf("Could not remove an earlier rebuild: %v", err)
+	}
+	index, err := search.OpenBleveJobSearchIndex(&rebuildCfg)
+	if err != nil {
+		log.Fatalf("Could not create the index: %v", err)
+	}
+
+	// The consumer group of the replica is created before jobs are indexed, so the replica keeps the index and
+	// indexes jobs written meanwhile when it starts.
+	redisClient, err := util.NewClient(cfg)
+	if err != nil {
+		log.Fatalf("Could not connect to Redis: %v", err)
+	}
+	defer redisClient.Close()
+	if _, err := event.CreateGroup(ctx, redisClient, cfg.EventStream, service.SearchIndexGroup(cfg.SearchIndexReplica)); err != nil {
+		log.Fatalf("Could not create the consumer group: %v", err)
+	}
+
+	searchIndexService := service.NewSearchIndexService(logger, repository.NewJobRepository(db, logger, index), index)
+	count, err := searchIndexService.Rebuild(ctx)
+	if err != nil {
+		log.Fatalf("Could not index jobs: %v", err)
+	}
+	if err := index.Close(); err != nil {
+		log.Fatalf("Could not close the index: %v", err)
+	}
+
+	// The new index replaces the current one
+	if err := current.Close(); err != nil {
+		log.Fatalf("Could not close the current index: %v", err)
+	}
+	if err := os.RemoveAll(cfg.SearchIndexPath); err != nil {
+		log.Fatalf("Could not remove the current index: %v", err)
+	}
+	if err := os.Rename(rebuildCfg.SearchIndexPath, cfg.SearchIndexPath); err != nil {
+		log.Fatalf("Could not replace the current index: %v", err)
+	}
+
+	log.Printf("%d jobs are indexed at %s", count, cfg.SearchIndexPath)
+}
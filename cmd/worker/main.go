@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"fliqt/config"
+	"fliqt/internal/event"
+	"fliqt/internal/jobs"
+	"fliqt/internal/notification"
+	"fliqt/internal/repository"
+	"fliqt/internal/search"
+	"fliqt/internal/service"
+	"fliqt/internal/util"
+)
+
+// The worker runs background work of the API: recurring and enqueued tasks, the outbox relay and
+// consumers of domain events.
+func main() {
+	cfg := config.NewConfig()
+	logger := util.NewLogger(cfg)
+	if err := cfg.Validate(); err != nil {
+		logger.Fatal().Err(err).Msg("invalid config")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	s3Client, err := util.NewS3Client(cfg)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to create S3 client")
+	}
+	s3PresignClient := util.NewS3PresignClient(s3Client)
+
+	db, err := util.NewGormDB(cfg)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to connect to the database")
+	}
+
+	redisClient, err := util.NewClient(cfg)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to create Redis client")
+	}
+
+	// Initialize repositories
+	// The worker doesn't search jobs, Bleve indexes are kept by API replicas.
+	jobRepo := repository.NewJobRepository(db, logger, search.NewMySQLJobSearchIndex())
+	encryptedObjectRepo := repository.NewEncryptedObjectRepository(db, logger)
+	userRepo := repository.NewUserRepository(db, logger)
+	retentionRepo := repository.NewRetentionRepository(db, logger)
+	applicationRepo := repository.NewApplicationRepository(db, logger)
+	bulkOperationRepo := repository.NewBulkOperationRepository(db, logger)
+	webhookRepo := repository.NewWebhookRepository(db, logger)
+	notificationRepo := repository.NewNotificationRepository(db, logger)
+
+	// Initialize services
+	var encryptionService service.EncryptionServiceInterface
+	if cfg.EncryptionEnabled {
+		kms, err := service.NewKMS(cfg)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("failed to create KMS")
+		}
+		encryptionService = service.NewEncryptionService(kms, encryptedObjectRepo)
+	}
+
+	s3Service := service.NewS3Service(cfg, redisClient, s3Client, s3PresignClient, encryptionService)
+	retentionService := service.NewRetentionService(cfg, logger, retentionRepo, s3Service)
+	webhookService := service.NewWebhookService(cfg, logger, webhookRepo)
+
+	renderer, err := notification.NewRenderer()
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to load notification templates")
+	}
+	notificationProvider, err := notification.NewProvider(cfg, logger)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to create notification provider")
+	}
+	notificationService := service.NewNotificationService(cfg, logger, userRepo, jobRepo, notificationRepo, renderer, notificationProvider)
+
+	hub := event.NewHub(cfg, redisClient, logger)
+
+	// OpenTelemetry tracing, can be ignored when there's no setup for tracing when developing locally.
+	tracerProvider, err := util.InitTracer(cfg)
+	if err != nil {
+		logger.Info().Msgf("Failed to initialize tracer: %v", err)
+	}
+
+	// The worker doesn't serve /metrics, its metrics are only pushed when METRICS_OTLP_ENDPOINT is set.
+	meterProvider, _, err := util.InitMeter(cfg)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to initialize meter")
+	}
+
+	queue := jobs.NewQueue(cfg, redisClient)
+	worker := jobs.NewWorker(cfg, queue, logger)
+	bulkApplicationService := service.NewBulkApplicationService(cfg, logger, userRepo, applicationRepo, bulkOperationRepo, s3Service, queue)
+	jobFacetService := service.NewJobFacetService(cfg, logger, redisClient, jobRepo)
+
+	// Uploaded parts of incomplete multipart uploads are kept (and billed) until they're aborted.
+	worker.Register(service.TaskAbortStaleMultipartUploads, func(ctx context.Context, task *jobs.Task) error {
+		aborted, err := s3Service.AbortStaleMultipartUploads(ctx, cfg.S3Bucket, cfg.S3MultipartMaxAge)
+		if err != nil {
+			return err
+		}
+		logger.Info().Int("aborted", aborted).Msg("stale multipart uploads aborted")
+		return nil
+	})
+
+	// Candidate data can't be kept forever, expired applications are purged by the retention policy.
+	worker.Register(service.TaskPurgeRetention, func(ctx context.Context, task *jobs.Task) error {
+		report, err := retentionService.Purge(ctx, false)
+		if err != nil {
+			return err
+		}
+		logger.Info().Int("purged", report.Purged).Int("failed", report.Failed).Msg("expired applications purged")
+		return nil
+	})
+
+	// Bulk actions on applications pick up where they left off when they're retried.
+	worker.Register(service.TaskBulkApplications, func(ctx context.Context, task *jobs.Task) error {
+		var payload service.BulkApplicationsPayload
+		if err := task.Decode(&payload); err != nil {
+			return err
+		}
+
+		err := bulkApplicationService.Run(ctx, payload.OperationID)
+		if err != nil && task.Attempt > task.MaxRetries {
+			// Without retries left the operation would be running forever.
+			if err := bulkApplicationService.Fail(context.WithoutCancel(ctx), payload.OperationID, err); err != nil {
+				logger.Error().Err(err).Str("operation_id", payload.OperationID).Msg("failed to fail bulk operation")
+			}
+		}
+		return err
+	})
+
+	// Exports hold candidate details and resumes, they're deleted once they expire.
+	worker.Register(service.TaskExpireExports, func(ctx context.Context, task *jobs.Task) error {
+		expired, err := bulkApplicationService.ExpireExports(ctx)
+		if err != nil {
+			return err
+		}
+		logger.Info().Int("expired", expired).Msg("expired exports deleted")
+		return nil
+	})
+
+	// Published events hold application payloads as well, they're only kept for a while to investigate relays.
+	relay := event.NewRelay(cfg, db, redisClient, logger)
+	worker.Register(event.TaskPruneOutbox, func(ctx context.Context, task *jobs.Task) error {
+		pruned, err := relay.Prune(ctx)
+		if err != nil {
+			return err
+		}
+		logger.Info().Int("pruned", pruned).Msg("published outbox events deleted")
+		return nil
+	})
+
+	scheduler := jobs.NewScheduler(queue, jobs.NewLocker(redisClient), logger)
+	for taskType, interval := range map[string]time.Duration{
+		service.TaskAbortStaleMultipartUploads: cfg.S3MultipartCleanupInterval,
+		service.TaskPurgeRetention:             cfg.RetentionPurgeInterval,
+		service.TaskExpireExports:              cfg.ExportExpiryInterval,
+		event.TaskPruneOutbox:                  cfg.EventOutboxPruneInterval,
+	} {
+		if err := scheduler.Add(fmt.Sprintf("@every %s", interval), taskType, nil); err != nil {
+			logger.Fatal().Err(err).Str("task", taskType).Msg("failed to schedule task")
+		}
+	}
+
+	var wg sync.WaitGroup
+	run := func(fn func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fn()
+		}()
+	}
+
+	// Domain events are written to the outbox along with the changes, and relayed to the Redis stream.
+	run(func() { runPeriodically(ctx, cfg.EventRelayInterval, relay.Run) })
+
+	for group, handler := range map[string]event.Handler{
+		"webhooks": webhookService.Dispatch,
+		// Notifications are rendered and sent asynchronously, failed ones are retried along with the event.
+		"notifications": notificationService.Handle,
+		// Events are published to every API replica through Redis pub/sub, and pushed to their SSE clients.
+		"stream": hub.Publish,
+		// Cached facets of job searches are invalidated once jobs change.
+		"job_facets": jobFacetService.Handle,
+	} {
+		consumer := event.NewConsumer(cfg, redisClient, logger, group, handler)
+		group := group
+		run(func() {
+			if err := consumer.Run(ctx); err != nil {
+				logger.Error().Err(err).Str("group", group).Msg("event consumer stopped")
+			}
+		})
+	}
+
+	scheduler.Start()
+	run(func() { worker.Run(ctx) })
+
+	logger.Info().Int("concurrency", cfg.WorkerConcurrency).Msg("worker started")
+
+	<-ctx.Done()
+	logger.Info().Msg("worker stopping")
+
+	<-scheduler.Stop().Done()
+	wg.Wait()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	if tracerProvider != nil {
+		if err := tracerProvider.Shutdown(shutdownCtx); err != nil {
+			logger.Error().Err(err).Msg("failed to flush spans")
+		}
+	}
+	if err := meterProvider.Shutdown(shutdownCtx); err != nil {
+		logger.Error().Err(err).Msg("failed to flush metrics")
+	}
+	if err := redisClient.Close(); err != nil {
+		logger.Error().Err(err).Msg("failed to close Redis client")
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		if err := sqlDB.Close(); err != nil {
+			logger.Error().Err(err).Msg("failed to close database")
+		}
+	}
+}
+
+// runPeriodically runs fn on every tick of interval until ctx is done.
+func runPeriodically(ctx context.Context, interval time.Duration, fn func(ctx context.Context)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			fn(ctx)
+		}
+	}
+}
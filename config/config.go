@@ -5,6 +5,7 @@ import (
 	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -22,7 +23,36 @@ type Config struct {
 	Debug     bool
 	PrettyLog bool
 
+	// HTTP server
+	Port             string
+	ShutdownTimeout  time.Duration
+	ReadinessTimeout time.Duration
+
 	TracerEndpoint string
+	// Ratio of traces sampled when there's no sampled parent, from 0 to 1
+	TracerSampleRatio float64
+
+	// Rate limits such as 60/1m by user, or by IP for anonymous requests, an empty limit is unlimited
+	RateLimitDefault            string
+	RateLimitHR                 string
+	RateLimitTOTP               string
+	RateLimitPresign            string
+	RateLimitJobSearchAnonymous string
+	// Comma separated IPs, CIDRs and user IDs which are never rate limited
+	RateLimitAllowList string
+
+	// Secret which signs pagination cursors
+	CursorSecret string
+
+	// Idempotency keys, responses are kept for the TTL and a key is locked while its request runs
+	IdempotencyKeyTTL      time.Duration
+	IdempotencyLockTimeout time.Duration
+	// IdempotencyMaxBodySize limits requests with a key and the responses which are kept, both are held in memory
+	IdempotencyMaxBodySize int64
+
+	// Metrics, they're pushed to the OTLP endpoint when it's set besides being exposed on /metrics
+	MetricsOTLPEndpoint    string
+	MetricsRefreshInterval time.Duration
 
 	RedisURL string
 
@@ -31,9 +61,100 @@ type Config struct {
 	S3Region   string
 	S3Key      string
 	S3Secret   string
+
+	// Multipart uploads
+	S3MultipartThreshold       int64
+	S3MultipartPartSize        int64
+	S3MultipartMaxAge          time.Duration
+	S3MultipartCleanupInterval time.Duration
+
+	// Envelope encryption of uploaded files
+	EncryptionEnabled bool
+	KMSProvider       string
+	KMSLocalKeyFile   string
+
+	// Command which converts Word documents to PDF for watermarked downloads
+	DocumentConverterCommand string
+
+	// Retention periods (in days) of applications by outcome, 0 keeps them forever
+	RetentionRejectedDays  int
+	RetentionWithdrawnDays int
+	RetentionHiredDays     int
+	RetentionPurgeInterval time.Duration
+
+	// Domain events, they're relayed from the outbox to a Redis stream. Relayed events are deleted from the
+	// outbox EventOutboxRetention after they're published, every EventOutboxPruneInterval.
+	EventStream              string
+	EventStreamMaxLen        int64
+	EventRelayInterval       time.Duration
+	EventRelayBatchSize      int
+	EventMaxAttempts         int
+	EventRetryBackoff        time.Duration
+	EventOutboxRetention     time.Duration
+	EventOutboxPruneInterval time.Duration
+
+	// Real-time updates over Server-Sent Events
+	StreamChannel           string
+	StreamHeartbeatInterval time.Duration
+	StreamReplayLimit       int64
+
+	// Outgoing webhooks
+	WebhookTimeout     time.Duration
+	WebhookMaxFailures int
+
+	// Email notifications
+	NotificationProvider string
+	SMTPHost             string
+	SMTPPort             int
+	SMTPUsername         string
+	SMTPPassword         string
+	SMTPFrom             string
+	UnsubscribeSecret    string
+	// Base URL of the API in links of notifications
+	PublicURL string
+
+	// Background jobs run by cmd/worker
+	WorkerConcurrency    int
+	JobPollInterval      time.Duration
+	JobDefaultTimeout    time.Duration
+	JobDefaultMaxRetries int
+	JobRetryBackoff      time.Duration
+
+	// Bulk actions on applications, a filter selects at most BulkOperationMaxApplications of them
+	BulkOperationTimeout         time.Duration
+	BulkOperationMaxApplications int
+	// Links to exports work without signing in until they expire, exports themselves are deleted
+	// ExportRetention after they're completed
+	ExportLinkSecret     string
+	ExportLinkTTL        time.Duration
+	ExportRetention      time.Duration
+	ExportExpiryInterval time.Duration
+
+	// Facet counts of job searches are cached for JobFacetsCacheTTL, salaries are counted in buckets between
+	// JobFacetSalaryBuckets
+	JobFacetsCacheTTL     time.Duration
+	JobFacetSalaryBuckets []int
+
+	// Jobs are searched with MySQL or with a Bleve index at SearchIndexPath, which every API replica keeps
+	// for itself. SearchIndexReplica names the replica, it must stay the same across restarts to keep the index.
+	SearchBackend      string
+	SearchIndexPath    string
+	SearchIndexReplica string
+	SearchMaxHits      int
+	SearchFuzziness    int
+	SearchSynonyms     string
+
+	// Maximum upload size for each file purpose
+	UploadMaxSizeResume       int64
+	UploadMaxSizePortfolio    int64
+	UploadMaxSizeVideo        int64
+	UploadMaxSizeDesignSample int64
 }
 
 func NewConfig() *Config {
+	debug := getEnv("DEBUG", "false") == "true"
+	hostname, _ := os.Hostname()
+
 	return &Config{
 		DBHost:        getEnv("DB_HOST", "localhost"),
 		DBPort:        getEnv("DB_PORT", "3306"),
@@ -45,10 +166,31 @@ func NewConfig() *Config {
 		DBMaxConn:     getEnvInt("DB_MAX_CONN", 10),
 		DBMaxLifeTime: getEnvInt("DB_MAX_LIFE", int(time.Minute*60)),
 
-		Debug:     getEnv("DEBUG", "false") == "true",
+		Debug:     debug,
 		PrettyLog: getEnv("PRETTY_LOG", "false") == "true",
 
-		TracerEndpoint: getEnv("TRACER_ENDPOINT", "localhost:4317"),
+		Port:             getEnv("PORT", "8080"),
+		ShutdownTimeout:  getEnvDuration("SHUTDOWN_TIMEOUT", 30*time.Second),
+		ReadinessTimeout: getEnvDuration("READINESS_TIMEOUT", 2*time.Second),
+
+		TracerEndpoint:    getEnv("TRACER_ENDPOINT", "localhost:4317"),
+		TracerSampleRatio: getEnvFloat("TRACER_SAMPLE_RATIO", 1.0),
+
+		RateLimitDefault:            getEnv("RATE_LIMIT_DEFAULT", "120/1m"),
+		RateLimitHR:                 getEnv("RATE_LIMIT_HR", "1200/1m"),
+		RateLimitTOTP:               getEnv("RATE_LIMIT_TOTP", "10/1m"),
+		RateLimitPresign:            getEnv("RATE_LIMIT_PRESIGN", "30/1m"),
+		RateLimitJobSearchAnonymous: getEnv("RATE_LIMIT_JOB_SEARCH_ANONYMOUS", "30/1m"),
+		RateLimitAllowList:          getEnv("RATE_LIMIT_ALLOWLIST", ""),
+
+		CursorSecret: getEnvSecret("CURSOR_SECRET", "insecure-cursor-secret", debug),
+
+		IdempotencyKeyTTL:      getEnvDuration("IDEMPOTENCY_KEY_TTL", 24*time.Hour),
+		IdempotencyLockTimeout: getEnvDuration("IDEMPOTENCY_LOCK_TIMEOUT", time.Minute),
+		IdempotencyMaxBodySize: getEnvInt64("IDEMPOTENCY_MAX_BODY_SIZE", 1024*1024),
+
+		MetricsOTLPEndpoint:    getEnv("METRICS_OTLP_ENDPOINT", ""),
+		MetricsRefreshInterval: getEnvDuration("METRICS_REFRESH_INTERVAL", time.Minute),
 
 		RedisURL: getEnv("REDIS_URL", "redis://localhost:6379"),
 
@@ -57,9 +199,109 @@ func NewConfig() *Config {
 		S3Region:   getEnv("S3_REGION", "us-east-1"),
 		S3Key:      getEnv("S3_KEY", ""),
 		S3Secret:   getEnv("S3_SECRET", ""),
+
+		S3MultipartThreshold:       getEnvInt64("S3_MULTIPART_THRESHOLD", 16*1024*1024),
+		S3MultipartPartSize:        getEnvInt64("S3_MULTIPART_PART_SIZE", 16*1024*1024),
+		S3MultipartMaxAge:          getEnvDuration("S3_MULTIPART_MAX_AGE", 24*time.Hour),
+		S3MultipartCleanupInterval: getEnvDuration("S3_MULTIPART_CLEANUP_INTERVAL", time.Hour),
+
+		EncryptionEnabled: getEnv("ENCRYPTION_ENABLED", "false") == "true",
+		KMSProvider:       getEnv("KMS_PROVIDER", "local"),
+		KMSLocalKeyFile:   getEnv("KMS_LOCAL_KEY_FILE", ".kms/keys.json"),
+
+		DocumentConverterCommand: getEnv("DOCUMENT_CONVERTER_COMMAND", "soffice"),
+
+		RetentionRejectedDays:  getEnvInt("RETENTION_REJECTED_DAYS", 180),
+		RetentionWithdrawnDays: getEnvInt("RETENTION_WITHDRAWN_DAYS", 90),
+		RetentionHiredDays:     getEnvInt("RETENTION_HIRED_DAYS", 0),
+		RetentionPurgeInterval: getEnvDuration("RETENTION_PURGE_INTERVAL", 24*time.Hour),
+
+		EventStream:              getEnv("EVENT_STREAM", "fliqt:events"),
+		EventStreamMaxLen:        getEnvInt64("EVENT_STREAM_MAX_LEN", 100000),
+		EventRelayInterval:       getEnvDuration("EVENT_RELAY_INTERVAL", time.Second),
+		EventRelayBatchSize:      getEnvInt("EVENT_RELAY_BATCH_SIZE", 100),
+		EventMaxAttempts:         getEnvInt("EVENT_MAX_ATTEMPTS", 5),
+		EventRetryBackoff:        getEnvDuration("EVENT_RETRY_BACKOFF", 5*time.Second),
+		EventOutboxRetention:     getEnvDuration("EVENT_OUTBOX_RETENTION", 7*24*time.Hour),
+		EventOutboxPruneInterval: getEnvDuration("EVENT_OUTBOX_PRUNE_INTERVAL", time.Hour),
+
+		StreamChannel:           getEnv("STREAM_CHANNEL", "fliqt:events:live"),
+		StreamHeartbeatInterval: getEnvDuration("STREAM_HEARTBEAT_INTERVAL", 15*time.Second),
+		StreamReplayLimit:       getEnvInt64("STREAM_REPLAY_LIMIT", 1000),
+
+		WebhookTimeout:     getEnvDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+		WebhookMaxFailures: getEnvInt("WEBHOOK_MAX_FAILURES", 10),
+
+		NotificationProvider: getEnv("NOTIFICATION_PROVIDER", "smtp"),
+		SMTPHost:             getEnv("SMTP_HOST", "localhost"),
+		SMTPPort:             getEnvInt("SMTP_PORT", 1025),
+		SMTPUsername:         getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:         getEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:             getEnv("SMTP_FROM", "fliQt <no-reply@fliqt.local>"),
+		UnsubscribeSecret:    getEnvSecret("UNSUBSCRIBE_SECRET", "insecure-unsubscribe-secret", debug),
+		PublicURL:            getEnv("PUBLIC_URL", "http://localhost:8080"),
+
+		WorkerConcurrency:    getEnvInt("WORKER_CONCURRENCY", 4),
+		JobPollInterval:      getEnvDuration("JOB_POLL_INTERVAL", time.Second),
+		JobDefaultTimeout:    getEnvDuration("JOB_DEFAULT_TIMEOUT", 5*time.Minute),
+		JobDefaultMaxRetries: getEnvInt("JOB_DEFAULT_MAX_RETRIES", 3),
+		JobRetryBackoff:      getEnvDuration("JOB_RETRY_BACKOFF", 10*time.Second),
+
+		BulkOperationTimeout:         getEnvDuration("BULK_OPERATION_TIMEOUT", 30*time.Minute),
+		BulkOperationMaxApplications: getEnvInt("BULK_OPERATION_MAX_APPLICATIONS", 10000),
+		ExportLinkSecret:             getEnvSecret("EXPORT_LINK_SECRET", "insecure-export-link-secret", debug),
+		ExportLinkTTL:                getEnvDuration("EXPORT_LINK_TTL", 15*time.Minute),
+		ExportRetention:              getEnvDuration("EXPORT_RETENTION", 7*24*time.Hour),
+		ExportExpiryInterval:         getEnvDuration("EXPORT_EXPIRY_INTERVAL", time.Hour),
+
+		JobFacetsCacheTTL:     getEnvDuration("JOB_FACETS_CACHE_TTL", 5*time.Minute),
+		JobFacetSalaryBuckets: getEnvInts("JOB_FACET_SALARY_BUCKETS", []int{30000, 50000, 80000, 120000}),
+
+		SearchBackend:      getEnv("SEARCH_BACKEND", "mysql"),
+		SearchIndexPath:    getEnv("SEARCH_INDEX_PATH", "/tmp/fliqt/jobs.bleve"),
+		SearchIndexReplica: getEnv("SEARCH_INDEX_REPLICA", hostname),
+		SearchMaxHits:      getEnvInt("SEARCH_MAX_HITS", 500),
+		SearchFuzziness:    getEnvInt("SEARCH_FUZZINESS", 1),
+		SearchSynonyms:     getEnv("SEARCH_SYNONYMS", "js,javascript;ts,typescript;k8s,kubernetes;frontend,front-end;backend,back-end;hr,human resources"),
+
+		UploadMaxSizeResume:       getEnvInt64("UPLOAD_MAX_SIZE_RESUME", 5*1024*1024),
+		UploadMaxSizePortfolio:    getEnvInt64("UPLOAD_MAX_SIZE_PORTFOLIO", 200*1024*1024),
+		UploadMaxSizeVideo:        getEnvInt64("UPLOAD_MAX_SIZE_VIDEO", 500*1024*1024),
+		UploadMaxSizeDesignSample: getEnvInt64("UPLOAD_MAX_SIZE_DESIGN_SAMPLE", 300*1024*1024),
 	}
 }
 
+// Validate checks the settings which have no usable default. Secrets only fall back to their public defaults
+// when debugging, so they must be set in production.
+func (c *Config) Validate() error {
+	var missing []string
+	for _, secret := range []struct {
+		key   string
+		value string
+	}{
+		{"CURSOR_SECRET", c.CursorSecret},
+		{"UNSUBSCRIBE_SECRET", c.UnsubscribeSecret},
+		{"EXPORT_LINK_SECRET", c.ExportLinkSecret},
+	} {
+		if secret.value == "" {
+			missing = append(missing, secret.key)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%s must be set unless DEBUG is true", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// getEnvSecret returns a secret, which falls back to insecureDefault only when debugging.
+func getEnvSecret(key, insecureDefault string, debug bool) string {
+	if !debug {
+		insecureDefault = ""
+	}
+	return getEnv(key, insecureDefault)
+}
+
 func getEnv(key, defaultValue string) string {
 	if value, ok := os.LookupEnv(key); ok {
 		return value
@@ -68,14 +310,62 @@ func getEnv(key, defaultValue string) string {
 }
 
 func getEnvInt(key string, defaultValue int) int {
-	envStr := getEnv(key, strconv.Itoa(defaultValue))
+	return int(getEnvInt64(key, int64(defaultValue)))
+}
+
+func getEnvInt64(key string, defaultValue int64) int64 {
+	envStr := getEnv(key, strconv.FormatInt(defaultValue, 10))
 
 	env, err := strconv.ParseInt(envStr, 10, 64)
-	if err == nil {
+	if err != nil {
+		return defaultValue
+	}
+
+	return env
+}
+
+// getEnvInts parses a comma-separated list of numbers, defaultValue is used unless all of them are numbers.
+func getEnvInts(key string, defaultValue []int) []int {
+	envStr, ok := os.LookupEnv(key)
+	if !ok {
+		return defaultValue
+	}
+
+	env := []int{}
+	for _, value := range strings.Split(envStr, ",") {
+		if value = strings.TrimSpace(value); value == "" {
+			continue
+		}
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return defaultValue
+		}
+		env = append(env, number)
+	}
+
+	return env
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	envStr := getEnv(key, strconv.FormatFloat(defaultValue, 'f', -1, 64))
+
+	env, err := strconv.ParseFloat(envStr, 64)
+	if err != nil {
+		return defaultValue
+	}
+
+	return env
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	envStr := getEnv(key, defaultValue.String())
+
+	env, err := time.ParseDuration(envStr)
+	if err != nil {
 		return defaultValue
 	}
 
-	return int(env)
+	return env
 }
 
 func (c *Config) GetDBDSN() string {
@@ -0,0 +1,85 @@
+package apperror
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Error is an error which is safe to show to clients. Code is stable, so clients can handle errors by it
+// rather than by Message, which is meant for humans and may change.
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+	// Details describe which fields of the request are invalid
+	Details []FieldError
+	// err is the cause, it's logged but never shown to clients
+	err error
+}
+
+// FieldError is an invalid field of a request, Field is its name in the request.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func New(status int, code string, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	if e.err != nil {
+		return e.Message + ": " + e.err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// Is reports whether target has the same code, so copies made by Wrap and WithDetails match the original.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// Wrap returns a copy of the error caused by err.
+func (e *Error) Wrap(err error) *Error {
+	wrapped := *e
+	wrapped.err = err
+	return &wrapped
+}
+
+// WithMessage returns a copy of the error with a more specific message.
+func (e *Error) WithMessage(message string) *Error {
+	copied := *e
+	copied.Message = message
+	return &copied
+}
+
+// WithDetails returns a copy of the error with invalid fields.
+func (e *Error) WithDetails(details ...FieldError) *Error {
+	copied := *e
+	copied.Details = append(append([]FieldError{}, e.Details...), details...)
+	return &copied
+}
+
+// As finds the first Error in the chain of err.
+func As(err error) (*Error, bool) {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr, true
+	}
+	return nil, false
+}
+
+var (
+	ErrInternal   = New(http.StatusInternalServerError, "internal_error", "an unexpected error occurred")
+	ErrBadRequest = New(http.StatusBadRequest, "bad_request", "the request is malformed")
+	ErrValidation = New(http.StatusBadRequest, "validation_failed", "some fields of the request are invalid")
+	ErrNotFound   = New(http.StatusNotFound, "not_found", "the resource was not found")
+	// ErrPreconditionFailed is returned when a resource was changed since the client fetched it
+	ErrPreconditionFailed = New(http.StatusPreconditionFailed, "precondition_failed", "the resource has been changed, fetch it again and retry")
+)
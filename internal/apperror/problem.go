@@ -0,0 +1,32 @@
+package apperror
+
+import "net/http"
+
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem document. Problems don't have their own documentation, so Type is
+// about:blank and Code tells them apart.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail"`
+	Instance string       `json:"instance,omitempty"`
+	Code     string       `json:"code"`
+	TraceID  string       `json:"trace_id,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// NewProblem describes err, instance is the path of the request it occurred on.
+func NewProblem(err *Error, instance string, traceID string) Problem {
+	return Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(err.Status),
+		Status:   err.Status,
+		Detail:   err.Message,
+		Instance: instance,
+		Code:     err.Code,
+		TraceID:  traceID,
+		Errors:   err.Details,
+	}
+}
@@ -0,0 +1,302 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/rs/zerolog"
+
+	"fliqt/config"
+)
+
+const (
+	consumerReadCount = 10
+	consumerBlock     = 5 * time.Second
+	maxRetryBackoff   = 10 * time.Minute
+)
+
+var ErrMalformedEvent = errors.New("malformed event")
+
+// Handler handles an event, events are redelivered with backoff until it returns nil.
+type Handler func(ctx context.Context, e Event) error
+
+// Consumer delivers events of the stream to its handler as a member of a consumer group,
+// every group receives every event once while its members share the work.
+type Consumer struct {
+	redisClient *redis.Client
+	logger      *zerolog.Logger
+	stream      string
+	group       string
+	name        string
+	maxAttempts int64
+	backoff     time.Duration
+	handler     Handler
+}
+
+func NewConsumer(
+	cfg *config.Config,
+	redisClient *redis.Client,
+	logger *zerolog.Logger,
+	group string,
+	handler Handler,
+) *Consumer {
+	hostname, _ := os.Hostname()
+
+	return &Consumer{
+		redisClient,
+		logger,
+		cfg.EventStream,
+		group,
+		fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		int64(cfg.EventMaxAttempts),
+		cfg.EventRetryBackoff,
+		handler,
+	}
+}
+
+// DeadLetterStream returns the stream where events are moved to after all attempts fail.
+func DeadLetterStream(stream string) string {
+	return stream + ":dead"
+}
+
+// CreateGroup creates a consumer group of the stream, it reports whether the group is new. New groups start from
+// new events, history isn't replayed to them.
+func CreateGroup(ctx context.Context, redisClient *redis.Client, stream string, group string) (bool, error) {
+	err := redisClient.XGroupCreateMkStream(ctx, stream, group, "$").Err()
+	if err != nil && strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Run consumes events until ctx is done, the group is created unless it exists.
+func (c *Consumer) Run(ctx context.Context) error {
+	if _, err := CreateGroup(ctx, c.redisClient, c.stream, c.group); err != nil {
+		return err
+	}
+
+	for ctx.Err() == nil {
+		if err := c.consume(ctx); err != nil && ctx.Err() == nil {
+			c.logger.Error().Err(err).Str("group", c.group).Msg("failed to consume events")
+			time.Sleep(c.backoff)
+		}
+
+		if err := c.retry(ctx); err != nil && ctx.Err() == nil {
+			c.logger.Error().Err(err).Str("group", c.group).Msg("failed to retry events")
+		}
+	}
+
+	return nil
+}
+
+func (c *Consumer) consume(ctx context.Context) error {
+	streams, err := c.redisClient.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    c.group,
+		Consumer: c.name,
+		Streams:  []string{c.stream, ">"},
+		Count:    consumerReadCount,
+		Block:    consumerBlock,
+	}).Result()
+	if err == redis.Nil {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, stream := range streams {
+		for _, message := range stream.Messages {
+			c.handle(ctx, message)
+		}
+	}
+
+	return nil
+}
+
+// retry claims events whose backoff has elapsed, events which failed too many times are dead-lettered. Pending
+// events are paged through, so the ones still backing off don't hold back those after them.
+func (c *Consumer) retry(ctx context.Context) error {
+	start := "-"
+	for ctx.Err() == nil {
+		pending, err := c.redisClient.XPendingExt(ctx, &redis.XPendingExtArgs{
+			Stream: c.stream,
+			Group:  c.group,
+			Idle:   c.backoff,
+			Start:  start,
+			End:    "+",
+			Count:  consumerReadCount,
+		}).Result()
+		if err != nil {
+			return err
+		}
+
+		for _, p := range pending {
+			if err := c.retryPending(ctx, p); err != nil {
+				return err
+			}
+		}
+
+		if len(pending) < consumerReadCount {
+			return nil
+		}
+		start = nextStreamID(pending[len(pending)-1].ID)
+	}
+
+	return nil
+}
+
+func (c *Consumer) retryPending(ctx context.Context, p redis.XPendingExt) error {
+	if p.RetryCount >= c.maxAttempts {
+		return c.deadLetter(ctx, p.ID, p.RetryCount)
+	}
+
+	backoff := retryBackoff(c.backoff, p.RetryCount)
+	if p.Idle < backoff {
+		return nil
+	}
+
+	// Claiming fails when another consumer has claimed it already.
+	messages, err := c.redisClient.XClaim(ctx, &redis.XClaimArgs{
+		Stream:   c.stream,
+		Group:    c.group,
+		Consumer: c.name,
+		MinIdle:  backoff,
+		Messages: []string{p.ID},
+	}).Result()
+	if err != nil {
+		return err
+	}
+
+	for _, message := range messages {
+		c.handle(ctx, message)
+	}
+
+	return nil
+}
+
+// nextStreamID returns the ID right after a stream ID, so a range can start after it.
+func nextStreamID(ID string) string {
+	ms, seq, _ := strings.Cut(ID, "-")
+	n, err := strconv.ParseUint(seq, 10, 64)
+	if err != nil {
+		return ID
+	}
+
+	return fmt.Sprintf("%s-%d", ms, n+1)
+}
+
+func (c *Consumer) handle(ctx context.Context, message redis.XMessage) {
+	e, err := decode(message)
+	if err != nil {
+		c.logger.Error().Err(err).Str("group", c.group).Str("message_id", message.ID).Msg("failed to decode event")
+		if err := c.deadLetter(ctx, message.ID, 0); err != nil {
+			c.logger.Error().Err(err).Str("group", c.group).Str("message_id", message.ID).Msg("failed to dead-letter event")
+		}
+		return
+	}
+
+	if err := c.handler(ctx, e); err != nil {
+		// It's left pending and retried with backoff.
+		c.logger.Error().Err(err).Str("group", c.group).Str("event_id", e.ID).Str("type", e.Type).Msg("failed to handle event")
+		return
+	}
+
+	if err := c.redisClient.XAck(ctx, c.stream, c.group, message.ID).Err(); err != nil {
+		c.logger.Error().Err(err).Str("group", c.group).Str("event_id", e.ID).Msg("failed to ack event")
+	}
+}
+
+// deadLetter moves an event to the dead-letter stream along with the group which failed to handle it.
+func (c *Consumer) deadLetter(ctx context.Context, messageID string, attempts int64) error {
+	messages, err := c.redisClient.XRange(ctx, c.stream, messageID, messageID).Result()
+	if err != nil {
+		return err
+	}
+
+	// The event may have been trimmed from the stream already.
+	if len(messages) > 0 {
+		values := messages[0].Values
+		values["group"] = c.group
+		values["attempts"] = attempts
+		values["message_id"] = messageID
+
+		if err := c.redisClient.XAdd(ctx, &redis.XAddArgs{
+			Stream: DeadLetterStream(c.stream),
+			Values: values,
+		}).Err(); err != nil {
+			return err
+		}
+	}
+
+	c.logger.Warn().Str("group", c.group).Str("message_id", messageID).Int64("attempts", attempts).Msg("event dead-lettered")
+
+	return c.redisClient.XAck(ctx, c.stream, c.group, messageID).Err()
+}
+
+// retryBackoff doubles the backoff on every delivery, up to maxRetryBackoff.
+func retryBackoff(base time.Duration, deliveries int64) time.Duration {
+	backoff := base
+	for i := int64(1); i < deliveries && backoff < maxRetryBackoff; i++ {
+		backoff *= 2
+	}
+
+	if backoff > maxRetryBackoff {
+		return maxRetryBackoff
+	}
+
+	return backoff
+}
+
+func encode(e Event) map[string]interface{} {
+	return map[string]interface{}{
+		"id":             e.ID,
+		"type":           e.Type,
+		"aggregate_type": e.AggregateType,
+		"aggregate_id":   e.AggregateID,
+		"payload":        string(e.Payload),
+		"occurred_at":    e.OccurredAt.UTC().Format(time.RFC3339Nano),
+	}
+}
+
+func decode(message redis.XMessage) (Event, error) {
+	field := func(name string) string {
+		value, _ := message.Values[name].(string)
+		return value
+	}
+
+	occurredAt, err := time.Parse(time.RFC3339Nano, field("occurred_at"))
+	if err != nil {
+		return Event{}, fmt.Errorf("%w: %v", ErrMalformedEvent, err)
+	}
+
+	payload := field("payload")
+	if !json.Valid([]byte(payload)) {
+		return Event{}, fmt.Errorf("%w: invalid payload", ErrMalformedEvent)
+	}
+
+	e := Event{
+		ID:            field("id"),
+		Type:          field("type"),
+		AggregateType: field("aggregate_type"),
+		AggregateID:   field("aggregate_id"),
+		Payload:       json.RawMessage(payload),
+		OccurredAt:    occurredAt,
+		StreamID:      message.ID,
+	}
+	if e.ID == "" || e.Type == "" {
+		return Event{}, ErrMalformedEvent
+	}
+
+	return e, nil
+}
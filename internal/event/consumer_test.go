@@ -0,0 +1,127 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/rs/zerolog"
+
+	"fliqt/config"
+)
+
+func TestRetryBackoff(t *testing.T) {
+	for deliveries, expected := range map[int64]time.Duration{
+		1:  5 * time.Second,
+		2:  10 * time.Second,
+		3:  20 * time.Second,
+		20: maxRetryBackoff,
+	} {
+		if backoff := retryBackoff(5*time.Second, deliveries); backoff != expected {
+			t.Errorf("expected %s after %d deliveries, got %s", expected, deliveries, backoff)
+		}
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	e := Event{
+		ID:            "cqanbg8cvavjpljmh7pg",
+		Type:          ApplicationStatusChanged,
+		AggregateType: AggregateApplication,
+		AggregateID:   "cqanbg8cvavjpljmh7ph",
+		Payload:       json.RawMessage(`{"to_status":"hired"}`),
+		OccurredAt:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	// Redis returns every value as a string.
+	values := map[string]interface{}{}
+	for k, v := range encode(e) {
+		values[k] = v.(string)
+	}
+
+	decoded, err := decode(redis.XMessage{ID: "1-0", Values: values})
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+
+	if decoded.ID != e.ID || decoded.Type != e.Type || string(decoded.Payload) != string(e.Payload) || !decoded.OccurredAt.Equal(e.OccurredAt) {
+		t.Errorf("expected %+v, got %+v", e, decoded)
+	}
+
+	if _, err := decode(redis.XMessage{ID: "1-0", Values: map[string]interface{}{"id": "1"}}); !errors.Is(err, ErrMalformedEvent) {
+		t.Errorf("expected ErrMalformedEvent, got %v", err)
+	}
+}
+
+func TestNextStreamID(t *testing.T) {
+	if ID := nextStreamID("1700000000000-9"); ID != "1700000000000-10" {
+		t.Errorf("expected 1700000000000-10, got %s", ID)
+	}
+}
+
+func TestCreateGroup(t *testing.T) {
+	mr := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	ctx := context.Background()
+
+	for _, expected := range []bool{true, false} {
+		created, err := CreateGroup(ctx, redisClient, "events", "group")
+		if err != nil {
+			t.Fatalf("Error: %s", err)
+		}
+		if created != expected {
+			t.Errorf("expected created to be %t, got %t", expected, created)
+		}
+	}
+}
+
+func TestConsumerRetryPagesThroughPending(t *testing.T) {
+	mr := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	logger := zerolog.Nop()
+	ctx := context.Background()
+
+	handled := map[string]bool{}
+	consumer := NewConsumer(&config.Config{EventStream: "events", EventMaxAttempts: 10, EventRetryBackoff: time.Second}, redisClient, &logger, "group", func(ctx context.Context, e Event) error {
+		handled[e.ID] = true
+		return nil
+	})
+
+	now := time.Now()
+	mr.SetTime(now)
+	if err := redisClient.XGroupCreateMkStream(ctx, "events", "group", "$").Err(); err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	var IDs []string
+	for i := 0; i < consumerReadCount+2; i++ {
+		e := Event{ID: fmt.Sprintf("event-%d", i), Type: JobCreated, Payload: json.RawMessage(`{}`), OccurredAt: now}
+		ID, err := redisClient.XAdd(ctx, &redis.XAddArgs{Stream: "events", Values: encode(e)}).Result()
+		if err != nil {
+			t.Fatalf("Error: %s", err)
+		}
+		IDs = append(IDs, ID)
+	}
+	if err := redisClient.XReadGroup(ctx, &redis.XReadGroupArgs{Group: "group", Consumer: "failed", Streams: []string{"events", ">"}}).Err(); err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	// The first page of pending events has been delivered more often, so it's still backing off
+	for i := 0; i < 2; i++ {
+		if err := redisClient.XClaim(ctx, &redis.XClaimArgs{Stream: "events", Group: "group", Consumer: "failed", Messages: IDs[:consumerReadCount]}).Err(); err != nil {
+			t.Fatalf("Error: %s", err)
+		}
+	}
+
+	mr.SetTime(now.Add(2 * time.Second))
+	if err := consumer.retry(ctx); err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+
+	if len(handled) != 2 || !handled[fmt.Sprintf("event-%d", consumerReadCount)] || !handled[fmt.Sprintf("event-%d", consumerReadCount+1)] {
+		t.Errorf("expected the events after the first page to be retried, got %v", handled)
+	}
+}
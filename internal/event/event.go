@@ -0,0 +1,69 @@
+package event
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+
+	"fliqt/internal/model"
+)
+
+// Types of domain events
+const (
+	JobCreated = "job.created"
+	JobUpdated = "job.updated"
+	JobDeleted = "job.deleted"
+
+	ApplicationSubmitted     = "application.submitted"
+	ApplicationStatusChanged = "application.status_changed"
+	ApplicationPurged        = "application.purged"
+)
+
+// Types of aggregates events are about
+const (
+	AggregateJob         = "job"
+	AggregateApplication = "application"
+)
+
+// Event is a domain event delivered to subscribers.
+type Event struct {
+	ID            string          `json:"id"`
+	Type          string          `json:"type"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id"`
+	Payload       json.RawMessage `json:"payload"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	// StreamID is the ID of the event in the Redis stream, it's ordered and used to resume from
+	StreamID string `json:"-"`
+}
+
+// ApplicationStatusChangedPayload is the payload of ApplicationStatusChanged events.
+type ApplicationStatusChangedPayload struct {
+	ApplicationID string `json:"application_id"`
+	JobID         string `json:"job_id"`
+	UserID        string `json:"user_id"`
+	FromStatus    string `json:"from_status"`
+	ToStatus      string `json:"to_status"`
+	ChangedBy     string `json:"changed_by"`
+	// Reason is told to the candidate, e.g. why they're rejected
+	Reason string `json:"reason,omitempty"`
+	// AssigneeID is the interviewer the application is assigned to, if any
+	AssigneeID string `json:"assignee_id,omitempty"`
+}
+
+// Record writes an event to the outbox, tx must be the transaction of the change the event describes
+// so the event is only published when the change is committed.
+func Record(tx *gorm.DB, eventType string, aggregateType string, aggregateID string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	return tx.Create(&model.OutboxEvent{
+		Type:          eventType,
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		Payload:       data,
+	}).Error
+}
@@ -0,0 +1,199 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/rs/zerolog"
+
+	"fliqt/config"
+)
+
+const hubSubscriberBuffer = 64
+
+// liveEvent is an event published through Redis pub/sub, StreamID isn't part of the JSON of events.
+type liveEvent struct {
+	StreamID string `json:"stream_id"`
+	Event    Event  `json:"event"`
+}
+
+// Hub fans events out to subscribers of this replica. Events are published to a Redis pub/sub channel
+// once by a consumer group, and every replica relays them to its own subscribers.
+type Hub struct {
+	redisClient *redis.Client
+	logger      *zerolog.Logger
+	stream      string
+	channel     string
+
+	mu          sync.RWMutex
+	subscribers map[chan Event]struct{}
+	closed      bool
+}
+
+func NewHub(
+	cfg *config.Config,
+	redisClient *redis.Client,
+	logger *zerolog.Logger,
+) *Hub {
+	return &Hub{
+		redisClient: redisClient,
+		logger:      logger,
+		stream:      cfg.EventStream,
+		channel:     cfg.StreamChannel,
+		subscribers: map[chan Event]struct{}{},
+	}
+}
+
+// Publish publishes an event to every replica, it's the handler of the consumer group.
+func (h *Hub) Publish(ctx context.Context, e Event) error {
+	data, err := json.Marshal(liveEvent{StreamID: e.StreamID, Event: e})
+	if err != nil {
+		return err
+	}
+
+	return h.redisClient.Publish(ctx, h.channel, data).Err()
+}
+
+// Run relays events of the pub/sub channel to subscribers until ctx is done.
+func (h *Hub) Run(ctx context.Context) error {
+	pubsub := h.redisClient.Subscribe(ctx, h.channel)
+	defer pubsub.Close()
+
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return err
+	}
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case message, ok := <-messages:
+			if !ok {
+				return nil
+			}
+
+			var live liveEvent
+			if err := json.Unmarshal([]byte(message.Payload), &live); err != nil {
+				h.logger.Error().Err(err).Msg("failed to decode live event")
+				continue
+			}
+			live.Event.StreamID = live.StreamID
+
+			h.broadcast(live.Event)
+		}
+	}
+}
+
+func (h *Hub) broadcast(e Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.subscribers {
+		select {
+		case ch <- e:
+		default:
+			// The subscriber is too slow, it's dropped and has to resume with the last event it received.
+			delete(h.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Close drops every subscriber and rejects new ones, so streams end when the server shuts down.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for ch := range h.subscribers {
+		delete(h.subscribers, ch)
+		close(ch)
+	}
+}
+
+// Subscribe returns a channel of events, it's closed when the subscriber is dropped or unsubscribes.
+func (h *Hub) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, hubSubscriberBuffer)
+
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		close(ch)
+		return ch, func() {}
+	}
+	h.subscribers[ch] = struct{}{}
+	h.mu.Unlock()
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		if _, ok := h.subscribers[ch]; ok {
+			delete(h.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Replay returns events of the stream after the stream ID, at most limit of them.
+func (h *Hub) Replay(ctx context.Context, afterStreamID string, limit int64) ([]Event, error) {
+	messages, err := h.redisClient.XRangeN(ctx, h.stream, "("+afterStreamID, "+", limit).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]Event, 0, len(messages))
+	for _, message := range messages {
+		e, err := decode(message)
+		if err != nil {
+			continue
+		}
+		events = append(events, e)
+	}
+
+	return events, nil
+}
+
+// CompareStreamIDs compares two Redis stream IDs ("<milliseconds>-<sequence>"), like strings.Compare.
+func CompareStreamIDs(a, b string) int {
+	aMs, aSeq := parseStreamID(a)
+	bMs, bSeq := parseStreamID(b)
+
+	switch {
+	case aMs < bMs:
+		return -1
+	case aMs > bMs:
+		return 1
+	case aSeq < bSeq:
+		return -1
+	case aSeq > bSeq:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func parseStreamID(id string) (uint64, uint64) {
+	ms, seq, _ := strings.Cut(id, "-")
+	msValue, _ := strconv.ParseUint(ms, 10, 64)
+	seqValue, _ := strconv.ParseUint(seq, 10, 64)
+	return msValue, seqValue
+}
+
+// ValidStreamID reports whether id looks like a Redis stream ID.
+func ValidStreamID(id string) bool {
+	ms, seq, found := strings.Cut(id, "-")
+	if _, err := strconv.ParseUint(ms, 10, 64); err != nil {
+		return false
+	}
+	if !found {
+		return true
+	}
+	_, err := strconv.ParseUint(seq, 10, 64)
+	return err == nil
+}
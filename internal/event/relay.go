@@ -0,0 +1,137 @@
+package event
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"fliqt/config"
+	"fliqt/internal/model"
+)
+
+// TaskPruneOutbox deletes events which were published longer than EventOutboxRetention ago.
+const TaskPruneOutbox = "outbox.prune"
+
+// Relay publishes events of the outbox to the Redis stream.
+type Relay struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+	logger      *zerolog.Logger
+	stream      string
+	maxLen      int64
+	batchSize   int
+	retention   time.Duration
+}
+
+func NewRelay(
+	cfg *config.Config,
+	db *gorm.DB,
+	redisClient *redis.Client,
+	logger *zerolog.Logger,
+) *Relay {
+	return &Relay{
+		db,
+		redisClient,
+		logger,
+		cfg.EventStream,
+		cfg.EventStreamMaxLen,
+		cfg.EventRelayBatchSize,
+		cfg.EventOutboxRetention,
+	}
+}
+
+// Publish publishes a batch of unpublished events and returns how many were published.
+// Rows are locked with SKIP LOCKED, so relays of every replica can run at the same time.
+func (r *Relay) Publish(ctx context.Context) (int, error) {
+	published := 0
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var events []model.OutboxEvent
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("published_at IS NULL").
+			Order("id ASC").
+			Limit(r.batchSize).
+			Find(&events).Error; err != nil {
+			return err
+		}
+
+		if len(events) == 0 {
+			return nil
+		}
+
+		// A failure rolls back the transaction and the whole batch is published again,
+		// so events are delivered at least once.
+		pipe := r.redisClient.Pipeline()
+		ids := make([]string, 0, len(events))
+		for _, e := range events {
+			pipe.XAdd(ctx, &redis.XAddArgs{
+				Stream: r.stream,
+				MaxLen: r.maxLen,
+				Approx: true,
+				Values: encode(Event{
+					ID:            e.ID,
+					Type:          e.Type,
+					AggregateType: e.AggregateType,
+					AggregateID:   e.AggregateID,
+					Payload:       e.Payload,
+					OccurredAt:    e.CreatedAt,
+				}),
+			})
+			ids = append(ids, e.ID)
+		}
+
+		if _, err := pipe.Exec(ctx); err != nil {
+			return err
+		}
+
+		if err := tx.Model(&model.OutboxEvent{}).Where("id IN ?", ids).UpdateColumn("published_at", time.Now()).Error; err != nil {
+			return err
+		}
+
+		published = len(events)
+		return nil
+	})
+
+	return published, err
+}
+
+// Run publishes events until the outbox is drained.
+func (r *Relay) Run(ctx context.Context) {
+	for {
+		published, err := r.Publish(ctx)
+		if err != nil {
+			r.logger.Error().Err(err).Msg("failed to publish outbox events")
+			return
+		}
+
+		if published < r.batchSize {
+			return
+		}
+	}
+}
+
+// Prune deletes events published longer than the retention ago and returns how many were deleted. They're
+// deleted in batches, so the outbox isn't locked for long.
+func (r *Relay) Prune(ctx context.Context) (int, error) {
+	before := time.Now().Add(-r.retention)
+	pruned := 0
+
+	for {
+		result := r.db.WithContext(ctx).Unscoped().
+			Where("published_at < ?", before).
+			Limit(r.batchSize).
+			Delete(&model.OutboxEvent{})
+		if result.Error != nil {
+			return pruned, result.Error
+		}
+
+		pruned += int(result.RowsAffected)
+		if result.RowsAffected < int64(r.batchSize) {
+			return pruned, nil
+		}
+	}
+}
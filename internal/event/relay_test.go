@@ -0,0 +1,41 @@
+package event
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/rs/zerolog"
+
+	"fliqt/config"
+	"fliqt/internal/util"
+)
+
+func TestRelayPrunePagesThroughBatches(t *testing.T) {
+	db, mock, cleanup := util.SetupMockDB(t)
+	defer cleanup()
+
+	logger := zerolog.Nop()
+	relay := NewRelay(&config.Config{EventRelayBatchSize: 2, EventOutboxRetention: time.Hour}, db, nil, &logger)
+
+	for _, deleted := range []int64{2, 1} {
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE FROM `outbox` WHERE published_at < \\? LIMIT \\?").
+			WithArgs(sqlmock.AnyArg(), 2).
+			WillReturnResult(sqlmock.NewResult(0, deleted))
+		mock.ExpectCommit()
+	}
+
+	pruned, err := relay.Prune(context.TODO())
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if pruned != 3 {
+		t.Errorf("expected 3 events to be pruned, got %d", pruned)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -7,9 +7,13 @@ import (
 	"fliqt/internal/util"
 	"fmt"
 	"net/http"
+	"path"
+	"strconv"
 	"strings"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/gin-gonic/gin"
+	"github.com/rs/xid"
 	"github.com/rs/zerolog"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
@@ -19,17 +23,24 @@ type ApplicationHandler struct {
 	applicationRepo *repository.ApplicationRepository
 	logger          *zerolog.Logger
 	authService     service.AuthServiceInterface
+	// bulkApplicationService runs bulk actions on the worker
+	bulkApplicationService service.BulkApplicationServiceInterface
+	downloadLogRepo        *repository.DownloadLogRepository
 }
 
 func NewApplicationHandler(
 	applicationRepo *repository.ApplicationRepository,
 	logger *zerolog.Logger,
 	authService service.AuthServiceInterface,
+	bulkApplicationService service.BulkApplicationServiceInterface,
+	downloadLogRepo *repository.DownloadLogRepository,
 ) *ApplicationHandler {
 	return &ApplicationHandler{
 		applicationRepo,
 		logger,
 		authService,
+		bulkApplicationService,
+		downloadLogRepo,
 	}
 }
 
@@ -115,5 +126,271 @@ func (h *ApplicationHandler) CreateApplication(ctx *gin.Context) {
 		return
 	}
 
+	ctx.Header("ETag", versionETag(application.Version, ""))
 	ctx.JSON(http.StatusCreated, application)
 }
+
+// GetApplication returns an application, candidates can only get their own
+func (h *ApplicationHandler) GetApplication(ctx *gin.Context) {
+	tracerCtx, span := tracer.Start(
+		ctx.Request.Context(),
+		util.GetSpanNameFromCaller(),
+		trace.WithAttributes(
+			attribute.String("id", ctx.Param("id")),
+		),
+	)
+	defer span.End()
+
+	user, err := h.authService.CurrentUser(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	application, err := h.applicationRepo.GetApplicationByID(tracerCtx, ctx.Param("id"))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	// Other candidates' applications don't exist as far as a candidate knows
+	if user.Role == model.RoleCandidate && application.UserID != user.ID {
+		ctx.Error(ErrNotFound)
+		return
+	}
+
+	if notModified(ctx, versionETag(application.Version, "")) {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, application)
+}
+
+// UpdateApplicationStatus changes the status of an application
+func (h *ApplicationHandler) UpdateApplicationStatus(ctx *gin.Context) {
+	var req repository.UpdateApplicationStatusDTO
+	if err := ctx.BindJSON(&req); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	tracerCtx, span := tracer.Start(
+		ctx.Request.Context(),
+		util.GetSpanNameFromCaller(),
+		trace.WithAttributes(
+			attribute.String("id", ctx.Param("id")),
+			attribute.String("request", fmt.Sprintf("%+v", req)),
+		),
+	)
+	defer span.End()
+
+	user, err := h.authService.CurrentUser(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	req.ChangedBy = user.ID
+
+	versions, err := ifMatchVersions(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	application, err := h.applicationRepo.UpdateApplicationStatus(tracerCtx, ctx.Param("id"), req, versions)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.Header("ETag", versionETag(application.Version, ""))
+	ctx.JSON(http.StatusOK, application)
+}
+
+// ListAuditLogs returns who took which actions on an application
+func (h *ApplicationHandler) ListAuditLogs(ctx *gin.Context) {
+	tracerCtx, span := tracer.Start(
+		ctx.Request.Context(),
+		util.GetSpanNameFromCaller(),
+		trace.WithAttributes(
+			attribute.String("id", ctx.Param("id")),
+		),
+	)
+	defer span.End()
+
+	if _, err := h.applicationRepo.GetApplicationByID(tracerCtx, ctx.Param("id")); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	logs, err := h.applicationRepo.ListApplicationAuditLogs(tracerCtx, ctx.Param("id"))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, logs)
+}
+
+// BulkApplications starts an action on many applications, it runs on the worker and reports its progress
+// at GET /applications/bulk/:id
+func (h *ApplicationHandler) BulkApplications(ctx *gin.Context) {
+	var req repository.BulkApplicationDTO
+	if err := ctx.BindJSON(&req); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	tracerCtx, span := tracer.Start(
+		ctx.Request.Context(),
+		util.GetSpanNameFromCaller(),
+		trace.WithAttributes(
+			attribute.String("action", req.Action),
+			attribute.Int("application_ids", len(req.ApplicationIDs)),
+		),
+	)
+	defer span.End()
+
+	user, err := h.authService.CurrentUser(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	operation, err := h.bulkApplicationService.Start(tracerCtx, user, req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.Header("Location", fmt.Sprintf("/api/applications/bulk/%s", operation.ID))
+	ctx.JSON(http.StatusAccepted, operation)
+}
+
+// GetBulkOperation returns the progress of a bulk operation, the failed items, and the download of an export
+func (h *ApplicationHandler) GetBulkOperation(ctx *gin.Context) {
+	tracerCtx, span := tracer.Start(
+		ctx.Request.Context(),
+		util.GetSpanNameFromCaller(),
+		trace.WithAttributes(
+			attribute.String("id", ctx.Param("id")),
+		),
+	)
+	defer span.End()
+
+	user, err := h.authService.CurrentUser(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	report, err := h.bulkApplicationService.Get(tracerCtx, user, ctx.Param("id"))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, report)
+}
+
+// ExportApplicationsParams is the query of an export of a job's applications
+type ExportApplicationsParams struct {
+	Format string `form:"format" binding:"omitempty,oneof=csv xlsx zip"`
+}
+
+// ExportJobApplications starts an export of the applications of a job, it requires TOTP like downloads of resumes.
+// The export is generated on the worker, and a short-lived link to it is reported at GET /applications/bulk/:id
+func (h *ApplicationHandler) ExportJobApplications(ctx *gin.Context) {
+	var params ExportApplicationsParams
+	if err := ctx.ShouldBindQuery(&params); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	tracerCtx, span := tracer.Start(
+		ctx.Request.Context(),
+		util.GetSpanNameFromCaller(),
+		trace.WithAttributes(
+			attribute.String("id", ctx.Param("id")),
+			attribute.String("format", params.Format),
+		),
+	)
+	defer span.End()
+
+	user, err := h.authService.CurrentUser(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	// Exports bundle candidate details and resumes, so they're verified the same way as downloads.
+	passcode, ok := ctx.GetQuery("passcode")
+	if !ok {
+		ctx.Error(ErrBadRequest)
+		return
+	}
+
+	if err := h.authService.VerifyTOTP(ctx, user.TotpSecret, passcode); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	jobID := ctx.Param("id")
+	operation, err := h.bulkApplicationService.Start(tracerCtx, user, repository.BulkApplicationDTO{
+		Action: model.BulkActionExport,
+		Filter: &repository.ApplicationFilterParams{JobID: &jobID},
+		Format: params.Format,
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.Header("Location", fmt.Sprintf("/api/applications/bulk/%s", operation.ID))
+	ctx.JSON(http.StatusAccepted, operation)
+}
+
+// DownloadExport streams the file of an export through a signed link, it doesn't require signing in so it can be
+// handed to people without accounts. The download is logged on behalf of the user who started the export.
+func (h *ApplicationHandler) DownloadExport(ctx *gin.Context) {
+	tracerCtx, span := tracer.Start(
+		ctx.Request.Context(),
+		util.GetSpanNameFromCaller(),
+		trace.WithAttributes(
+			attribute.String("id", ctx.Param("id")),
+		),
+	)
+	defer span.End()
+
+	expires, err := strconv.ParseInt(ctx.Query("expires"), 10, 64)
+	if err != nil {
+		ctx.Error(service.ErrInvalidExportLink)
+		return
+	}
+
+	operation, object, err := h.bulkApplicationService.OpenExport(tracerCtx, ctx.Param("id"), expires, ctx.Query("signature"))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	defer object.Body.Close()
+
+	downloadLog := model.DownloadLog{
+		Base: model.Base{
+			ID: xid.New().String(),
+		},
+		ObjectKey: operation.ResultObjectKey,
+		UserID:    operation.CreatedBy,
+		ClientIP:  ctx.ClientIP(),
+		UserAgent: ctx.Request.UserAgent(),
+	}
+	if err := h.downloadLogRepo.CreateDownloadLog(tracerCtx, &downloadLog); err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.Header("X-Download-ID", downloadLog.ID)
+
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "applications-"+path.Base(operation.ResultObjectKey)))
+	ctx.DataFromReader(http.StatusOK, aws.ToInt64(object.ContentLength), aws.ToString(object.ContentType), object.Body, nil)
+}
@@ -3,22 +3,33 @@ package handler
 import (
 	"fliqt/internal/model"
 	"fliqt/internal/service"
-	"net/http"
 	"slices"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func AuthHandler(authService service.AuthServiceInterface, allowedRoles []model.UserRole) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		user, err := authService.CurrentUser(c)
 		if err != nil {
-			c.AbortWithError(http.StatusUnauthorized, err)
+			c.Error(err)
+			c.Abort()
 			return
 		}
 
+		// The user is recorded on the server span, so traces can be found by user.
+		if c.Request != nil {
+			trace.SpanFromContext(c.Request.Context()).SetAttributes(
+				attribute.String("enduser.id", user.ID),
+				attribute.String("enduser.role", string(user.Role)),
+			)
+		}
+
 		if !slices.Contains(allowedRoles, user.Role) {
-			c.AbortWithError(http.StatusForbidden, ErrForbidden)
+			c.Error(ErrForbidden)
+			c.Abort()
 			return
 		}
 
@@ -1,13 +1,17 @@
 package handler
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
 
+	"fliqt/internal/apperror"
 	"fliqt/internal/model"
+	"fliqt/internal/service"
 )
 
 type mockedAuthServiceForHR struct {
@@ -25,20 +29,50 @@ func (m *mockedAuthServiceForHR) VerifyTOTP(ctx *gin.Context, secret string, pas
 	return nil
 }
 
+type mockedAuthServiceForAnonymous struct {
+	mockedAuthServiceForHR
+}
+
+func (m *mockedAuthServiceForAnonymous) CurrentUser(ctx *gin.Context) (*model.User, error) {
+	return nil, service.ErrUnauthorized
+}
+
 func TestAuthHandler(t *testing.T) {
-	authService := &mockedAuthServiceForHR{}
+	logger := zerolog.Nop()
 
-	w := httptest.NewRecorder()
-	ctx, _ := gin.CreateTestContext(w)
+	request := func(authService service.AuthServiceInterface, allowedRoles []model.UserRole) *httptest.ResponseRecorder {
+		app := gin.New()
+		app.Use(ErrorHandler(&logger))
+		app.GET("/jobs", AuthHandler(authService, allowedRoles), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
 
-	AuthHandler(authService, []model.UserRole{model.RoleHR})(ctx)
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/jobs", nil))
+		return w
+	}
 
-	if w.Code != http.StatusOK {
+	if w := request(&mockedAuthServiceForHR{}, []model.UserRole{model.RoleHR}); w.Code != http.StatusOK {
 		t.Errorf("expected status code 200, got %d", w.Code)
 	}
 
-	AuthHandler(authService, []model.UserRole{model.RoleCandidate})(ctx)
-	if w.Code != http.StatusForbidden {
-		t.Errorf("expected status code 401, got %d", w.Code)
+	// Rejected requests are rendered as problems rather than with an empty body.
+	for _, tc := range []struct {
+		authService service.AuthServiceInterface
+		status      int
+		code        string
+	}{
+		{&mockedAuthServiceForHR{}, http.StatusForbidden, "forbidden"},
+		{&mockedAuthServiceForAnonymous{}, http.StatusUnauthorized, "unauthorized"},
+	} {
+		w := request(tc.authService, []model.UserRole{model.RoleCandidate})
+
+		var problem apperror.Problem
+		if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+			t.Fatalf("expected a problem, got %q", w.Body.String())
+		}
+		if w.Code != tc.status || problem.Code != tc.code {
+			t.Errorf("expected %d %s, got %d %+v", tc.status, tc.code, w.Code, problem)
+		}
 	}
 }
@@ -1,61 +1,186 @@
 package handler
 
 import (
+	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/validator/v10"
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/gorm"
 
-	"fliqt/internal/repository"
-	"fliqt/internal/service"
+	"fliqt/internal/apperror"
+	"fliqt/internal/i18n"
 )
 
 var (
-	ErrNotFound   = errors.New("not found")
-	ErrBadRequest = errors.New("bad request")
-	ErrForbidden  = errors.New("forbidden")
-)
-
-var errStatusMap = map[error]int{
-	gorm.ErrRecordNotFound: http.StatusNotFound,
-	ErrNotFound:            http.StatusNotFound,
-	ErrBadRequest:          http.StatusBadRequest,
-	ErrForbidden:           http.StatusForbidden,
+	ErrNotFound   = apperror.ErrNotFound
+	ErrBadRequest = apperror.ErrBadRequest
+	ErrForbidden  = apperror.New(http.StatusForbidden, "forbidden", "you are not allowed to access the resource")
 
-	repository.ErrJobSalaryRange: http.StatusBadRequest,
+	ErrUnsupportedMediaType = apperror.New(http.StatusUnsupportedMediaType, "unsupported_media_type", "the Content-Type of the request is not supported")
+)
 
-	service.ErrUnauthorized: http.StatusUnauthorized,
-	service.ErrFailedTOTP:   http.StatusUnauthorized,
+func init() {
+	// Invalid fields are named as in requests rather than as in Go.
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			for _, tag := range []string{"json", "form", "uri"} {
+				name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+				if name == "-" {
+					return ""
+				}
+				if name != "" {
+					return name
+				}
+			}
+			return field.Name
+		})
+	}
 }
 
+// ErrorHandler is a handler that renders the first error of a request as an RFC 7807 problem. Errors which
+// aren't an apperror.Error are hidden behind a generic one, so messages of GORM and others don't leak.
 func ErrorHandler(logger *zerolog.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Next()
 
-		if len(c.Errors) > 0 {
-			for _, err := range c.Errors {
-				logger.Error().Err(err).Msg("error occurred")
-
-				status := http.StatusInternalServerError
+		if len(c.Errors) == 0 {
+			return
+		}
 
-				var ve validator.ValidationErrors
-				if errors.As(err, &ve) {
-					status = http.StatusBadRequest
-				} else if s, ok := errStatusMap[err.Err]; ok {
-					status = s
-				}
+		for _, err := range c.Errors {
+			logger.Error().Err(err.Err).Msg("error occurred")
+		}
 
-				c.JSON(status, gin.H{"error": err.Error()})
-			}
+		// Streams may fail after their response has started, it can't be replaced anymore.
+		if c.Writer.Written() {
+			return
 		}
+
+		renderProblem(c, toAppError(c.Errors[0], localizerFrom(c)))
 	}
 }
 
 func NotFoundHandler() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+		renderProblem(c, ErrNotFound)
+	}
+}
+
+func renderProblem(c *gin.Context, err *apperror.Error) {
+	var traceID string
+	if spanContext := trace.SpanContextFromContext(c.Request.Context()); spanContext.HasTraceID() {
+		traceID = spanContext.TraceID().String()
+	}
+
+	// Errors are translated by their codes, the English message is kept when a locale lacks one.
+	localized := err.WithMessage(localizerFrom(c).T(err.Code, err.Message))
+	problem := apperror.NewProblem(localized, c.Request.URL.Path, traceID)
+
+	c.Header("Content-Type", apperror.ProblemContentType)
+	c.Status(err.Status)
+	if err := json.NewEncoder(c.Writer).Encode(problem); err != nil {
+		c.Error(err)
+	}
+}
+
+// toAppError translates errors of libraries into application errors.
+func toAppError(ginErr *gin.Error, localizer *i18n.Localizer) *apperror.Error {
+	err := ginErr.Err
+	if appErr, ok := apperror.As(err); ok {
+		return appErr
+	}
+
+	var validationErrors validator.ValidationErrors
+	var syntaxError *json.SyntaxError
+	var typeError *json.UnmarshalTypeError
+	var numError *strconv.NumError
+
+	switch {
+	case errors.As(err, &validationErrors):
+		details := make([]apperror.FieldError, 0, len(validationErrors))
+		for _, fieldError := range validationErrors {
+			details = append(details, apperror.FieldError{
+				Field:   fieldName(fieldError),
+				Code:    fieldError.Tag(),
+				Message: validationMessage(localizer, fieldError),
+			})
+		}
+		return apperror.ErrValidation.Wrap(err).WithDetails(details...)
+	case errors.As(err, &typeError):
+		return apperror.ErrValidation.Wrap(err).WithDetails(apperror.FieldError{
+			Field:   typeError.Field,
+			Code:    "type",
+			Message: localizer.T("validation.type."+jsonTypeName(typeError.Type), "is invalid"),
+		})
+	// Requests which can't be parsed at all, gin marks errors of Bind* as bind errors.
+	case ginErr.IsType(gin.ErrorTypeBind), errors.As(err, &syntaxError), errors.As(err, &numError),
+		errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return ErrBadRequest.Wrap(err)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrNotFound.Wrap(err)
+	}
+
+	return apperror.ErrInternal.Wrap(err)
+}
+
+// fieldName is the path of the field in the request without the name of the request struct, e.g. parts[0].etag.
+func fieldName(fieldError validator.FieldError) string {
+	_, name, ok := strings.Cut(fieldError.Namespace(), ".")
+	if !ok {
+		return fieldError.Field()
+	}
+	return name
+}
+
+func validationMessage(localizer *i18n.Localizer, fieldError validator.FieldError) string {
+	param := fieldError.Param()
+
+	key := fieldError.Tag()
+	switch key {
+	case "required", "url", "gt", "gte", "lt", "lte":
+	case "required_if":
+		// required_if=LegalHold true
+		param = strings.Replace(param, " ", " = ", 1)
+	case "oneof":
+		param = localizer.List(strings.Fields(param))
+	case "min", "max":
+		// min and max are values of numbers but lengths of strings and lists
+		switch fieldError.Kind() {
+		case reflect.String:
+			key += "_length"
+		case reflect.Slice, reflect.Array, reflect.Map:
+			key += "_items"
+		}
+	default:
+		key = "invalid"
+	}
+
+	return localizer.T("validation."+key, "is invalid", param)
+}
+
+// jsonTypeName is the name of a JSON type in validation.type.<name> messages.
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "int"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "list"
 	}
+	return "object"
 }
@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+
+	"fliqt/internal/apperror"
+	"fliqt/internal/repository"
+)
+
+func TestErrorHandler(t *testing.T) {
+	logger := zerolog.Nop()
+
+	app := gin.New()
+	app.Use(ErrorHandler(&logger))
+	app.NoRoute(NotFoundHandler())
+	app.POST("/jobs", func(c *gin.Context) {
+		var req repository.CreateJobDTO
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Error(err)
+			return
+		}
+	})
+	app.GET("/internal", func(c *gin.Context) {
+		c.Error(errors.New("Error 1146: Table 'fliqt.jobs' doesn't exist"))
+		c.Error(ErrForbidden)
+	})
+
+	request := func(method string, path string, body string) (*httptest.ResponseRecorder, apperror.Problem) {
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+
+		var problem apperror.Problem
+		if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+			t.Fatalf("expected a single problem, got %s", w.Body.String())
+		}
+		if w.Header().Get("Content-Type") != apperror.ProblemContentType {
+			t.Errorf("expected %s, got %s", apperror.ProblemContentType, w.Header().Get("Content-Type"))
+		}
+		return w, problem
+	}
+
+	t.Run("Validation", func(t *testing.T) {
+		w, problem := request(http.MethodPost, "/jobs", `{"title":"Engineer","job_type":"intern","salary_min":1}`)
+		if w.Code != http.StatusBadRequest || problem.Code != "validation_failed" {
+			t.Fatalf("expected validation_failed, got %d %+v", w.Code, problem)
+		}
+
+		messages := map[string]string{}
+		for _, fieldError := range problem.Errors {
+			messages[fieldError.Field] = fieldError.Message
+		}
+		expected := map[string]string{
+			"company":    "is required",
+			"job_type":   "must be one of full-time, part-time, contract",
+			"salary_max": "is required",
+		}
+		for field, message := range expected {
+			if messages[field] != message {
+				t.Errorf("expected %s to be %q, got %q", field, message, messages[field])
+			}
+		}
+	})
+
+	t.Run("WrongType", func(t *testing.T) {
+		_, problem := request(http.MethodPost, "/jobs", `{"salary_min":"a lot"}`)
+		if len(problem.Errors) != 1 || problem.Errors[0].Field != "salary_min" || problem.Errors[0].Message != "must be an integer" {
+			t.Errorf("expected salary_min to be invalid, got %+v", problem.Errors)
+		}
+	})
+
+	t.Run("MalformedBody", func(t *testing.T) {
+		w, problem := request(http.MethodPost, "/jobs", `{`)
+		if w.Code != http.StatusBadRequest || problem.Code != "bad_request" {
+			t.Errorf("expected bad_request, got %d %+v", w.Code, problem)
+		}
+	})
+
+	t.Run("InternalError", func(t *testing.T) {
+		w, problem := request(http.MethodGet, "/internal", "")
+		if w.Code != http.StatusInternalServerError || problem.Code != "internal_error" {
+			t.Errorf("expected internal_error, got %d %+v", w.Code, problem)
+		}
+		if strings.Contains(w.Body.String(), "Table") {
+			t.Errorf("expected the cause to be hidden, got %s", w.Body.String())
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		w, problem := request(http.MethodGet, "/missing", "")
+		if w.Code != http.StatusNotFound || problem.Code != "not_found" || problem.Instance != "/missing" {
+			t.Errorf("expected not_found, got %d %+v", w.Code, problem)
+		}
+	})
+}
+
+func TestErrorHandlerLocalized(t *testing.T) {
+	logger := zerolog.Nop()
+
+	app := gin.New()
+	app.Use(Localization(), ErrorHandler(&logger))
+	app.POST("/jobs", func(c *gin.Context) {
+		var req repository.CreateJobDTO
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Error(err)
+			return
+		}
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(`{"title":"Engineer","company":"fliQt","job_type":"intern","salary_min":1,"salary_max":2}`))
+	req.Header.Set("Accept-Language", "ja-JP,ja;q=0.9,en;q=0.8")
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, req)
+
+	var problem apperror.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("expected a problem, got %s", w.Body.String())
+	}
+	if w.Header().Get("Content-Language") != "ja" {
+		t.Errorf("expected Content-Language ja, got %q", w.Header().Get("Content-Language"))
+	}
+	if problem.Code != "validation_failed" || problem.Detail != "一部の項目が正しくありません" {
+		t.Errorf("expected a Japanese problem, got %+v", problem)
+	}
+	if len(problem.Errors) != 1 || problem.Errors[0].Message != "は full-time、part-time、contract のいずれかである必要があります" {
+		t.Errorf("expected a Japanese field error, got %+v", problem.Errors)
+	}
+}
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"fliqt/internal/apperror"
+)
+
+var ErrPreconditionRequired = apperror.New(http.StatusPreconditionRequired, "precondition_required", "If-Match is required, use the ETag of the resource")
+
+// versionETag is the ETag of a version of a resource. Resources with several representations, such as jobs in
+// several languages, tell them apart with variant, e.g. "3.ja". Either way it's a strong ETag.
+func versionETag(version int64, variant string) string {
+	if variant == "" {
+		return `"` + strconv.FormatInt(version, 10) + `"`
+	}
+	return `"` + strconv.FormatInt(version, 10) + "." + variant + `"`
+}
+
+// parseVersionETag returns the version of an ETag made by versionETag.
+func parseVersionETag(etag string) (int64, bool) {
+	etag, ok := strings.CutPrefix(strings.TrimSpace(etag), `"`)
+	if !ok {
+		return 0, false
+	}
+	etag, ok = strings.CutSuffix(etag, `"`)
+	if !ok {
+		return 0, false
+	}
+
+	version, _, _ := strings.Cut(etag, ".")
+	v, err := strconv.ParseInt(version, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return v, true
+}
+
+// ifMatchVersions returns the versions of If-Match which a resource may be at to be changed. It's nil for `*`,
+// which matches any version. If-Match is required, so changes made since the client fetched the resource
+// aren't silently overwritten.
+func ifMatchVersions(c *gin.Context) ([]int64, error) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" {
+		return nil, ErrPreconditionRequired
+	}
+	if header == "*" {
+		return nil, nil
+	}
+
+	versions := []int64{}
+	for _, etag := range strings.Split(header, ",") {
+		// Weak ETags never match If-Match
+		if version, ok := parseVersionETag(etag); ok {
+			versions = append(versions, version)
+		}
+	}
+	if len(versions) == 0 {
+		return nil, apperror.ErrPreconditionFailed
+	}
+
+	return versions, nil
+}
+
+// notModified sets the ETag of the response, and responds 304 when If-None-Match has it. It reports whether
+// the response is complete.
+func notModified(c *gin.Context, etag string) bool {
+	c.Header("ETag", etag)
+
+	header := c.GetHeader("If-None-Match")
+	if header == "" {
+		return false
+	}
+
+	for _, candidate := range strings.Split(header, ",") {
+		// If-None-Match compares ETags weakly
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			c.Status(http.StatusNotModified)
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"fliqt/internal/apperror"
+)
+
+func TestIfMatchVersions(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		versions []int64
+		err      error
+	}{
+		{"Missing", "", nil, ErrPreconditionRequired},
+		{"Any", "*", nil, nil},
+		{"Version", `"3"`, []int64{3}, nil},
+		{"Variant", `"3.ja"`, []int64{3}, nil},
+		{"Several", `"3", W/"4", "5.en"`, []int64{3, 5}, nil},
+		{"Weak", `W/"3"`, nil, apperror.ErrPreconditionFailed},
+		{"Garbage", `"three"`, nil, apperror.ErrPreconditionFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodPut, "/jobs/1", nil)
+			if tt.header != "" {
+				c.Request.Header.Set("If-Match", tt.header)
+			}
+
+			versions, err := ifMatchVersions(c)
+			if err != tt.err {
+				t.Errorf("expected %v, got %v", tt.err, err)
+			}
+			if !reflect.DeepEqual(versions, tt.versions) {
+				t.Errorf("expected %v, got %v", tt.versions, versions)
+			}
+		})
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	app := gin.New()
+	app.GET("/jobs/1", func(c *gin.Context) {
+		if notModified(c, versionETag(3, "ja")) {
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"id": "1"})
+	})
+
+	get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/jobs/1", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := get(""); w.Code != http.StatusOK || w.Header().Get("ETag") != `"3.ja"` {
+		t.Errorf("expected 200 with the ETag, got %d %q", w.Code, w.Header().Get("ETag"))
+	}
+	if w := get(`W/"3.ja"`); w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("expected 304, got %d %s", w.Code, w.Body.String())
+	}
+	if w := get(`"3.en"`); w.Code != http.StatusOK {
+		t.Errorf("expected 200 for another representation, got %d", w.Code)
+	}
+}
@@ -2,51 +2,120 @@ package handler
 
 import (
 	"fmt"
+	"net/http"
+	"path"
+	"slices"
 	"strings"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/gin-gonic/gin"
 	"github.com/rs/xid"
 
 	"fliqt/config"
+	"fliqt/internal/apperror"
 	"fliqt/internal/model"
+	"fliqt/internal/repository"
 	"fliqt/internal/service"
 )
 
-const (
-	// 5MB
-	maxFileSize = 5 * 1024 * 1024
-	// pdf, image, docx, doc only
-	allowedFileTypes = "application/pdf,image/jpeg,image/png,application/vnd.openxmlformats-officedocument.wordprocessingml.document,application/msword"
+var (
+	ErrFileTooLarge         = apperror.New(http.StatusRequestEntityTooLarge, "file_too_large", "file size exceeds the limit")
+	ErrFileTypeNotAllowed   = apperror.New(http.StatusUnsupportedMediaType, "file_type_not_allowed", "file type is not allowed")
+	ErrMultipartRequired    = apperror.New(http.StatusBadRequest, "multipart_required", "file size exceeds the single upload limit, use multipart upload instead")
+	ErrMultipartNotRequired = apperror.New(http.StatusBadRequest, "multipart_not_required", "file size is below the multipart upload threshold, use single upload instead")
 )
 
+var (
+	documentFileTypes = []string{"application/pdf", "application/vnd.openxmlformats-officedocument.wordprocessingml.document", "application/msword"}
+	imageFileTypes    = []string{"image/jpeg", "image/png"}
+	videoFileTypes    = []string{"video/mp4", "video/quicktime", "video/webm"}
+	archiveFileTypes  = []string{"application/zip"}
+)
+
+type uploadPolicy struct {
+	maxSize      int64
+	allowedTypes []string
+}
+
 type FileHandler struct {
-	cfg         *config.Config
-	authService service.AuthServiceInterface
-	s3Service   service.S3ServiceInterface
+	cfg              *config.Config
+	downloadLogRepo  *repository.DownloadLogRepository
+	authService      service.AuthServiceInterface
+	s3Service        service.S3ServiceInterface
+	watermarkService service.WatermarkServiceInterface
+	policies         map[model.FilePurpose]uploadPolicy
 }
 
 func NewFileHandler(
 	cfg *config.Config,
+	downloadLogRepo *repository.DownloadLogRepository,
 	authService service.AuthServiceInterface,
 	s3Service service.S3ServiceInterface,
+	watermarkService service.WatermarkServiceInterface,
 ) *FileHandler {
 	return &FileHandler{
 		cfg,
+		downloadLogRepo,
 		authService,
 		s3Service,
+		watermarkService,
+		map[model.FilePurpose]uploadPolicy{
+			// pdf, image, docx, doc only
+			model.FilePurposeResume: {
+				maxSize:      cfg.UploadMaxSizeResume,
+				allowedTypes: slices.Concat(documentFileTypes, imageFileTypes),
+			},
+			model.FilePurposePortfolio: {
+				maxSize:      cfg.UploadMaxSizePortfolio,
+				allowedTypes: slices.Concat(documentFileTypes, imageFileTypes, archiveFileTypes),
+			},
+			model.FilePurposeVideo: {
+				maxSize:      cfg.UploadMaxSizeVideo,
+				allowedTypes: videoFileTypes,
+			},
+			model.FilePurposeDesignSample: {
+				maxSize:      cfg.UploadMaxSizeDesignSample,
+				allowedTypes: slices.Concat([]string{"application/pdf", "image/svg+xml"}, imageFileTypes, archiveFileTypes),
+			},
+		},
 	}
 }
 
 type UploadFileRequest struct {
-	ContentType string `json:"content_type" binding:"required"`
-	FileName    string `json:"file_name" binding:"required"`
-	FileSize    int64  `json:"file_size" binding:"required"`
+	ContentType string            `json:"content_type" binding:"required"`
+	FileName    string            `json:"file_name" binding:"required"`
+	FileSize    int64             `json:"file_size" binding:"required,gt=0"`
+	Purpose     model.FilePurpose `json:"purpose" binding:"omitempty,oneof=resume portfolio video design_sample"`
 }
 
 type UploadFileResponse struct {
 	ObjectKey string                 `json:"object_key"`
 	URL       string                 `json:"url"`
 	Metadata  map[string]interface{} `json:"metadata"`
+	// Headers must be sent along with the upload, they're set when the file is encrypted.
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// validateUpload checks the file against the limits of its purpose.
+func (h *FileHandler) validateUpload(req *UploadFileRequest) error {
+	if req.Purpose == "" {
+		req.Purpose = model.FilePurposeResume
+	}
+
+	policy, ok := h.policies[req.Purpose]
+	if !ok {
+		return ErrBadRequest
+	}
+
+	if req.FileSize > policy.maxSize {
+		return ErrFileTooLarge
+	}
+
+	if !slices.Contains(policy.allowedTypes, req.ContentType) {
+		return ErrFileTypeNotAllowed
+	}
+
+	return nil
 }
 
 func (h *FileHandler) GetUploadInfo(ctx *gin.Context) {
@@ -62,29 +131,189 @@ func (h *FileHandler) GetUploadInfo(ctx *gin.Context) {
 		return
 	}
 
-	if req.FileSize > maxFileSize || strings.Contains(allowedFileTypes, req.ContentType) {
-		ctx.Error(ErrBadRequest)
+	if err := h.validateUpload(&req); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	if req.FileSize > h.cfg.S3MultipartThreshold {
+		ctx.Error(ErrMultipartRequired)
 		return
 	}
 
 	ID := xid.New().String()
 	objectKey := fmt.Sprintf("%s/%s", user.ID, ID)
 
-	URL, err := h.s3Service.PresignUpload(ctx, h.cfg.S3Bucket, user.ID, objectKey, req.ContentType, req.FileSize)
+	upload, err := h.s3Service.PresignUpload(ctx, h.cfg.S3Bucket, user.ID, objectKey, req.ContentType, req.FileSize)
 	if err != nil {
 		ctx.Error(err)
 		return
 	}
 
+	// The presigned URL may be reused from a previous request, so the object key comes along with it.
 	ctx.JSON(200, UploadFileResponse{
-		ObjectKey: objectKey,
-		URL:       URL,
+		ObjectKey: upload.ObjectKey,
+		URL:       upload.URL,
+		Metadata:  nil,
+		Headers:   upload.Headers,
+	})
+}
+
+type MultipartUploadResponse struct {
+	ObjectKey string                  `json:"object_key"`
+	UploadID  string                  `json:"upload_id"`
+	PartSize  int64                   `json:"part_size"`
+	PartCount int32                   `json:"part_count"`
+	Parts     []service.PresignedPart `json:"parts"`
+	Uploaded  []service.UploadedPart  `json:"uploaded"`
+}
+
+// CreateMultipartUpload initiates a multipart upload and returns presigned URLs of all parts.
+func (h *FileHandler) CreateMultipartUpload(ctx *gin.Context) {
+	var req UploadFileRequest
+	if err := ctx.BindJSON(&req); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	user, err := h.authService.CurrentUser(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	if err := h.validateUpload(&req); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	if req.FileSize <= h.cfg.S3MultipartThreshold {
+		ctx.Error(ErrMultipartNotRequired)
+		return
+	}
+
+	ID := xid.New().String()
+	objectKey := fmt.Sprintf("%s/%s", user.ID, ID)
+
+	upload, err := h.s3Service.CreateMultipartUpload(ctx, h.cfg.S3Bucket, user.ID, objectKey, req.ContentType, req.FileSize)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	parts, _, err := h.s3Service.PresignUploadParts(ctx, h.cfg.S3Bucket, user.ID, upload.UploadID, nil)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, MultipartUploadResponse{
+		ObjectKey: upload.ObjectKey,
+		UploadID:  upload.UploadID,
+		PartSize:  upload.PartSize,
+		PartCount: upload.PartCount,
+		Parts:     parts,
+		Uploaded:  []service.UploadedPart{},
+	})
+}
+
+type MultipartPartsRequest struct {
+	UploadID    string  `json:"upload_id" binding:"required"`
+	PartNumbers []int32 `json:"part_numbers"`
+}
+
+// PresignMultipartParts renews the presigned URLs of an upload, which is how clients resume an
+// interrupted upload. Without part numbers, every part which hasn't been uploaded is presigned.
+func (h *FileHandler) PresignMultipartParts(ctx *gin.Context) {
+	var req MultipartPartsRequest
+	if err := ctx.BindJSON(&req); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	user, err := h.authService.CurrentUser(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	parts, uploaded, err := h.s3Service.PresignUploadParts(ctx, h.cfg.S3Bucket, user.ID, req.UploadID, req.PartNumbers)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	if uploaded == nil {
+		uploaded = []service.UploadedPart{}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"parts":    parts,
+		"uploaded": uploaded,
+	})
+}
+
+type CompleteMultipartUploadRequest struct {
+	UploadID string                 `json:"upload_id" binding:"required"`
+	Parts    []service.UploadedPart `json:"parts" binding:"required,dive"`
+}
+
+func (h *FileHandler) CompleteMultipartUpload(ctx *gin.Context) {
+	var req CompleteMultipartUploadRequest
+	if err := ctx.BindJSON(&req); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	user, err := h.authService.CurrentUser(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	upload, err := h.s3Service.CompleteMultipartUpload(ctx, h.cfg.S3Bucket, user.ID, req.UploadID, req.Parts)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, UploadFileResponse{
+		ObjectKey: upload.ObjectKey,
 		Metadata:  nil,
 	})
 }
 
+type AbortMultipartUploadRequest struct {
+	UploadID string `json:"upload_id" binding:"required"`
+}
+
+func (h *FileHandler) AbortMultipartUpload(ctx *gin.Context) {
+	var req AbortMultipartUploadRequest
+	if err := ctx.BindJSON(&req); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	user, err := h.authService.CurrentUser(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	if err := h.s3Service.AbortMultipartUpload(ctx, h.cfg.S3Bucket, user.ID, req.UploadID); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// GetDownloadInfo returns a presigned URL to download a file. Encrypted files can only be downloaded
+// with the returned headers, or streamed through the API with `stream=true`, which decrypts them on the fly.
+// HR and interviewers can ask for a watermarked PDF with `watermark=true`. Every download is logged,
+// and the ID of the log is returned in the `X-Download-ID` header.
 func (h *FileHandler) GetDownloadInfo(ctx *gin.Context) {
-	objectKey := ctx.Param("object_key")
+	objectKey := strings.TrimPrefix(ctx.Param("object_key"), "/")
 
 	user, err := h.authService.CurrentUser(ctx)
 	if err != nil {
@@ -92,7 +321,7 @@ func (h *FileHandler) GetDownloadInfo(ctx *gin.Context) {
 		return
 	}
 
-	if user.Role == model.RoleCandidate && !strings.HasPrefix(objectKey, fmt.Sprintf("/%s", user.ID)) {
+	if user.Role == model.RoleCandidate && !strings.HasPrefix(objectKey, fmt.Sprintf("%s/", user.ID)) {
 		ctx.Error(ErrForbidden)
 		return
 	}
@@ -111,13 +340,76 @@ func (h *FileHandler) GetDownloadInfo(ctx *gin.Context) {
 		}
 	}
 
-	URL, err := h.s3Service.GetPresignDownloadURL(ctx, h.cfg.S3Bucket, objectKey)
+	watermarked := user.Role != model.RoleCandidate && ctx.Query("watermark") == "true"
+
+	downloadLog := model.DownloadLog{
+		Base: model.Base{
+			ID: xid.New().String(),
+		},
+		ObjectKey:   objectKey,
+		UserID:      user.ID,
+		Watermarked: watermarked,
+		ClientIP:    ctx.ClientIP(),
+		UserAgent:   ctx.Request.UserAgent(),
+	}
+	if err := h.downloadLogRepo.CreateDownloadLog(ctx, &downloadLog); err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.Header("X-Download-ID", downloadLog.ID)
+
+	if watermarked {
+		h.streamWatermarkedObject(ctx, objectKey, user, downloadLog)
+		return
+	}
+
+	if ctx.Query("stream") == "true" {
+		h.streamObject(ctx, objectKey)
+		return
+	}
+
+	download, err := h.s3Service.GetPresignDownloadURL(ctx, h.cfg.S3Bucket, objectKey)
 	if err != nil {
 		ctx.Error(err)
 		return
 	}
 
-	ctx.JSON(200, gin.H{
-		"url": URL,
+	ctx.JSON(200, download)
+}
+
+// streamWatermarkedObject renders the object as a PDF stamped with the viewer's identity.
+func (h *FileHandler) streamWatermarkedObject(ctx *gin.Context, objectKey string, user *model.User, downloadLog model.DownloadLog) {
+	object, err := h.s3Service.GetObject(ctx, h.cfg.S3Bucket, objectKey)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	defer object.Body.Close()
+
+	pdf, err := h.watermarkService.Render(ctx, object.Body, service.Watermark{
+		DownloadID: downloadLog.ID,
+		ViewerID:   user.ID,
+		ViewerRole: string(user.Role),
+		Timestamp:  downloadLog.CreatedAt,
 	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", path.Base(objectKey)+".pdf"))
+	ctx.Data(http.StatusOK, "application/pdf", pdf)
+}
+
+// streamObject proxies the object from S3 to the client.
+func (h *FileHandler) streamObject(ctx *gin.Context, objectKey string) {
+	object, err := h.s3Service.GetObject(ctx, h.cfg.S3Bucket, objectKey)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	defer object.Body.Close()
+
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", path.Base(objectKey)))
+	ctx.DataFromReader(http.StatusOK, aws.ToInt64(object.ContentLength), aws.ToString(object.ContentType), object.Body, nil)
 }
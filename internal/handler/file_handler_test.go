@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"testing"
+
+	"fliqt/config"
+	"fliqt/internal/model"
+)
+
+func TestFileHandlerValidateUpload(t *testing.T) {
+	cfg := &config.Config{
+		UploadMaxSizeResume: 5 * 1024 * 1024,
+		UploadMaxSizeVideo:  500 * 1024 * 1024,
+	}
+	h := NewFileHandler(cfg, nil, &mockedAuthServiceForHR{}, nil, nil)
+
+	req := UploadFileRequest{ContentType: "application/pdf", FileName: "resume.pdf", FileSize: 1024}
+	if err := h.validateUpload(&req); err != nil {
+		t.Errorf("expected nil, got %v", err)
+	}
+	if req.Purpose != model.FilePurposeResume {
+		t.Errorf("expected purpose to default to resume, got %s", req.Purpose)
+	}
+
+	req = UploadFileRequest{ContentType: "application/pdf", FileName: "resume.pdf", FileSize: 6 * 1024 * 1024}
+	if err := h.validateUpload(&req); err != ErrFileTooLarge {
+		t.Errorf("expected ErrFileTooLarge, got %v", err)
+	}
+
+	req = UploadFileRequest{ContentType: "video/mp4", FileName: "resume.mp4", FileSize: 1024}
+	if err := h.validateUpload(&req); err != ErrFileTypeNotAllowed {
+		t.Errorf("expected ErrFileTypeNotAllowed, got %v", err)
+	}
+
+	req = UploadFileRequest{ContentType: "video/mp4", FileName: "intro.mp4", FileSize: 300 * 1024 * 1024, Purpose: model.FilePurposeVideo}
+	if err := h.validateUpload(&req); err != nil {
+		t.Errorf("expected nil, got %v", err)
+	}
+}
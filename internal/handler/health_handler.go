@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"fliqt/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthHandler struct {
+	healthService service.HealthServiceInterface
+}
+
+func NewHealthHandler(
+	healthService service.HealthServiceInterface,
+) *HealthHandler {
+	return &HealthHandler{
+		healthService,
+	}
+}
+
+// Healthz reports the process is alive, dependencies aren't checked so an outage doesn't restart every replica.
+// Probes aren't traced, they'd flood the tracer.
+func (h *HealthHandler) Healthz(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"status": service.HealthStatusOK})
+}
+
+// Readyz reports whether the replica can serve requests, with the status of each dependency
+func (h *HealthHandler) Readyz(ctx *gin.Context) {
+	report := h.healthService.Ready(ctx.Request.Context())
+
+	status := http.StatusOK
+	if report.Status != service.HealthStatusOK {
+		status = http.StatusServiceUnavailable
+	}
+
+	ctx.JSON(status, report)
+}
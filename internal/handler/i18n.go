@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"fliqt/internal/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+const localizerContextKey = "localizer"
+
+// Localization is a handler that negotiates the locale of the response with Accept-Language. The preferred
+// supported locale is announced with Content-Language, handlers get the fallback chain with localizerFrom.
+func Localization() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		localizer := i18n.NewLocalizer(i18n.Negotiate(c.GetHeader("Accept-Language"))...)
+
+		c.Set(localizerContextKey, localizer)
+		c.Header("Content-Language", localizer.Locale())
+		c.Writer.Header().Add("Vary", "Accept-Language")
+
+		c.Next()
+	}
+}
+
+// localizerFrom returns the localizer of the request, it's English when Localization isn't used.
+func localizerFrom(c *gin.Context) *i18n.Localizer {
+	if localizer, ok := c.Get(localizerContextKey); ok {
+		return localizer.(*i18n.Localizer)
+	}
+	return i18n.NewLocalizer(i18n.DefaultLocale)
+}
@@ -0,0 +1,215 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fliqt/config"
+	"fliqt/internal/apperror"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/rs/zerolog"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+	idempotencyStatusProgress = "in_progress"
+	idempotencyStatusDone     = "completed"
+)
+
+var (
+	ErrInvalidIdempotencyKey    = apperror.New(http.StatusBadRequest, "invalid_idempotency_key", "idempotency key must be at most 255 characters")
+	ErrIdempotencyKeyMismatch   = apperror.New(http.StatusUnprocessableEntity, "idempotency_key_mismatch", "idempotency key was used with a different request")
+	ErrIdempotencyKeyInProgress = apperror.New(http.StatusConflict, "idempotency_key_in_progress", "a request with the idempotency key is in progress")
+	ErrIdempotencyBodyTooLarge  = apperror.New(http.StatusRequestEntityTooLarge, "idempotency_body_too_large", "request body is too large for an idempotency key")
+)
+
+// idempotencyRecord is stored in Redis under the idempotency key, the response is set once it's completed.
+type idempotencyRecord struct {
+	Status      string      `json:"status"`
+	Fingerprint string      `json:"fingerprint"`
+	StatusCode  int         `json:"status_code,omitempty"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        []byte      `json:"body,omitempty"`
+}
+
+// idempotencyWriter keeps a copy of the response, so it can be replayed. Responses larger than maxSize aren't
+// kept.
+type idempotencyWriter struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	maxSize  int64
+	overflow bool
+}
+
+func (w *idempotencyWriter) keep(size int) bool {
+	if int64(w.body.Len()+size) > w.maxSize {
+		w.overflow = true
+		w.body.Reset()
+	}
+	return !w.overflow
+}
+
+func (w *idempotencyWriter) Write(data []byte) (int, error) {
+	if w.keep(len(data)) {
+		w.body.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *idempotencyWriter) WriteString(s string) (int, error) {
+	if w.keep(len(s)) {
+		w.body.WriteString(s)
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency is a handler that makes POST requests with an Idempotency-Key header safe to retry. The first
+// request runs and its response is replayed to retries with the same key and body, a retry with a different
+// body gets 422 and a retry while the first request is still running gets 409. Keys are scoped by user.
+// Failed requests aren't stored, so they can be retried with the same key. Requests and responses are held in
+// memory, so requests with a body larger than cfg.IdempotencyMaxBodySize are refused, and larger responses
+// aren't stored.
+func Idempotency(cfg *config.Config, redisClient *redis.Client, logger *zerolog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		idempotencyKey := c.GetHeader(IdempotencyKeyHeader)
+		if c.Request.Method != http.MethodPost || idempotencyKey == "" {
+			c.Next()
+			return
+		}
+		if len(idempotencyKey) > maxIdempotencyKeyLength {
+			c.Error(ErrInvalidIdempotencyKey)
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, cfg.IdempotencyMaxBodySize))
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.Error(ErrIdempotencyBodyTooLarge)
+			c.Abort()
+			return
+		}
+		if err != nil {
+			c.Error(ErrBadRequest)
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		fingerprint := idempotencyFingerprint(c.Request.Method, c.Request.URL.RequestURI(), body)
+		key := "idempotency:" + c.GetHeader("X-FLIQT-USER") + ":" + idempotencyKey
+		ctx := c.Request.Context()
+
+		progress, err := json.Marshal(idempotencyRecord{Status: idempotencyStatusProgress, Fingerprint: fingerprint})
+		if err != nil {
+			c.Error(err)
+			c.Abort()
+			return
+		}
+
+		// The key is locked while the request runs, so concurrent requests don't both run.
+		acquired, err := redisClient.SetNX(ctx, key, progress, cfg.IdempotencyLockTimeout).Result()
+		if err != nil {
+			c.Error(err)
+			c.Abort()
+			return
+		}
+
+		if !acquired {
+			var record idempotencyRecord
+			data, err := redisClient.Get(ctx, key).Bytes()
+			if errors.Is(err, redis.Nil) {
+				// The other request failed or its lock expired right now, it's safe to try again.
+				c.Error(ErrIdempotencyKeyInProgress)
+				c.Abort()
+				return
+			}
+			if err == nil {
+				err = json.Unmarshal(data, &record)
+			}
+			if err != nil {
+				c.Error(err)
+				c.Abort()
+				return
+			}
+
+			switch {
+			case record.Fingerprint != fingerprint:
+				c.Error(ErrIdempotencyKeyMismatch)
+				c.Abort()
+			case record.Status != idempotencyStatusDone:
+				c.Error(ErrIdempotencyKeyInProgress)
+				c.Abort()
+			default:
+				for name, values := range record.Header {
+					for _, value := range values {
+						c.Writer.Header().Add(name, value)
+					}
+				}
+				c.Header(IdempotentReplayedHeader, "true")
+				c.Data(record.StatusCode, record.Header.Get("Content-Type"), record.Body)
+				c.Abort()
+			}
+			return
+		}
+
+		writer := &idempotencyWriter{ResponseWriter: c.Writer, maxSize: cfg.IdempotencyMaxBodySize}
+		c.Writer = writer
+
+		c.Next()
+
+		// The response is stored even when the client has gone away, so its retry gets it.
+		ctx = context.WithoutCancel(ctx)
+
+		// Errors are rendered by ErrorHandler after this handler returns, so they can't be replayed.
+		if len(c.Errors) > 0 || writer.Status() >= http.StatusInternalServerError || writer.overflow {
+			if writer.overflow {
+				logger.Warn().Str("idempotency_key", idempotencyKey).Msg("idempotent response is too large to be stored")
+			}
+			if err := redisClient.Del(ctx, key).Err(); err != nil {
+				logger.Error().Err(err).Str("idempotency_key", idempotencyKey).Msg("failed to release idempotency key")
+			}
+			return
+		}
+
+		header := http.Header{}
+		for name, values := range writer.Header() {
+			// Rate limits describe the first request, they'd be misleading on a replay.
+			if strings.HasPrefix(name, "Ratelimit-") || name == "Retry-After" {
+				continue
+			}
+			header[name] = values
+		}
+
+		done, err := json.Marshal(idempotencyRecord{
+			Status:      idempotencyStatusDone,
+			Fingerprint: fingerprint,
+			StatusCode:  writer.Status(),
+			Header:      header,
+			Body:        writer.body.Bytes(),
+		})
+		if err == nil {
+			err = redisClient.Set(ctx, key, done, cfg.IdempotencyKeyTTL).Err()
+		}
+		if err != nil {
+			logger.Error().Err(err).Str("idempotency_key", idempotencyKey).Msg("failed to store idempotent response")
+		}
+	}
+}
+
+func idempotencyFingerprint(method string, uri string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method + " " + uri + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/rs/zerolog"
+
+	"fliqt/config"
+)
+
+func TestIdempotency(t *testing.T) {
+	mr := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	logger := zerolog.Nop()
+	cfg := &config.Config{IdempotencyKeyTTL: time.Hour, IdempotencyLockTimeout: time.Minute, IdempotencyMaxBodySize: 1024}
+
+	created := 0
+	app := gin.New()
+	app.Use(ErrorHandler(&logger), Idempotency(cfg, redisClient, &logger))
+	app.POST("/jobs", func(c *gin.Context) {
+		created++
+		c.JSON(http.StatusCreated, gin.H{"id": created})
+	})
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(body))
+		req.Header.Set("X-FLIQT-USER", "1")
+		req.Header.Set(IdempotencyKeyHeader, "key")
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, req)
+		return w
+	}
+
+	first := post(`{"title":"Engineer"}`)
+	retry := post(`{"title":"Engineer"}`)
+	if created != 1 {
+		t.Errorf("expected the job to be created once, got %d", created)
+	}
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() {
+		t.Errorf("expected the response to be replayed, got %d %s", retry.Code, retry.Body.String())
+	}
+	if retry.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Errorf("expected %s header", IdempotentReplayedHeader)
+	}
+
+	if w := post(`{"title":"Designer"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422 for a different body, got %d", w.Code)
+	}
+
+	mr.Set("idempotency:1:other", `{"status":"in_progress","fingerprint":"`+idempotencyFingerprint(http.MethodPost, "/jobs", []byte("{}"))+`"}`)
+	req := httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader("{}"))
+	req.Header.Set("X-FLIQT-USER", "1")
+	req.Header.Set(IdempotencyKeyHeader, "other")
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, req)
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected 409 while the request is in progress, got %d", w.Code)
+	}
+}
+
+func TestIdempotencyMaxBodySize(t *testing.T) {
+	mr := miniredis.RunT(t)
+	redisClient := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	logger := zerolog.Nop()
+	cfg := &config.Config{IdempotencyKeyTTL: time.Hour, IdempotencyLockTimeout: time.Minute, IdempotencyMaxBodySize: 16}
+
+	created := 0
+	app := gin.New()
+	app.Use(ErrorHandler(&logger), Idempotency(cfg, redisClient, &logger))
+	app.POST("/jobs", func(c *gin.Context) {
+		created++
+		c.JSON(http.StatusCreated, gin.H{"id": created, "title": "Backend Engineer"})
+	})
+
+	post := func(key string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(body))
+		req.Header.Set("X-FLIQT-USER", "1")
+		req.Header.Set(IdempotencyKeyHeader, key)
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := post("large", `{"title":"Backend Engineer"}`); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 for a large body, got %d", w.Code)
+	}
+	if created != 0 {
+		t.Errorf("expected a large request not to run, got %d", created)
+	}
+
+	// The response is larger than the limit, so it isn't stored and the retry runs again
+	post("small", "{}")
+	if w := post("small", "{}"); w.Code != http.StatusCreated || w.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Errorf("expected a large response not to be replayed, got %d", w.Code)
+	}
+	if created != 2 {
+		t.Errorf("expected the request to run twice, got %d", created)
+	}
+}
@@ -1,32 +1,46 @@
 package handler
 
 import (
+	"encoding/json"
+	"fliqt/internal/mergepatch"
+	"fliqt/internal/model"
 	"fliqt/internal/repository"
+	"fliqt/internal/service"
 	"fliqt/internal/util"
 	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/rs/zerolog"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 )
 
 type JobHandler struct {
-	repo   *repository.JobRepository
-	logger *zerolog.Logger
+	repo         *repository.JobRepository
+	facetService service.JobFacetServiceInterface
+	logger       *zerolog.Logger
 }
 
 func NewJobHandler(
 	repo *repository.JobRepository,
+	facetService service.JobFacetServiceInterface,
 	logger *zerolog.Logger,
 ) *JobHandler {
 	return &JobHandler{
-		repo:   repo,
-		logger: logger,
+		repo:         repo,
+		facetService: facetService,
+		logger:       logger,
 	}
 }
 
+// JobListResponse is a page of jobs, along with the facets of the search when they're requested.
+type JobListResponse struct {
+	model.PaginationResponse[repository.JobResponseDTO]
+	Facets *repository.JobFacets `json:"facets,omitempty"`
+}
+
 // ListJobs is a handler for listing all jobs.
 func (h *JobHandler) ListJobs(ctx *gin.Context) {
 	var filterParams repository.JobFilterParams
@@ -45,6 +59,7 @@ func (h *JobHandler) ListJobs(ctx *gin.Context) {
 	defer span.End()
 
 	filterParams.Normalize()
+	filterParams.Locales = localizerFrom(ctx).Locales()
 
 	accounts, err := h.repo.ListJobs(tracerCtx, filterParams)
 
@@ -53,7 +68,16 @@ func (h *JobHandler) ListJobs(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, accounts)
+	response := JobListResponse{PaginationResponse: accounts}
+	if filterParams.Facets {
+		response.Facets, err = h.facetService.GetJobFacets(tracerCtx, filterParams)
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+	}
+
+	ctx.JSON(http.StatusOK, response)
 }
 
 // GetJob is a handler for getting job details.
@@ -66,13 +90,17 @@ func (h *JobHandler) GetJob(ctx *gin.Context) {
 		ctx.Error(ErrNotFound)
 		return
 	}
-	account, err := h.repo.GetJobByID(tracerCtx, id)
+	account, err := h.repo.GetLocalizedJob(tracerCtx, id, localizerFrom(ctx).Locales())
 
 	if err != nil {
 		ctx.Error(err)
 		return
 	}
 
+	if notModified(ctx, versionETag(account.Version, account.Locale)) {
+		return
+	}
+
 	ctx.JSON(http.StatusOK, account)
 }
 
@@ -104,6 +132,7 @@ func (h *JobHandler) CreateJob(ctx *gin.Context) {
 		return
 	}
 
+	ctx.Header("ETag", versionETag(job.Version, job.Locale))
 	ctx.JSON(http.StatusCreated, job)
 }
 
@@ -130,21 +159,230 @@ func (h *JobHandler) UpdateJob(ctx *gin.Context) {
 		return
 	}
 
+	versions, err := ifMatchVersions(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
 	if err := req.Validate(); err != nil {
 		ctx.Error(err)
 		return
 	}
 
-	job, err := h.repo.UpdateJob(tracerCtx, ID, req)
+	job, err := h.repo.UpdateJob(tracerCtx, ID, req, versions)
 
 	if err != nil {
 		ctx.Error(err)
 		return
 	}
 
+	ctx.Header("ETag", versionETag(job.Version, job.Locale))
 	ctx.JSON(http.StatusOK, job)
 }
 
+// PatchJob is a handler for changing some fields of a job with a JSON merge patch (RFC 7396), null clears a
+// field. The patched job is validated as a whole, like the request of UpdateJob.
+func (h *JobHandler) PatchJob(ctx *gin.Context) {
+	if contentType := ctx.ContentType(); contentType != mergepatch.ContentType && contentType != binding.MIMEJSON {
+		ctx.Error(ErrUnsupportedMediaType)
+		return
+	}
+
+	patch, err := ctx.GetRawData()
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	tracerCtx, span := tracer.Start(
+		ctx.Request.Context(),
+		util.GetSpanNameFromCaller(),
+		trace.WithAttributes(
+			attribute.String("id", ctx.Param("id")),
+			attribute.String("patch", string(patch)),
+		),
+	)
+	defer span.End()
+
+	ID := ctx.Param("id")
+	if ID == "" {
+		ctx.Error(ErrNotFound)
+		return
+	}
+
+	versions, err := ifMatchVersions(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	job, err := h.repo.PatchJob(tracerCtx, ID, func(dto *repository.UpdateJobDTO) error {
+		current, err := json.Marshal(dto)
+		if err != nil {
+			return err
+		}
+		merged, err := mergepatch.Apply(current, patch)
+		if err != nil {
+			return err
+		}
+
+		// Fields which the patch removed are left zero, so required ones fail validation
+		var patched repository.UpdateJobDTO
+		if err := json.Unmarshal(merged, &patched); err != nil {
+			return err
+		}
+		if err := binding.Validator.ValidateStruct(patched); err != nil {
+			return err
+		}
+		if err := patched.Validate(); err != nil {
+			return err
+		}
+
+		*dto = patched
+		return nil
+	}, versions)
+
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.Header("ETag", versionETag(job.Version, job.Locale))
+	ctx.JSON(http.StatusOK, job)
+}
+
+type ImportJobsQuery struct {
+	DryRun bool `form:"dry_run"`
+}
+
+// ImportJobs is a handler for creating and updating jobs from a CSV or NDJSON file, jobs are matched by their
+// external references. Jobs are validated and imported one by one, and the report tells how each one went.
+func (h *JobHandler) ImportJobs(ctx *gin.Context) {
+	var query ImportJobsQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	tracerCtx, span := tracer.Start(
+		ctx.Request.Context(),
+		util.GetSpanNameFromCaller(),
+		trace.WithAttributes(
+			attribute.String("content_type", ctx.ContentType()),
+			attribute.Bool("dry_run", query.DryRun),
+		),
+	)
+	defer span.End()
+
+	records, err := readJobImport(ctx.ContentType(), ctx.Request.Body)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	var dtos []repository.ImportJobDTO
+	lines := map[string]int{}
+	for i, record := range records {
+		if record.err == nil {
+			record.err = binding.Validator.ValidateStruct(record.dto)
+		}
+		if record.err == nil {
+			record.err = record.dto.Validate()
+		}
+		if _, ok := lines[record.dto.ExternalRef]; ok && record.err == nil {
+			record.err = ErrDuplicateExternalRef
+		}
+		if record.err == nil {
+			lines[record.dto.ExternalRef] = record.line
+			dtos = append(dtos, record.dto)
+		}
+		records[i] = record
+	}
+
+	results, err := h.repo.ImportJobs(tracerCtx, dtos, query.DryRun)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	localizer := localizerFrom(ctx)
+	report := JobImportReport{DryRun: query.DryRun, Rows: make([]JobImportRow, 0, len(records))}
+	for _, record := range records {
+		row := JobImportRow{Line: record.line, ExternalRef: record.dto.ExternalRef, Status: repository.JobImportFailed}
+		if record.err == nil {
+			result := results[0]
+			results = results[1:]
+
+			row.Status = result.Status
+			record.err = result.Err
+			// Jobs created by dry runs don't exist
+			if result.Job != nil && !(query.DryRun && result.Status == repository.JobImportCreated) {
+				row.JobID = result.Job.ID
+			}
+		}
+		if record.err != nil {
+			row.Error = newJobImportError(record.err, localizer)
+		}
+
+		switch row.Status {
+		case repository.JobImportCreated:
+			report.Created++
+		case repository.JobImportUpdated:
+			report.Updated++
+		case repository.JobImportUnchanged:
+			report.Unchanged++
+		default:
+			report.Failed++
+		}
+		report.Rows = append(report.Rows, row)
+	}
+
+	ctx.JSON(http.StatusOK, report)
+}
+
+type ExportJobsParams struct {
+	repository.JobFilterParams
+
+	Format string `form:"format,omitempty" binding:"omitempty,oneof=csv ndjson"`
+}
+
+// ExportJobs is a handler for streaming the jobs which match the filters as CSV or NDJSON, in the columns
+// ImportJobs reads.
+func (h *JobHandler) ExportJobs(ctx *gin.Context) {
+	var params ExportJobsParams
+	if err := ctx.ShouldBindQuery(&params); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	tracerCtx, span := tracer.Start(
+		ctx.Request.Context(),
+		util.GetSpanNameFromCaller(),
+		trace.WithAttributes(
+			attribute.String("query", fmt.Sprintf("%+v", params)),
+		),
+	)
+	defer span.End()
+
+	params.Locales = localizerFrom(ctx).Locales()
+
+	writer := newJobExportWriter(params.Format, ctx.Writer)
+	ctx.Header("Content-Type", writer.ContentType())
+	ctx.Header("Content-Disposition", `attachment; filename="jobs.`+writer.Extension()+`"`)
+	ctx.Status(http.StatusOK)
+
+	// Once the export has started its status can't be changed, ErrorHandler only logs errors then.
+	err := h.repo.ExportJobs(tracerCtx, params.JobFilterParams, writer.Write)
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		span.RecordError(err)
+		ctx.Error(err)
+	}
+}
+
 func (h *JobHandler) DeleteJob(ctx *gin.Context) {
 	ID := ctx.Param("id")
 	if ID == "" {
@@ -170,3 +408,78 @@ func (h *JobHandler) DeleteJob(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusNoContent, nil)
 }
+
+// ListJobTranslations is a handler for listing every translation of a job.
+func (h *JobHandler) ListJobTranslations(ctx *gin.Context) {
+	tracerCtx, span := tracer.Start(
+		ctx.Request.Context(),
+		util.GetSpanNameFromCaller(),
+		trace.WithAttributes(
+			attribute.String("id", ctx.Param("id")),
+		),
+	)
+	defer span.End()
+
+	translations, err := h.repo.ListJobTranslations(tracerCtx, ctx.Param("id"))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, translations)
+}
+
+// PutJobTranslation is a handler for translating a job into a locale.
+func (h *JobHandler) PutJobTranslation(ctx *gin.Context) {
+	var req repository.PutJobTranslationDTO
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	tracerCtx, span := tracer.Start(
+		ctx.Request.Context(),
+		util.GetSpanNameFromCaller(),
+		trace.WithAttributes(
+			attribute.String("id", ctx.Param("id")),
+			attribute.String("locale", ctx.Param("locale")),
+		),
+	)
+	defer span.End()
+
+	versions, err := ifMatchVersions(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	translation, version, err := h.repo.PutJobTranslation(tracerCtx, ctx.Param("id"), ctx.Param("locale"), req, versions)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	// The translation is the job's representation in the locale
+	ctx.Header("ETag", versionETag(version, translation.Locale))
+	ctx.JSON(http.StatusOK, translation)
+}
+
+// DeleteJobTranslation is a handler for deleting the translation of a job.
+func (h *JobHandler) DeleteJobTranslation(ctx *gin.Context) {
+	tracerCtx, span := tracer.Start(
+		ctx.Request.Context(),
+		util.GetSpanNameFromCaller(),
+		trace.WithAttributes(
+			attribute.String("id", ctx.Param("id")),
+			attribute.String("locale", ctx.Param("locale")),
+		),
+	)
+	defer span.End()
+
+	if err := h.repo.DeleteJobTranslation(tracerCtx, ctx.Param("id"), ctx.Param("locale")); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
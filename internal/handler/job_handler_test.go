@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/rs/zerolog"
+
+	"fliqt/internal/apperror"
+	"fliqt/internal/mergepatch"
+	"fliqt/internal/repository"
+	"fliqt/internal/search"
+	"fliqt/internal/util"
+)
+
+func TestJobHandlerPatchJob(t *testing.T) {
+	db, mock, cleanup := util.SetupMockDB(t)
+	defer cleanup()
+
+	logger := zerolog.Nop()
+	jobHandler := NewJobHandler(repository.NewJobRepository(db, &logger, search.NewMySQLJobSearchIndex()), nil, &logger)
+
+	app := gin.New()
+	app.Use(ErrorHandler(&logger))
+	app.PATCH("/jobs/:id", jobHandler.PatchJob)
+
+	patch := func(contentType string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/jobs/cqanbg8cvavjpljmh7pg", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("If-Match", `"3"`)
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, req)
+		return w
+	}
+
+	columns := []string{"id", "title", "company", "description", "locale", "job_type", "salary_min", "salary_max", "version"}
+	expectJob := func(version int64, description string) {
+		mock.ExpectQuery("SELECT \\* FROM `jobs` WHERE id = \\?").
+			WillReturnRows(sqlmock.NewRows(columns).AddRow("cqanbg8cvavjpljmh7pg", "Engineer", "fliQt", description, "en", "full-time", 1000, 2000, version))
+	}
+
+	t.Run("ClearField", func(t *testing.T) {
+		mock.ExpectBegin()
+		expectJob(3, "Build things")
+		mock.ExpectExec("UPDATE `jobs` SET `description`=\\?,`version`=version \\+ 1,`updated_at`=\\? WHERE id = \\?").
+			WithArgs("", sqlmock.AnyArg(), "cqanbg8cvavjpljmh7pg").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		expectJob(4, "")
+		mock.ExpectExec("INSERT INTO `outbox`").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		w := patch(mergepatch.ContentType, `{"description":null,"company":"fliQt"}`)
+		if w.Code != http.StatusOK || w.Header().Get("ETag") != `"4.en"` {
+			t.Errorf("expected the job to be patched, got %d %q %s", w.Code, w.Header().Get("ETag"), w.Body.String())
+		}
+	})
+
+	t.Run("InvalidResult", func(t *testing.T) {
+		mock.ExpectBegin()
+		expectJob(3, "")
+		mock.ExpectRollback()
+
+		w := patch(mergepatch.ContentType, `{"title":null,"salary_min":3000}`)
+
+		var problem apperror.Problem
+		json.Unmarshal(w.Body.Bytes(), &problem)
+		if w.Code != http.StatusBadRequest || len(problem.Errors) != 1 || problem.Errors[0].Field != "title" {
+			t.Errorf("expected title to be required, got %d %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("SalaryRange", func(t *testing.T) {
+		mock.ExpectBegin()
+		expectJob(3, "")
+		mock.ExpectRollback()
+
+		w := patch(binding.MIMEJSON, `{"salary_min":3000}`)
+
+		var problem apperror.Problem
+		json.Unmarshal(w.Body.Bytes(), &problem)
+		if problem.Code != repository.ErrJobSalaryRange.Code {
+			t.Errorf("expected %s, got %d %s", repository.ErrJobSalaryRange.Code, w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("UnsupportedMediaType", func(t *testing.T) {
+		if w := patch("text/plain", `{}`); w.Code != http.StatusUnsupportedMediaType {
+			t.Errorf("expected 415, got %d", w.Code)
+		}
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
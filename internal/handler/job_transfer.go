@@ -0,0 +1,276 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"fliqt/internal/apperror"
+	"fliqt/internal/i18n"
+	"fliqt/internal/model"
+	"fliqt/internal/repository"
+	"fliqt/internal/util"
+)
+
+const (
+	csvContentType    = "text/csv"
+	ndjsonContentType = "application/x-ndjson"
+
+	// maxJobImportRows is the number of jobs which can be imported at once, they're imported in a transaction.
+	maxJobImportRows = 1000
+	// maxJobImportLineSize is the size of a line of NDJSON imports.
+	maxJobImportLineSize = 1 << 20
+)
+
+var (
+	ErrJobImportTooLarge    = apperror.New(http.StatusRequestEntityTooLarge, "too_many_jobs", "at most 1000 jobs can be imported at once")
+	ErrDuplicateExternalRef = apperror.New(http.StatusBadRequest, "duplicate_external_ref", "the external reference is used by an earlier job of the import")
+)
+
+// jobExportColumns are the columns of CSV exports, they're also the fields of NDJSON exports. Imports read the
+// columns they know, so exports can be imported again.
+var jobExportColumns = []string{
+	"id", "external_ref", "title", "company", "description", "locale", "job_type", "salary_min", "salary_max",
+	"location", "created_at", "updated_at",
+}
+
+// jobImportIntColumns are the CSV columns which are numbers in JSON.
+var jobImportIntColumns = map[string]bool{"salary_min": true, "salary_max": true}
+
+// jobImportRecord is a job of an import file, err tells why it can't be imported.
+type jobImportRecord struct {
+	line int
+	dto  repository.ImportJobDTO
+	err  error
+}
+
+type JobImportError struct {
+	Code    string                `json:"code"`
+	Message string                `json:"message"`
+	Errors  []apperror.FieldError `json:"errors,omitempty"`
+}
+
+type JobImportRow struct {
+	// Line is where the job is in the import file, the header of a CSV file is line 1
+	Line        int             `json:"line"`
+	ExternalRef string          `json:"external_ref,omitempty"`
+	Status      string          `json:"status"`
+	JobID       string          `json:"job_id,omitempty"`
+	Error       *JobImportError `json:"error,omitempty"`
+}
+
+type JobImportReport struct {
+	DryRun    bool           `json:"dry_run"`
+	Created   int            `json:"created"`
+	Updated   int            `json:"updated"`
+	Unchanged int            `json:"unchanged"`
+	Failed    int            `json:"failed"`
+	Rows      []JobImportRow `json:"rows"`
+}
+
+// readJobImport reads the jobs of a CSV or NDJSON import file. Jobs which can't be read are returned with
+// errors, only files which are malformed as a whole fail.
+func readJobImport(contentType string, body io.Reader) ([]jobImportRecord, error) {
+	switch contentType {
+	case csvContentType:
+		return readJobImportCSV(body)
+	case ndjsonContentType:
+		return readJobImportNDJSON(body)
+	}
+
+	return nil, ErrUnsupportedMediaType
+}
+
+func readJobImportCSV(body io.Reader) ([]jobImportRecord, error) {
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, ErrBadRequest.Wrap(err)
+	}
+	for i, name := range header {
+		// Spreadsheets may save CSV files with a byte order mark
+		header[i] = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+	}
+
+	var records []jobImportRecord
+	for {
+		values, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, ErrBadRequest.Wrap(err)
+		}
+		if len(records) == maxJobImportRows {
+			return nil, ErrJobImportTooLarge
+		}
+
+		// Rows are read as JSON objects, so they're decoded and validated like NDJSON and the API. Empty cells
+		// are left out.
+		fields := map[string]interface{}{}
+		for i, name := range header {
+			if i >= len(values) || values[i] == "" {
+				continue
+			}
+			value := util.UnescapeCSVCell(values[i])
+			fields[name] = value
+			if jobImportIntColumns[name] {
+				if number, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+					fields[name] = number
+				}
+			}
+		}
+
+		line, _ := reader.FieldPos(0)
+		record := jobImportRecord{line: line}
+		data, err := json.Marshal(fields)
+		if err == nil {
+			err = json.Unmarshal(data, &record.dto)
+		}
+		record.err = err
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+func readJobImportNDJSON(body io.Reader) ([]jobImportRecord, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(nil, maxJobImportLineSize)
+
+	var records []jobImportRecord
+	for line := 1; scanner.Scan(); line++ {
+		data := scanner.Bytes()
+		if len(strings.TrimSpace(string(data))) == 0 {
+			continue
+		}
+		if len(records) == maxJobImportRows {
+			return nil, ErrJobImportTooLarge
+		}
+
+		record := jobImportRecord{line: line}
+		record.err = json.Unmarshal(data, &record.dto)
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, ErrBadRequest.Wrap(err)
+	}
+
+	return records, nil
+}
+
+// newJobImportError describes why a job can't be imported the way ErrorHandler describes errors of requests.
+func newJobImportError(err error, localizer *i18n.Localizer) *JobImportError {
+	appErr := toAppError(&gin.Error{Err: err}, localizer)
+
+	return &JobImportError{
+		Code:    appErr.Code,
+		Message: localizer.T(appErr.Code, appErr.Message),
+		Errors:  appErr.Details,
+	}
+}
+
+// jobExportWriter writes jobs of an export, Close flushes what's buffered.
+type jobExportWriter interface {
+	ContentType() string
+	Extension() string
+	Write(job model.Job) error
+	Close() error
+}
+
+func newJobExportWriter(format string, w io.Writer) jobExportWriter {
+	if format == "ndjson" {
+		buffered := bufio.NewWriter(w)
+		return &ndjsonJobExportWriter{buffered, json.NewEncoder(buffered)}
+	}
+
+	return &csvJobExportWriter{writer: csv.NewWriter(w)}
+}
+
+type csvJobExportWriter struct {
+	writer        *csv.Writer
+	headerWritten bool
+}
+
+func (w *csvJobExportWriter) ContentType() string { return csvContentType + "; charset=utf-8" }
+
+func (w *csvJobExportWriter) Extension() string { return "csv" }
+
+func (w *csvJobExportWriter) Write(job model.Job) error {
+	if !w.headerWritten {
+		if err := w.writer.Write(jobExportColumns); err != nil {
+			return err
+		}
+		w.headerWritten = true
+	}
+
+	var externalRef string
+	if job.ExternalRef != nil {
+		externalRef = *job.ExternalRef
+	}
+
+	// Cells aren't evaluated as formulas when the file is opened in a spreadsheet, imports unescape them
+	return w.writer.Write(util.EscapeCSVRow([]string{
+		job.ID, externalRef, job.Title, job.Company, job.Description, job.Locale, string(job.JobType),
+		strconv.Itoa(job.SalaryMin), strconv.Itoa(job.SalaryMax), job.Location,
+		job.CreatedAt.Format(time.RFC3339), job.UpdatedAt.Format(time.RFC3339),
+	}))
+}
+
+func (w *csvJobExportWriter) Close() error {
+	// Exports without jobs still have a header
+	if !w.headerWritten {
+		if err := w.writer.Write(jobExportColumns); err != nil {
+			return err
+		}
+	}
+
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+type ndjsonJobExportWriter struct {
+	buffered *bufio.Writer
+	encoder  *json.Encoder
+}
+
+func (w *ndjsonJobExportWriter) ContentType() string { return ndjsonContentType }
+
+func (w *ndjsonJobExportWriter) Extension() string { return "ndjson" }
+
+func (w *ndjsonJobExportWriter) Write(job model.Job) error {
+	return w.encoder.Encode(struct {
+		ID          string    `json:"id"`
+		ExternalRef *string   `json:"external_ref"`
+		Title       string    `json:"title"`
+		Company     string    `json:"company"`
+		Description string    `json:"description"`
+		Locale      string    `json:"locale"`
+		JobType     string    `json:"job_type"`
+		SalaryMin   int       `json:"salary_min"`
+		SalaryMax   int       `json:"salary_max"`
+		Location    string    `json:"location"`
+		CreatedAt   time.Time `json:"created_at"`
+		UpdatedAt   time.Time `json:"updated_at"`
+	}{
+		job.ID, job.ExternalRef, job.Title, job.Company, job.Description, job.Locale, string(job.JobType),
+		job.SalaryMin, job.SalaryMax, job.Location, job.CreatedAt, job.UpdatedAt,
+	})
+}
+
+func (w *ndjsonJobExportWriter) Close() error {
+	return w.buffered.Flush()
+}
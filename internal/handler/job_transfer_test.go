@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"fliqt/internal/i18n"
+	"fliqt/internal/model"
+	"fliqt/internal/repository"
+)
+
+func TestReadJobImportCSV(t *testing.T) {
+	body := "\ufeffExternal_Ref,title,company,job_type,salary_min,salary_max,unknown\n" +
+		"REQ-1,Engineer,fliQt,full-time,1000,2000,ignored\n" +
+		"\n" +
+		"REQ-2,\"Designer, Senior\",fliQt,contract,a lot,2000,\n"
+
+	records, err := readJobImport(csvContentType, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 jobs, got %d", len(records))
+	}
+
+	expected := repository.ImportJobDTO{
+		ExternalRef: "REQ-1", Title: "Engineer", Company: "fliQt", JobType: "full-time", SalaryMin: 1000, SalaryMax: 2000,
+	}
+	if records[0].err != nil || records[0].dto != expected || records[0].line != 2 {
+		t.Errorf("expected %+v on line 2, got %+v", expected, records[0])
+	}
+
+	if records[1].line != 4 || records[1].dto.Title != "Designer, Senior" {
+		t.Errorf("expected the quoted title on line 4, got %+v", records[1])
+	}
+	importErr := newJobImportError(records[1].err, i18n.NewLocalizer())
+	if importErr.Code != "validation_failed" || len(importErr.Errors) != 1 || importErr.Errors[0].Field != "salary_min" {
+		t.Errorf("expected salary_min to be invalid, got %+v", importErr)
+	}
+}
+
+func TestReadJobImportNDJSON(t *testing.T) {
+	body := `{"external_ref":"REQ-1","title":"Engineer","company":"fliQt","job_type":"full-time","salary_min":1000,"salary_max":2000}` + "\n" +
+		"\n" +
+		`{"external_ref":"REQ-2",` + "\n"
+
+	records, err := readJobImport(ndjsonContentType, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].err != nil || records[0].dto.ExternalRef != "REQ-1" {
+		t.Fatalf("expected REQ-1 to be read, got %+v", records)
+	}
+	if records[1].line != 3 || newJobImportError(records[1].err, i18n.NewLocalizer()).Code != "bad_request" {
+		t.Errorf("expected line 3 to be malformed, got %+v", records[1])
+	}
+
+	if _, err := readJobImport("application/json", strings.NewReader(body)); err != ErrUnsupportedMediaType {
+		t.Errorf("expected ErrUnsupportedMediaType, got %v", err)
+	}
+}
+
+func TestReadJobImportTooLarge(t *testing.T) {
+	body := strings.Repeat(`{"external_ref":"REQ"}`+"\n", maxJobImportRows+1)
+	if _, err := readJobImport(ndjsonContentType, strings.NewReader(body)); err != ErrJobImportTooLarge {
+		t.Errorf("expected ErrJobImportTooLarge, got %v", err)
+	}
+}
+
+func TestJobExportWriterRoundTrip(t *testing.T) {
+	externalRef := "REQ-1"
+	job := model.Job{
+		Base:        model.Base{ID: "cqanbg8cvavjpljmh7pg", CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		ExternalRef: &externalRef,
+		Title:       "=HYPERLINK(\"https://example.com\")",
+		Company:     "fliQt",
+		Description: "Line one\nline two, with \"quotes\"",
+		Locale:      "ja",
+		JobType:     model.JobTypeContract,
+		SalaryMin:   1000,
+		SalaryMax:   2000,
+	}
+	expected := repository.ImportJobDTO{
+		ExternalRef: "REQ-1", Title: job.Title, Company: job.Company, Description: job.Description, Locale: "ja",
+		JobType: "contract", SalaryMin: 1000, SalaryMax: 2000,
+	}
+
+	for _, format := range []string{"csv", "ndjson"} {
+		var buf bytes.Buffer
+		writer := newJobExportWriter(format, &buf)
+		if err := writer.Write(job); err != nil {
+			t.Fatal(err)
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		contentType := csvContentType
+		if format == "ndjson" {
+			contentType = ndjsonContentType
+		} else if !strings.Contains(buf.String(), `"'=HYPERLINK(`) {
+			t.Errorf("expected the formula to be escaped, got %s", buf.String())
+		}
+		records, err := readJobImport(contentType, &buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) != 1 || records[0].err != nil || records[0].dto != expected {
+			t.Errorf("%s: expected %+v, got %+v", format, expected, records)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Logger is a handler that logs requests using zerolog
@@ -14,7 +15,13 @@ func Logger(logger *zerolog.Logger) gin.HandlerFunc {
 
 		c.Next()
 
-		logger.Info().
+		event := logger.Info()
+		// Logs of requests can be correlated with their traces
+		if spanContext := trace.SpanContextFromContext(c.Request.Context()); spanContext.HasTraceID() {
+			event = event.Str("trace_id", spanContext.TraceID().String())
+		}
+
+		event.
 			Str("client_ip", c.ClientIP()).
 			Str("method", c.Request.Method).
 			Str("path", c.Request.URL.Path).
@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"fliqt/internal/util"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+var (
+	meter = otel.Meter(util.GetFileNameFromCaller())
+
+	httpRequestDuration = util.Must(meter.Float64Histogram(
+		"http.server.request.duration",
+		metric.WithDescription("Duration of HTTP requests"),
+		metric.WithUnit("s"),
+	))
+)
+
+// Metrics is a handler that records the duration of requests by route template, so IDs don't blow up
+// the cardinality. Requests which match no route are recorded with an empty route.
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		httpRequestDuration.Record(c.Request.Context(), time.Since(start).Seconds(), metric.WithAttributes(
+			attribute.String("http.request.method", c.Request.Method),
+			attribute.String("http.route", c.FullPath()),
+			attribute.String("http.response.status_code", strconv.Itoa(c.Writer.Status())),
+		))
+	}
+}
@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"fliqt/internal/repository"
+	"fliqt/internal/service"
+	"fliqt/internal/util"
+	"fmt"
+	"html/template"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type NotificationHandler struct {
+	notificationRepo    *repository.NotificationRepository
+	logger              *zerolog.Logger
+	authService         service.AuthServiceInterface
+	notificationService service.NotificationServiceInterface
+}
+
+func NewNotificationHandler(
+	notificationRepo *repository.NotificationRepository,
+	logger *zerolog.Logger,
+	authService service.AuthServiceInterface,
+	notificationService service.NotificationServiceInterface,
+) *NotificationHandler {
+	return &NotificationHandler{
+		notificationRepo,
+		logger,
+		authService,
+		notificationService,
+	}
+}
+
+// unsubscribePage confirms unsubscribing before it's done, and tells when it's done. Without Action there's no form.
+var unsubscribePage = template.Must(template.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html lang="{{.Locale}}">
+<head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1"><title>{{.Message}}</title></head>
+<body>
+  <p>{{.Message}}</p>
+  {{if .Action}}<form method="post" action="{{.Action}}"><button type="submit">{{.Submit}}</button></form>{{end}}
+</body>
+</html>
+`))
+
+type UnsubscribeRequest struct {
+	UserID string `form:"user_id" binding:"required"`
+	Type   string `form:"type" binding:"required"`
+	Token  string `form:"token" binding:"required"`
+}
+
+// GetPreferences returns which types of notifications the current user receives
+func (h *NotificationHandler) GetPreferences(ctx *gin.Context) {
+	tracerCtx, span := tracer.Start(ctx.Request.Context(), util.GetSpanNameFromCaller())
+	defer span.End()
+
+	user, err := h.authService.CurrentUser(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	preferences, err := h.notificationRepo.GetPreferences(tracerCtx, user.ID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, preferences)
+}
+
+// UpdatePreferences enables or disables types of notifications for the current user
+func (h *NotificationHandler) UpdatePreferences(ctx *gin.Context) {
+	var req repository.NotificationPreferencesDTO
+	if err := ctx.BindJSON(&req); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	tracerCtx, span := tracer.Start(
+		ctx.Request.Context(),
+		util.GetSpanNameFromCaller(),
+		trace.WithAttributes(
+			attribute.String("request", fmt.Sprintf("%+v", req)),
+		),
+	)
+	defer span.End()
+
+	user, err := h.authService.CurrentUser(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	preferences, err := h.notificationRepo.SetPreferences(tracerCtx, user.ID, req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, preferences)
+}
+
+// ConfirmUnsubscribe checks the signed link in notifications and asks to confirm, it doesn't change anything since
+// links are opened by mail scanners and prefetchers as well. The confirmation is posted to Unsubscribe.
+func (h *NotificationHandler) ConfirmUnsubscribe(ctx *gin.Context) {
+	var req UnsubscribeRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	tracerCtx, span := tracer.Start(
+		ctx.Request.Context(),
+		util.GetSpanNameFromCaller(),
+		trace.WithAttributes(
+			attribute.String("user_id", req.UserID),
+			attribute.String("type", req.Type),
+		),
+	)
+	defer span.End()
+
+	if err := h.notificationService.CheckUnsubscribe(tracerCtx, req.UserID, req.Type, req.Token); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	localizer := localizerFrom(ctx)
+	renderUnsubscribePage(ctx, map[string]string{
+		"Locale":  localizer.Locale(),
+		"Message": localizer.T("unsubscribe.confirm", "Stop receiving these emails?"),
+		"Submit":  localizer.T("unsubscribe.submit", "Unsubscribe"),
+		"Action":  ctx.Request.URL.RequestURI(),
+	})
+}
+
+// Unsubscribe disables a type of notifications with the signed link in notifications, it doesn't require signing in.
+// It's posted by the confirmation page and by mail clients for one-click unsubscribe (RFC 8058), the page gets
+// HTML back.
+func (h *NotificationHandler) Unsubscribe(ctx *gin.Context) {
+	var req UnsubscribeRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	tracerCtx, span := tracer.Start(
+		ctx.Request.Context(),
+		util.GetSpanNameFromCaller(),
+		trace.WithAttributes(
+			attribute.String("user_id", req.UserID),
+			attribute.String("type", req.Type),
+		),
+	)
+	defer span.End()
+
+	if err := h.notificationService.Unsubscribe(tracerCtx, req.UserID, req.Type, req.Token); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	if ctx.NegotiateFormat(gin.MIMEJSON, gin.MIMEHTML) == gin.MIMEHTML {
+		localizer := localizerFrom(ctx)
+		renderUnsubscribePage(ctx, map[string]string{
+			"Locale":  localizer.Locale(),
+			"Message": localizer.T("unsubscribe.done", "You're unsubscribed from these emails."),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"unsubscribed": req.Type})
+}
+
+func renderUnsubscribePage(ctx *gin.Context, data map[string]string) {
+	ctx.Header("Content-Type", "text/html; charset=utf-8")
+	ctx.Status(http.StatusOK)
+	if err := unsubscribePage.Execute(ctx.Writer, data); err != nil {
+		ctx.Error(err)
+	}
+}
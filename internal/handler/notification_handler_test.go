@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+
+	"fliqt/internal/event"
+)
+
+type mockedNotificationService struct {
+	unsubscribed []string
+}
+
+func (m *mockedNotificationService) Handle(ctx context.Context, e event.Event) error {
+	return nil
+}
+
+func (m *mockedNotificationService) CheckUnsubscribe(ctx context.Context, userID string, notificationType string, token string) error {
+	return nil
+}
+
+func (m *mockedNotificationService) Unsubscribe(ctx context.Context, userID string, notificationType string, token string) error {
+	m.unsubscribed = append(m.unsubscribed, notificationType)
+	return nil
+}
+
+func TestNotificationHandlerUnsubscribe(t *testing.T) {
+	logger := zerolog.Nop()
+	notificationService := &mockedNotificationService{}
+	notificationHandler := NewNotificationHandler(nil, &logger, &mockedAuthServiceForHR{}, notificationService)
+
+	app := gin.New()
+	app.Use(ErrorHandler(&logger))
+	app.GET("/notifications/unsubscribe", notificationHandler.ConfirmUnsubscribe)
+	app.POST("/notifications/unsubscribe", notificationHandler.Unsubscribe)
+
+	request := func(method string, accept string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/notifications/unsubscribe?user_id=1&type=application_submitted&token=abc", nil)
+		r.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, r)
+		return w
+	}
+
+	// Opening the link only asks to confirm
+	w := request(http.MethodGet, "text/html")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `<form method="post" action="/notifications/unsubscribe?user_id=1&amp;type=application_submitted&amp;token=abc">`) {
+		t.Errorf("expected a confirmation form, got %d %s", w.Code, w.Body.String())
+	}
+	if len(notificationService.unsubscribed) != 0 {
+		t.Fatalf("expected GET not to unsubscribe, got %v", notificationService.unsubscribed)
+	}
+
+	// Mail clients post without asking for HTML
+	if w := request(http.MethodPost, ""); w.Code != http.StatusOK || w.Body.String() != `{"unsubscribed":"application_submitted"}` {
+		t.Errorf("expected JSON, got %d %s", w.Code, w.Body.String())
+	}
+	if w := request(http.MethodPost, "text/html,application/xhtml+xml"); !strings.Contains(w.Body.String(), "unsubscribed") || strings.Contains(w.Body.String(), "<form") {
+		t.Errorf("expected the page to tell it's done, got %s", w.Body.String())
+	}
+	if len(notificationService.unsubscribed) != 2 {
+		t.Errorf("expected POST to unsubscribe, got %v", notificationService.unsubscribed)
+	}
+}
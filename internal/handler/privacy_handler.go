@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"fliqt/internal/repository"
+	"fliqt/internal/service"
+	"fliqt/internal/util"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type PrivacyHandler struct {
+	privacyRepo    *repository.PrivacyRepository
+	logger         *zerolog.Logger
+	authService    service.AuthServiceInterface
+	privacyService service.PrivacyServiceInterface
+}
+
+func NewPrivacyHandler(
+	privacyRepo *repository.PrivacyRepository,
+	logger *zerolog.Logger,
+	authService service.AuthServiceInterface,
+	privacyService service.PrivacyServiceInterface,
+) *PrivacyHandler {
+	return &PrivacyHandler{
+		privacyRepo,
+		logger,
+		authService,
+		privacyService,
+	}
+}
+
+type RejectErasureRequest struct {
+	Reason string `json:"reason" binding:"required,max=255"`
+}
+
+// ExportMyData streams a ZIP archive of everything held about the current user
+func (h *PrivacyHandler) ExportMyData(ctx *gin.Context) {
+	tracerCtx, span := tracer.Start(ctx.Request.Context(), util.GetSpanNameFromCaller())
+	defer span.End()
+
+	user, err := h.authService.CurrentUser(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.Header("Content-Type", "application/zip")
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="fliqt-export-%s.zip"`, user.ID))
+	ctx.Status(http.StatusOK)
+
+	// The archive is streamed, the status can't be changed once it's started so errors are only logged.
+	if err := h.privacyService.Export(tracerCtx, user, ctx.Writer); err != nil {
+		span.RecordError(err)
+		h.logger.Error().Err(err).Str("user_id", user.ID).Msg("failed to export user data")
+	}
+}
+
+// RequestErasure starts the erasure of the current user's data, HR has to approve it
+func (h *PrivacyHandler) RequestErasure(ctx *gin.Context) {
+	tracerCtx, span := tracer.Start(ctx.Request.Context(), util.GetSpanNameFromCaller())
+	defer span.End()
+
+	user, err := h.authService.CurrentUser(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	request, err := h.privacyService.RequestErasure(tracerCtx, user)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, request)
+}
+
+// ListErasureRequests returns a list of erasure requests
+func (h *PrivacyHandler) ListErasureRequests(ctx *gin.Context) {
+	var filterParams repository.ErasureRequestFilterParams
+	if err := ctx.BindQuery(&filterParams); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	filterParams.Normalize()
+
+	tracerCtx, span := tracer.Start(
+		ctx.Request.Context(),
+		util.GetSpanNameFromCaller(),
+		trace.WithAttributes(
+			attribute.String("filterParams", fmt.Sprintf("%+v", filterParams)),
+		),
+	)
+	defer span.End()
+
+	result, err := h.privacyRepo.ListErasureRequests(tracerCtx, filterParams)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// ApproveErasureRequest erases the data of the requesting candidate
+func (h *PrivacyHandler) ApproveErasureRequest(ctx *gin.Context) {
+	tracerCtx, span := tracer.Start(
+		ctx.Request.Context(),
+		util.GetSpanNameFromCaller(),
+		trace.WithAttributes(
+			attribute.String("id", ctx.Param("id")),
+		),
+	)
+	defer span.End()
+
+	user, err := h.authService.CurrentUser(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	request, err := h.privacyService.ApproveErasure(tracerCtx, user, ctx.Param("id"))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, request)
+}
+
+// RejectErasureRequest closes an erasure request without erasing anything
+func (h *PrivacyHandler) RejectErasureRequest(ctx *gin.Context) {
+	var req RejectErasureRequest
+	if err := ctx.BindJSON(&req); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	tracerCtx, span := tracer.Start(
+		ctx.Request.Context(),
+		util.GetSpanNameFromCaller(),
+		trace.WithAttributes(
+			attribute.String("id", ctx.Param("id")),
+			attribute.String("request", fmt.Sprintf("%+v", req)),
+		),
+	)
+	defer span.End()
+
+	user, err := h.authService.CurrentUser(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	request, err := h.privacyService.RejectErasure(tracerCtx, user, ctx.Param("id"), req.Reason)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, request)
+}
@@ -0,0 +1,212 @@
+package handler
+
+import (
+	"fliqt/config"
+	"fliqt/internal/apperror"
+	"fliqt/internal/model"
+	"fliqt/internal/ratelimit"
+	"fliqt/internal/service"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+)
+
+var ErrTooManyRequests = apperror.New(http.StatusTooManyRequests, "too_many_requests", "too many requests")
+
+const (
+	RateLimitPolicyDefault   = "default"
+	RateLimitPolicyTOTP      = "totp"
+	RateLimitPolicyPresign   = "presign"
+	RateLimitPolicyJobSearch = "job_search"
+)
+
+// RateLimitPolicy limits each user, or each IP of anonymous requests. A zero limit leaves them unlimited.
+type RateLimitPolicy struct {
+	Limit          ratelimit.Limit
+	HRLimit        ratelimit.Limit
+	AnonymousLimit ratelimit.Limit
+}
+
+// RateLimiter applies rate limit policies to routes, callers on the allow-list are never limited.
+type RateLimiter struct {
+	logger       *zerolog.Logger
+	limiter      *ratelimit.Limiter
+	authService  service.AuthServiceInterface
+	policies     map[string]RateLimitPolicy
+	allowedUsers map[string]bool
+	allowedIPs   []*net.IPNet
+}
+
+func NewRateLimiter(
+	cfg *config.Config,
+	logger *zerolog.Logger,
+	limiter *ratelimit.Limiter,
+	authService service.AuthServiceInterface,
+) (*RateLimiter, error) {
+	limits := map[string]ratelimit.Limit{}
+	for name, value := range map[string]string{
+		"RATE_LIMIT_DEFAULT":              cfg.RateLimitDefault,
+		"RATE_LIMIT_HR":                   cfg.RateLimitHR,
+		"RATE_LIMIT_TOTP":                 cfg.RateLimitTOTP,
+		"RATE_LIMIT_PRESIGN":              cfg.RateLimitPresign,
+		"RATE_LIMIT_JOB_SEARCH_ANONYMOUS": cfg.RateLimitJobSearchAnonymous,
+	} {
+		limit, err := ratelimit.ParseLimit(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		limits[name] = limit
+	}
+
+	allowedUsers, allowedIPs, err := parseRateLimitAllowList(cfg.RateLimitAllowList)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RateLimiter{
+		logger,
+		limiter,
+		authService,
+		map[string]RateLimitPolicy{
+			// HR works through lots of applications, so they get a generous limit.
+			RateLimitPolicyDefault: {
+				Limit:          limits["RATE_LIMIT_DEFAULT"],
+				HRLimit:        limits["RATE_LIMIT_HR"],
+				AnonymousLimit: limits["RATE_LIMIT_DEFAULT"],
+			},
+			// Every download of HR and interviewers is a TOTP attempt, passcodes mustn't be brute-forced.
+			RateLimitPolicyTOTP: {
+				Limit:   limits["RATE_LIMIT_TOTP"],
+				HRLimit: limits["RATE_LIMIT_TOTP"],
+			},
+			RateLimitPolicyPresign: {
+				Limit:   limits["RATE_LIMIT_PRESIGN"],
+				HRLimit: limits["RATE_LIMIT_PRESIGN"],
+			},
+			// Job search is public, crawlers are limited by IP.
+			RateLimitPolicyJobSearch: {
+				AnonymousLimit: limits["RATE_LIMIT_JOB_SEARCH_ANONYMOUS"],
+			},
+		},
+		allowedUsers,
+		allowedIPs,
+	}, nil
+}
+
+// parseRateLimitAllowList parses comma separated IPs, CIDRs and user IDs.
+func parseRateLimitAllowList(allowList string) (map[string]bool, []*net.IPNet, error) {
+	users := map[string]bool{}
+	var ips []*net.IPNet
+
+	for _, entry := range strings.Split(allowList, ",") {
+		entry = strings.TrimSpace(entry)
+		switch {
+		case entry == "":
+		case strings.Contains(entry, "/"):
+			_, ipNet, err := net.ParseCIDR(entry)
+			if err != nil {
+				return nil, nil, fmt.Errorf("RATE_LIMIT_ALLOWLIST: %w", err)
+			}
+			ips = append(ips, ipNet)
+		case net.ParseIP(entry) != nil:
+			ip := net.ParseIP(entry)
+			bits := 8 * len(ip.To16())
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			ips = append(ips, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+		default:
+			users[entry] = true
+		}
+	}
+
+	return users, ips, nil
+}
+
+func (l *RateLimiter) allowed(c *gin.Context, user *model.User) bool {
+	if user != nil && l.allowedUsers[user.ID] {
+		return true
+	}
+
+	ip := net.ParseIP(c.ClientIP())
+	for _, ipNet := range l.allowedIPs {
+		if ip != nil && ipNet.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Limit is a handler that limits requests by the policy. It responds 429 with Retry-After once the limit
+// is exceeded, and describes the limit with RateLimit-* headers. Requests are allowed when Redis fails.
+func (l *RateLimiter) Limit(name string) gin.HandlerFunc {
+	policy := l.policies[name]
+
+	return func(c *gin.Context) {
+		// Unknown users are limited by IP, they're rejected by AuthHandler anyway.
+		user, _ := l.authService.CurrentUser(c)
+		if l.allowed(c, user) {
+			c.Next()
+			return
+		}
+
+		limit := policy.AnonymousLimit
+		key := fmt.Sprintf("%s:ip:%s", name, c.ClientIP())
+		if user != nil {
+			limit = policy.Limit
+			if user.Role == model.RoleHR {
+				limit = policy.HRLimit
+			}
+			key = fmt.Sprintf("%s:user:%s", name, user.ID)
+		}
+		if limit.IsZero() {
+			c.Next()
+			return
+		}
+
+		result, err := l.limiter.Allow(c.Request.Context(), key, limit)
+		if err != nil {
+			l.logger.Error().Err(err).Str("policy", name).Msg("failed to rate limit")
+			c.Next()
+			return
+		}
+
+		setRateLimitHeaders(c, result)
+
+		if !result.Allowed {
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			c.Error(ErrTooManyRequests)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// setRateLimitHeaders sets RateLimit-* headers of the IETF draft. When several policies apply,
+// the one with the fewest remaining requests is described.
+func setRateLimitHeaders(c *gin.Context, result *ratelimit.Result) {
+	if previous := c.Writer.Header().Get("RateLimit-Remaining"); previous != "" {
+		if remaining, err := strconv.Atoi(previous); err == nil && remaining <= result.Remaining {
+			return
+		}
+	}
+
+	c.Header("RateLimit-Limit", strconv.Itoa(result.Limit.Requests))
+	c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+	c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d", result.Limit.Requests, ceilSeconds(result.Limit.Period)))
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"fliqt/internal/jobs"
+	"fliqt/internal/repository"
+	"fliqt/internal/service"
+	"fliqt/internal/util"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type RetentionHandler struct {
+	userRepo         *repository.UserRepository
+	logger           *zerolog.Logger
+	retentionService service.RetentionServiceInterface
+	queue            *jobs.Queue
+}
+
+func NewRetentionHandler(
+	userRepo *repository.UserRepository,
+	logger *zerolog.Logger,
+	retentionService service.RetentionServiceInterface,
+	queue *jobs.Queue,
+) *RetentionHandler {
+	return &RetentionHandler{
+		userRepo,
+		logger,
+		retentionService,
+		queue,
+	}
+}
+
+// GetRetentionReport returns applications which would be purged by the retention policy now
+func (h *RetentionHandler) GetRetentionReport(ctx *gin.Context) {
+	tracerCtx, span := tracer.Start(ctx.Request.Context(), util.GetSpanNameFromCaller())
+	defer span.End()
+
+	report, err := h.retentionService.Purge(tracerCtx, true)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, report)
+}
+
+// PurgeNow enqueues a purge of expired applications, it runs on the worker
+func (h *RetentionHandler) PurgeNow(ctx *gin.Context) {
+	tracerCtx, span := tracer.Start(ctx.Request.Context(), util.GetSpanNameFromCaller())
+	defer span.End()
+
+	task, err := h.queue.Enqueue(tracerCtx, service.TaskPurgeRetention, nil)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, gin.H{"task_id": task.ID})
+}
+
+// SetLegalHold places a candidate on legal hold, so their data is never purged, or releases them
+func (h *RetentionHandler) SetLegalHold(ctx *gin.Context) {
+	var req repository.LegalHoldDTO
+	if err := ctx.BindJSON(&req); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	tracerCtx, span := tracer.Start(
+		ctx.Request.Context(),
+		util.GetSpanNameFromCaller(),
+		trace.WithAttributes(
+			attribute.String("id", ctx.Param("id")),
+			attribute.String("request", fmt.Sprintf("%+v", req)),
+		),
+	)
+	defer span.End()
+
+	ID := ctx.Param("id")
+	if ID == "" {
+		ctx.Error(ErrNotFound)
+		return
+	}
+
+	user, err := h.userRepo.SetLegalHold(tracerCtx, ID, req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, user)
+}
@@ -1,11 +1,15 @@
 package handler
 
 import (
+	"net/http"
+
+	"fliqt/internal/jobs"
 	"fliqt/internal/model"
 	"fliqt/internal/repository"
 	"fliqt/internal/service"
 
 	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
 	"github.com/rs/zerolog"
 
 	"fliqt/config"
@@ -16,26 +20,98 @@ func NewRouter(
 	app *gin.Engine,
 	logger *zerolog.Logger,
 	jobRepo *repository.JobRepository,
+	jobFacetService service.JobFacetServiceInterface,
 	applicationRepo *repository.ApplicationRepository,
+	bulkApplicationService service.BulkApplicationServiceInterface,
+	downloadLogRepo *repository.DownloadLogRepository,
+	userRepo *repository.UserRepository,
 	authService service.AuthServiceInterface,
 	s3Service service.S3ServiceInterface,
+	watermarkService service.WatermarkServiceInterface,
+	retentionService service.RetentionServiceInterface,
+	privacyRepo *repository.PrivacyRepository,
+	privacyService service.PrivacyServiceInterface,
+	webhookRepo *repository.WebhookRepository,
+	webhookService service.WebhookServiceInterface,
+	notificationRepo *repository.NotificationRepository,
+	notificationService service.NotificationServiceInterface,
+	streamService service.StreamServiceInterface,
+	queue *jobs.Queue,
+	healthService service.HealthServiceInterface,
+	metricsHandler http.Handler,
+	rateLimiter *RateLimiter,
+	redisClient *redis.Client,
 ) {
-	r := app.Group("/api")
+	// Probes of the orchestrator and metrics of Prometheus, they don't require authentication.
+	healthHandler := NewHealthHandler(healthService)
+	app.GET("/healthz", healthHandler.Healthz)
+	app.GET("/readyz", healthHandler.Readyz)
+	app.GET("/metrics", gin.WrapH(metricsHandler))
+
+	r := app.Group("/api", rateLimiter.Limit(RateLimitPolicyDefault), Idempotency(cfg, redisClient, logger))
 
-	applicationHandler := NewApplicationHandler(applicationRepo, logger, authService)
+	applicationHandler := NewApplicationHandler(applicationRepo, logger, authService, bulkApplicationService, downloadLogRepo)
 	r.GET("/applications", AuthHandler(authService, []model.UserRole{model.RoleHR, model.RoleInteviewer, model.RoleCandidate}), applicationHandler.ListApplications)
 	r.POST("/applications", AuthHandler(authService, []model.UserRole{model.RoleCandidate}), applicationHandler.CreateApplication)
+	r.GET("/applications/:id", AuthHandler(authService, []model.UserRole{model.RoleHR, model.RoleInteviewer, model.RoleCandidate}), applicationHandler.GetApplication)
+	r.PUT("/applications/:id/status", AuthHandler(authService, []model.UserRole{model.RoleHR}), applicationHandler.UpdateApplicationStatus)
+	r.GET("/applications/:id/audit-logs", AuthHandler(authService, []model.UserRole{model.RoleHR}), applicationHandler.ListAuditLogs)
+	r.POST("/applications/bulk", AuthHandler(authService, []model.UserRole{model.RoleHR}), applicationHandler.BulkApplications)
+	r.GET("/applications/bulk/:id", AuthHandler(authService, []model.UserRole{model.RoleHR, model.RoleInteviewer}), applicationHandler.GetBulkOperation)
 
 	r.GET("/jobs/:id/applications", AuthHandler(authService, []model.UserRole{model.RoleHR, model.RoleInteviewer, model.RoleCandidate}), applicationHandler.ListApplications)
+	r.GET("/jobs/:id/applications/export", AuthHandler(authService, []model.UserRole{model.RoleHR}), rateLimiter.Limit(RateLimitPolicyTOTP), applicationHandler.ExportJobApplications)
+	// Links to exports are signed, so they work without signing in.
+	r.GET("/exports/:id", applicationHandler.DownloadExport)
 
-	jobHandler := NewJobHandler(jobRepo, logger)
-	r.GET("/jobs", jobHandler.ListJobs)
+	jobHandler := NewJobHandler(jobRepo, jobFacetService, logger)
+	r.GET("/jobs", rateLimiter.Limit(RateLimitPolicyJobSearch), jobHandler.ListJobs)
 	r.GET("/jobs/:id", jobHandler.GetJob)
 	r.POST("/jobs", AuthHandler(authService, []model.UserRole{model.RoleHR}), jobHandler.CreateJob)
+	r.POST("/jobs/import", AuthHandler(authService, []model.UserRole{model.RoleHR}), jobHandler.ImportJobs)
+	r.GET("/jobs/export", AuthHandler(authService, []model.UserRole{model.RoleHR}), jobHandler.ExportJobs)
 	r.PUT("/jobs/:id", AuthHandler(authService, []model.UserRole{model.RoleHR}), jobHandler.UpdateJob)
+	r.PATCH("/jobs/:id", AuthHandler(authService, []model.UserRole{model.RoleHR}), jobHandler.PatchJob)
 	r.DELETE("/jobs/:id", AuthHandler(authService, []model.UserRole{model.RoleHR}), jobHandler.DeleteJob)
+	r.GET("/jobs/:id/translations", AuthHandler(authService, []model.UserRole{model.RoleHR}), jobHandler.ListJobTranslations)
+	r.PUT("/jobs/:id/translations/:locale", AuthHandler(authService, []model.UserRole{model.RoleHR}), jobHandler.PutJobTranslation)
+	r.DELETE("/jobs/:id/translations/:locale", AuthHandler(authService, []model.UserRole{model.RoleHR}), jobHandler.DeleteJobTranslation)
+
+	fileHandler := NewFileHandler(cfg, downloadLogRepo, authService, s3Service, watermarkService)
+	r.POST("/files", AuthHandler(authService, []model.UserRole{model.RoleCandidate}), rateLimiter.Limit(RateLimitPolicyPresign), fileHandler.GetUploadInfo)
+	r.POST("/files/multipart", AuthHandler(authService, []model.UserRole{model.RoleCandidate}), rateLimiter.Limit(RateLimitPolicyPresign), fileHandler.CreateMultipartUpload)
+	r.POST("/files/multipart/parts", AuthHandler(authService, []model.UserRole{model.RoleCandidate}), rateLimiter.Limit(RateLimitPolicyPresign), fileHandler.PresignMultipartParts)
+	r.POST("/files/multipart/complete", AuthHandler(authService, []model.UserRole{model.RoleCandidate}), fileHandler.CompleteMultipartUpload)
+	r.POST("/files/multipart/abort", AuthHandler(authService, []model.UserRole{model.RoleCandidate}), fileHandler.AbortMultipartUpload)
+	retentionHandler := NewRetentionHandler(userRepo, logger, retentionService, queue)
+	r.GET("/retention/report", AuthHandler(authService, []model.UserRole{model.RoleHR}), retentionHandler.GetRetentionReport)
+	r.POST("/retention/purge", AuthHandler(authService, []model.UserRole{model.RoleHR}), retentionHandler.PurgeNow)
+	r.PUT("/users/:id/legal-hold", AuthHandler(authService, []model.UserRole{model.RoleHR}), retentionHandler.SetLegalHold)
+
+	privacyHandler := NewPrivacyHandler(privacyRepo, logger, authService, privacyService)
+	r.GET("/me/export", AuthHandler(authService, []model.UserRole{model.RoleCandidate}), privacyHandler.ExportMyData)
+	r.POST("/me/erase", AuthHandler(authService, []model.UserRole{model.RoleCandidate}), privacyHandler.RequestErasure)
+	r.GET("/erasure-requests", AuthHandler(authService, []model.UserRole{model.RoleHR}), privacyHandler.ListErasureRequests)
+	r.POST("/erasure-requests/:id/approve", AuthHandler(authService, []model.UserRole{model.RoleHR}), privacyHandler.ApproveErasureRequest)
+	r.POST("/erasure-requests/:id/reject", AuthHandler(authService, []model.UserRole{model.RoleHR}), privacyHandler.RejectErasureRequest)
+
+	webhookHandler := NewWebhookHandler(webhookRepo, logger, authService, webhookService)
+	r.GET("/webhooks", AuthHandler(authService, []model.UserRole{model.RoleHR}), webhookHandler.ListWebhooks)
+	r.POST("/webhooks", AuthHandler(authService, []model.UserRole{model.RoleHR}), webhookHandler.CreateWebhook)
+	r.GET("/webhooks/:id", AuthHandler(authService, []model.UserRole{model.RoleHR}), webhookHandler.GetWebhook)
+	r.PUT("/webhooks/:id", AuthHandler(authService, []model.UserRole{model.RoleHR}), webhookHandler.UpdateWebhook)
+	r.DELETE("/webhooks/:id", AuthHandler(authService, []model.UserRole{model.RoleHR}), webhookHandler.DeleteWebhook)
+	r.GET("/webhooks/:id/deliveries", AuthHandler(authService, []model.UserRole{model.RoleHR}), webhookHandler.ListDeliveries)
+	r.POST("/webhooks/:id/deliveries/:delivery_id/redeliver", AuthHandler(authService, []model.UserRole{model.RoleHR}), webhookHandler.Redeliver)
+
+	notificationHandler := NewNotificationHandler(notificationRepo, logger, authService, notificationService)
+	r.GET("/me/notification-preferences", AuthHandler(authService, []model.UserRole{model.RoleHR, model.RoleInteviewer, model.RoleCandidate}), notificationHandler.GetPreferences)
+	r.PUT("/me/notification-preferences", AuthHandler(authService, []model.UserRole{model.RoleHR, model.RoleInteviewer, model.RoleCandidate}), notificationHandler.UpdatePreferences)
+	r.GET("/notifications/unsubscribe", notificationHandler.ConfirmUnsubscribe)
+	r.POST("/notifications/unsubscribe", notificationHandler.Unsubscribe)
+
+	streamHandler := NewStreamHandler(cfg, logger, authService, streamService)
+	r.GET("/stream", AuthHandler(authService, []model.UserRole{model.RoleHR, model.RoleInteviewer, model.RoleCandidate}), streamHandler.Stream)
 
-	fileHandler := NewFileHandler(cfg, authService, s3Service)
-	r.POST("/files", AuthHandler(authService, []model.UserRole{model.RoleCandidate}), fileHandler.GetUploadInfo)
-	r.GET("/files/*object_key", AuthHandler(authService, []model.UserRole{model.RoleHR, model.RoleInteviewer, model.RoleCandidate}), fileHandler.GetDownloadInfo)
+	r.GET("/files/*object_key", AuthHandler(authService, []model.UserRole{model.RoleHR, model.RoleInteviewer, model.RoleCandidate}), rateLimiter.Limit(RateLimitPolicyTOTP), fileHandler.GetDownloadInfo)
 }
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"fliqt/config"
+	"fliqt/internal/service"
+	"fliqt/internal/util"
+	"net/http"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type StreamHandler struct {
+	cfg           *config.Config
+	logger        *zerolog.Logger
+	authService   service.AuthServiceInterface
+	streamService service.StreamServiceInterface
+}
+
+func NewStreamHandler(
+	cfg *config.Config,
+	logger *zerolog.Logger,
+	authService service.AuthServiceInterface,
+	streamService service.StreamServiceInterface,
+) *StreamHandler {
+	return &StreamHandler{
+		cfg,
+		logger,
+		authService,
+		streamService,
+	}
+}
+
+// Stream pushes events the current user can see over Server-Sent Events, clients resume with Last-Event-ID
+func (h *StreamHandler) Stream(ctx *gin.Context) {
+	lastEventID := ctx.GetHeader("Last-Event-ID")
+
+	tracerCtx, span := tracer.Start(
+		ctx.Request.Context(),
+		util.GetSpanNameFromCaller(),
+		trace.WithAttributes(
+			attribute.String("last_event_id", lastEventID),
+		),
+	)
+	defer span.End()
+
+	user, err := h.authService.CurrentUser(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	events, err := h.streamService.Subscribe(tracerCtx, user, lastEventID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.Header("Content-Type", sse.ContentType)
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	// Don't let proxies buffer the stream
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+	ctx.Writer.Flush()
+
+	heartbeat := time.NewTicker(h.cfg.StreamHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-tracerCtx.Done():
+			return
+		case <-heartbeat.C:
+			// Comments keep the connection and proxies alive without waking clients up.
+			if _, err := ctx.Writer.WriteString(": heartbeat\n\n"); err != nil {
+				return
+			}
+			ctx.Writer.Flush()
+		case e, ok := <-events:
+			if !ok {
+				// The client fell behind, it reconnects and resumes with Last-Event-ID.
+				return
+			}
+
+			ctx.Render(-1, sse.Event{
+				Id:    e.StreamID,
+				Event: e.Type,
+				Data:  e,
+			})
+			ctx.Writer.Flush()
+		}
+	}
+}
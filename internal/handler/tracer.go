@@ -2,8 +2,29 @@ package handler
 
 import (
 	"fliqt/internal/util"
+	"net/http"
 
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 	"go.opentelemetry.io/otel"
 )
 
 var tracer = otel.Tracer(util.GetFileNameFromCaller())
+
+// untracedPaths are polled by the orchestrator and Prometheus, tracing them would only be noise
+var untracedPaths = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+	"/metrics": true,
+}
+
+// Tracing starts a server span for every request named after its route template, with HTTP semantic
+// attributes. It continues the trace and baggage of incoming W3C headers, so spans of handlers are its children.
+func Tracing() gin.HandlerFunc {
+	return otelgin.Middleware(
+		util.ServiceName,
+		otelgin.WithFilter(func(r *http.Request) bool {
+			return !untracedPaths[r.URL.Path]
+		}),
+	)
+}
@@ -0,0 +1,217 @@
+package handler
+
+import (
+	"fliqt/internal/repository"
+	"fliqt/internal/service"
+	"fliqt/internal/util"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type WebhookHandler struct {
+	webhookRepo    *repository.WebhookRepository
+	logger         *zerolog.Logger
+	authService    service.AuthServiceInterface
+	webhookService service.WebhookServiceInterface
+}
+
+func NewWebhookHandler(
+	webhookRepo *repository.WebhookRepository,
+	logger *zerolog.Logger,
+	authService service.AuthServiceInterface,
+	webhookService service.WebhookServiceInterface,
+) *WebhookHandler {
+	return &WebhookHandler{
+		webhookRepo,
+		logger,
+		authService,
+		webhookService,
+	}
+}
+
+// ListWebhooks returns every webhook
+func (h *WebhookHandler) ListWebhooks(ctx *gin.Context) {
+	tracerCtx, span := tracer.Start(ctx.Request.Context(), util.GetSpanNameFromCaller())
+	defer span.End()
+
+	webhooks, err := h.webhookRepo.ListWebhooks(tracerCtx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, webhooks)
+}
+
+// GetWebhook returns a webhook
+func (h *WebhookHandler) GetWebhook(ctx *gin.Context) {
+	tracerCtx, span := tracer.Start(
+		ctx.Request.Context(),
+		util.GetSpanNameFromCaller(),
+		trace.WithAttributes(
+			attribute.String("id", ctx.Param("id")),
+		),
+	)
+	defer span.End()
+
+	webhook, err := h.webhookRepo.GetWebhookByID(tracerCtx, ctx.Param("id"))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	if notModified(ctx, versionETag(webhook.Version, "")) {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, webhook)
+}
+
+// CreateWebhook creates a webhook, its secret is only returned in the response
+func (h *WebhookHandler) CreateWebhook(ctx *gin.Context) {
+	var req repository.CreateWebhookDTO
+	if err := ctx.BindJSON(&req); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	tracerCtx, span := tracer.Start(
+		ctx.Request.Context(),
+		util.GetSpanNameFromCaller(),
+		trace.WithAttributes(
+			attribute.String("url", req.URL),
+			attribute.StringSlice("event_types", req.EventTypes),
+		),
+	)
+	defer span.End()
+
+	user, err := h.authService.CurrentUser(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	req.CreatedBy = user.ID
+
+	webhook, err := h.webhookService.CreateWebhook(tracerCtx, req)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.Header("ETag", versionETag(webhook.Version, ""))
+	ctx.JSON(http.StatusCreated, webhook)
+}
+
+// UpdateWebhook updates a webhook
+func (h *WebhookHandler) UpdateWebhook(ctx *gin.Context) {
+	var req repository.UpdateWebhookDTO
+	if err := ctx.BindJSON(&req); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	tracerCtx, span := tracer.Start(
+		ctx.Request.Context(),
+		util.GetSpanNameFromCaller(),
+		trace.WithAttributes(
+			attribute.String("id", ctx.Param("id")),
+			attribute.String("request", fmt.Sprintf("%+v", req)),
+		),
+	)
+	defer span.End()
+
+	versions, err := ifMatchVersions(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	webhook, err := h.webhookRepo.UpdateWebhook(tracerCtx, ctx.Param("id"), req, versions)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.Header("ETag", versionETag(webhook.Version, ""))
+	ctx.JSON(http.StatusOK, webhook)
+}
+
+// DeleteWebhook deletes a webhook
+func (h *WebhookHandler) DeleteWebhook(ctx *gin.Context) {
+	tracerCtx, span := tracer.Start(
+		ctx.Request.Context(),
+		util.GetSpanNameFromCaller(),
+		trace.WithAttributes(
+			attribute.String("id", ctx.Param("id")),
+		),
+	)
+	defer span.End()
+
+	if err := h.webhookRepo.DeleteWebhook(tracerCtx, ctx.Param("id")); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// ListDeliveries returns the request and response history of a webhook
+func (h *WebhookHandler) ListDeliveries(ctx *gin.Context) {
+	var filterParams repository.WebhookDeliveryFilterParams
+	if err := ctx.ShouldBindQuery(&filterParams); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	filterParams.Normalize()
+
+	tracerCtx, span := tracer.Start(
+		ctx.Request.Context(),
+		util.GetSpanNameFromCaller(),
+		trace.WithAttributes(
+			attribute.String("id", ctx.Param("id")),
+			attribute.String("filterParams", fmt.Sprintf("%+v", filterParams)),
+		),
+	)
+	defer span.End()
+
+	if _, err := h.webhookRepo.GetWebhookByID(tracerCtx, ctx.Param("id")); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	deliveries, err := h.webhookRepo.ListDeliveries(tracerCtx, ctx.Param("id"), filterParams)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, deliveries)
+}
+
+// Redeliver sends the request of a past delivery again
+func (h *WebhookHandler) Redeliver(ctx *gin.Context) {
+	tracerCtx, span := tracer.Start(
+		ctx.Request.Context(),
+		util.GetSpanNameFromCaller(),
+		trace.WithAttributes(
+			attribute.String("id", ctx.Param("id")),
+			attribute.String("delivery_id", ctx.Param("delivery_id")),
+		),
+	)
+	defer span.End()
+
+	delivery, err := h.webhookService.Redeliver(tracerCtx, ctx.Param("id"), ctx.Param("delivery_id"))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, delivery)
+}
@@ -0,0 +1,167 @@
+package i18n
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/locales"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/ja"
+	"github.com/go-playground/locales/zh_Hant_TW"
+	ut "github.com/go-playground/universal-translator"
+)
+
+const (
+	LocaleEnglish            = "en"
+	LocaleTraditionalChinese = "zh-TW"
+	LocaleJapanese           = "ja"
+
+	DefaultLocale = LocaleEnglish
+)
+
+// SupportedLocales are BCP 47 tags of the languages the API speaks.
+var SupportedLocales = []string{LocaleEnglish, LocaleTraditionalChinese, LocaleJapanese}
+
+// translators of the supported locales, they're keyed by BCP 47 tags rather than the CLDR names of locales.
+var translators = newTranslators()
+
+func newTranslators() map[string]ut.Translator {
+	universal := ut.New(en.New(), en.New(), zh_Hant_TW.New(), ja.New())
+
+	result := map[string]ut.Translator{}
+	for tag, l := range map[string]locales.Translator{
+		LocaleEnglish:            en.New(),
+		LocaleTraditionalChinese: zh_Hant_TW.New(),
+		LocaleJapanese:           ja.New(),
+	} {
+		translator, _ := universal.GetTranslator(l.Locale())
+		for key, text := range messages[tag] {
+			// The catalog is static, a broken message is a bug
+			if err := translator.Add(key, text, false); err != nil {
+				panic(err)
+			}
+		}
+		result[tag] = translator
+	}
+
+	return result
+}
+
+// IsSupported reports whether locale is one of SupportedLocales.
+func IsSupported(locale string) bool {
+	_, ok := translators[locale]
+	return ok
+}
+
+// Match returns the supported locale of a BCP 47 tag, e.g. zh-Hant and zh-HK are spoken as zh-TW.
+func Match(tag string) (string, bool) {
+	language, _, _ := strings.Cut(strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-")), "-")
+
+	switch language {
+	case "en":
+		return LocaleEnglish, true
+	case "zh":
+		return LocaleTraditionalChinese, true
+	case "ja":
+		return LocaleJapanese, true
+	}
+
+	return "", false
+}
+
+// Negotiate returns the supported locales of an Accept-Language header in the order of preference, it always
+// ends with DefaultLocale so it can be used as a fallback chain.
+func Negotiate(acceptLanguage string) []string {
+	type preference struct {
+		locale  string
+		quality float64
+	}
+
+	var preferences []preference
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		if quality <= 0 {
+			continue
+		}
+
+		if locale, ok := Match(tag); ok {
+			preferences = append(preferences, preference{locale, quality})
+		}
+	}
+
+	sort.SliceStable(preferences, func(i, j int) bool {
+		return preferences[i].quality > preferences[j].quality
+	})
+
+	var result []string
+	for _, p := range append(preferences, preference{DefaultLocale, 0}) {
+		if !contains(result, p.locale) {
+			result = append(result, p.locale)
+		}
+	}
+
+	return result
+}
+
+func contains(locales []string, locale string) bool {
+	for _, l := range locales {
+		if l == locale {
+			return true
+		}
+	}
+	return false
+}
+
+// Localizer translates messages into the first locale of a fallback chain which has them.
+type Localizer struct {
+	locales []string
+}
+
+func NewLocalizer(locales ...string) *Localizer {
+	var supported []string
+	for _, locale := range locales {
+		if IsSupported(locale) && !contains(supported, locale) {
+			supported = append(supported, locale)
+		}
+	}
+	if len(supported) == 0 {
+		supported = []string{DefaultLocale}
+	}
+
+	return &Localizer{supported}
+}
+
+// Locale is the preferred locale.
+func (l *Localizer) Locale() string {
+	return l.locales[0]
+}
+
+// Locales is the fallback chain.
+func (l *Localizer) Locales() []string {
+	return l.locales
+}
+
+// T translates the message of key with params in place of {0}, {1}..., fallback is used when no locale has it.
+func (l *Localizer) T(key string, fallback string, params ...string) string {
+	for _, locale := range l.locales {
+		if text, err := translators[locale].T(key, params...); err == nil {
+			return text
+		}
+	}
+
+	return fallback
+}
+
+// List joins items the way the preferred locale lists them.
+func (l *Localizer) List(items []string) string {
+	return strings.Join(items, l.T("list.separator", ", "))
+}
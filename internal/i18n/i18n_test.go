@@ -0,0 +1,50 @@
+package i18n
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	for header, expected := range map[string][]string{
+		"":                             {"en"},
+		"ja":                           {"ja", "en"},
+		"zh-Hant-TW, zh;q=0.9":         {"zh-TW", "en"},
+		"fr, en-US;q=0.5, ja;q=0.8":    {"ja", "en"},
+		"ja;q=0, zh_TW":                {"zh-TW", "en"},
+		"de, *;q=0.1":                  {"en"},
+		"en;q=0.2, zh-HK;q=0.9, ja-JP": {"ja", "zh-TW", "en"},
+	} {
+		if locales := Negotiate(header); !reflect.DeepEqual(locales, expected) {
+			t.Errorf("Negotiate(%q) = %v, expected %v", header, locales, expected)
+		}
+	}
+}
+
+func TestLocalizer(t *testing.T) {
+	localizer := NewLocalizer("ja", "en")
+
+	if text := localizer.T("validation.max_length", "", "255"); text != "は 255 文字以下である必要があります" {
+		t.Errorf("unexpected translation %q", text)
+	}
+	if text := localizer.T("no_such_message", "fallback"); text != "fallback" {
+		t.Errorf("expected the fallback, got %q", text)
+	}
+	if text := localizer.List([]string{"a", "b"}); text != "a、b" {
+		t.Errorf("unexpected list %q", text)
+	}
+
+	// Validation messages are in every locale, and both translations translate the same errors.
+	for _, locales := range [][2]string{
+		{LocaleEnglish, LocaleTraditionalChinese},
+		{LocaleEnglish, LocaleJapanese},
+		{LocaleTraditionalChinese, LocaleJapanese},
+		{LocaleJapanese, LocaleTraditionalChinese},
+	} {
+		for key := range messages[locales[0]] {
+			if _, ok := messages[locales[1]][key]; !ok {
+				t.Errorf("%s of %s isn't translated to %s", key, locales[0], locales[1])
+			}
+		}
+	}
+}
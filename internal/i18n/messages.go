@@ -0,0 +1,182 @@
+package i18n
+
+// messages are keyed by the codes of apperror.Error, by validation.<tag> for invalid fields and by
+// unsubscribe.<key> for the unsubscribe page. English messages are next to their use, so they're only translated
+// here. Params are {0}, {1}...
+var messages = map[string]map[string]string{
+	LocaleEnglish: {
+		"list.separator": ", ",
+
+		"validation.required":    "is required",
+		"validation.required_if": "is required when {0}",
+		"validation.oneof":       "must be one of {0}",
+		"validation.url":         "must be a URL",
+		"validation.min":         "must be at least {0}",
+		"validation.min_length":  "must be at least {0} characters",
+		"validation.min_items":   "must have at least {0} items",
+		"validation.max":         "must be at most {0}",
+		"validation.max_length":  "must be at most {0} characters",
+		"validation.max_items":   "must have at most {0} items",
+		"validation.gt":          "must be greater than {0}",
+		"validation.gte":         "must be greater than or equal to {0}",
+		"validation.lt":          "must be less than {0}",
+		"validation.lte":         "must be less than or equal to {0}",
+		"validation.invalid":     "is invalid",
+		"validation.type.string": "must be a string",
+		"validation.type.bool":   "must be a boolean",
+		"validation.type.int":    "must be an integer",
+		"validation.type.number": "must be a number",
+		"validation.type.list":   "must be a list",
+		"validation.type.object": "must be an object",
+	},
+	LocaleTraditionalChinese: {
+		"list.separator": "、",
+
+		"validation.required":    "為必填",
+		"validation.required_if": "在 {0} 時為必填",
+		"validation.oneof":       "必須是 {0} 其中之一",
+		"validation.url":         "必須是網址",
+		"validation.min":         "不得小於 {0}",
+		"validation.min_length":  "至少需要 {0} 個字元",
+		"validation.min_items":   "至少需要 {0} 個項目",
+		"validation.max":         "不得大於 {0}",
+		"validation.max_length":  "最多 {0} 個字元",
+		"validation.max_items":   "最多 {0} 個項目",
+		"validation.gt":          "必須大於 {0}",
+		"validation.gte":         "必須大於或等於 {0}",
+		"validation.lt":          "必須小於 {0}",
+		"validation.lte":         "必須小於或等於 {0}",
+		"validation.invalid":     "格式不正確",
+		"validation.type.string": "必須是字串",
+		"validation.type.bool":   "必須是布林值",
+		"validation.type.int":    "必須是整數",
+		"validation.type.number": "必須是數字",
+		"validation.type.list":   "必須是清單",
+		"validation.type.object": "必須是物件",
+
+		"unsubscribe.confirm": "確定不再收到這類信件嗎？",
+		"unsubscribe.submit":  "取消訂閱",
+		"unsubscribe.done":    "您已取消訂閱這類信件",
+
+		"internal_error":              "發生未預期的錯誤",
+		"bad_request":                 "請求格式不正確",
+		"validation_failed":           "部分欄位不正確",
+		"not_found":                   "找不到資源",
+		"forbidden":                   "您沒有權限存取此資源",
+		"unauthorized":                "尚未登入",
+		"too_many_requests":           "請求過於頻繁，請稍後再試",
+		"invalid_cursor":              "分頁游標不正確",
+		"invalid_sort":                "排序方式不正確",
+		"unsupported_locale":          "不支援此語言",
+		"invalid_salary_range":        "salary_min 必須小於或等於 salary_max",
+		"unknown_notification_type":   "未知的通知類型",
+		"invalid_unsubscribe_token":   "取消訂閱連結不正確",
+		"totp_failed":                 "驗證碼不正確",
+		"multipart_upload_not_found":  "找不到分段上傳",
+		"invalid_part_number":         "分段編號不正確",
+		"unsupported_document":        "不支援此文件格式",
+		"legal_hold":                  "使用者的資料因法律保留無法刪除",
+		"erasure_request_reviewed":    "刪除請求已審核",
+		"invalid_last_event_id":       "Last-Event-ID 不正確",
+		"invalid_idempotency_key":     "冪等鍵最多 255 個字元",
+		"idempotency_key_mismatch":    "冪等鍵已用於不同的請求",
+		"idempotency_key_in_progress": "使用此冪等鍵的請求正在處理中",
+		"idempotency_body_too_large":  "使用冪等鍵的請求內容超過大小上限",
+		"file_too_large":              "檔案超過大小上限",
+		"file_type_not_allowed":       "不允許此檔案類型",
+		"multipart_required":          "檔案超過單次上傳的上限，請改用分段上傳",
+		"multipart_not_required":      "檔案未達分段上傳的門檻，請改用單次上傳",
+		"precondition_required":       "需要 If-Match，請使用資源的 ETag",
+		"precondition_failed":         "資源已被修改，請重新取得後再試",
+		"unsupported_media_type":      "不支援此請求的 Content-Type",
+		"job_closed":                  "此外部編號的職缺已關閉",
+		"too_many_jobs":               "一次最多匯入 1000 個職缺",
+		"duplicate_external_ref":      "此外部編號已用於匯入檔中較前面的職缺",
+		"invalid_selection":           "請以 application_ids 或 filter 其中之一選擇申請",
+		"bulk_tags_required":          "必須提供 add_tags 或 remove_tags",
+		"too_many_tags":               "一個申請最多只能有 20 個標籤",
+		"bulk_action_forbidden":       "只有 HR 可以批次處理申請",
+		"invalid_assignee":            "被指派者必須是面試官",
+		"invalid_reason_template":     "原因範本無效",
+		"too_many_applications":       "篩選條件選到的申請過多",
+		"application_purged":          "此申請已被清除",
+		"invalid_export_link":         "匯出連結無效",
+		"export_link_expired":         "匯出連結已過期",
+		"webhook_delivery_scrubbed":   "此傳送的請求已被清除",
+	},
+	LocaleJapanese: {
+		"list.separator": "、",
+
+		"validation.required":    "は必須です",
+		"validation.required_if": "は {0} の場合に必須です",
+		"validation.oneof":       "は {0} のいずれかである必要があります",
+		"validation.url":         "は URL である必要があります",
+		"validation.min":         "は {0} 以上である必要があります",
+		"validation.min_length":  "は {0} 文字以上である必要があります",
+		"validation.min_items":   "は {0} 件以上である必要があります",
+		"validation.max":         "は {0} 以下である必要があります",
+		"validation.max_length":  "は {0} 文字以下である必要があります",
+		"validation.max_items":   "は {0} 件以下である必要があります",
+		"validation.gt":          "は {0} より大きい必要があります",
+		"validation.gte":         "は {0} 以上である必要があります",
+		"validation.lt":          "は {0} より小さい必要があります",
+		"validation.lte":         "は {0} 以下である必要があります",
+		"validation.invalid":     "が正しくありません",
+		"validation.type.string": "は文字列である必要があります",
+		"validation.type.bool":   "は真偽値である必要があります",
+		"validation.type.int":    "は整数である必要があります",
+		"validation.type.number": "は数値である必要があります",
+		"validation.type.list":   "はリストである必要があります",
+		"validation.type.object": "はオブジェクトである必要があります",
+
+		"unsubscribe.confirm": "このようなメールの受信を停止しますか？",
+		"unsubscribe.submit":  "配信を停止する",
+		"unsubscribe.done":    "このようなメールの配信を停止しました",
+
+		"internal_error":              "予期しないエラーが発生しました",
+		"bad_request":                 "リクエストの形式が正しくありません",
+		"validation_failed":           "一部の項目が正しくありません",
+		"not_found":                   "リソースが見つかりません",
+		"forbidden":                   "このリソースにアクセスする権限がありません",
+		"unauthorized":                "認証されていません",
+		"too_many_requests":           "リクエストが多すぎます。しばらくしてから再試行してください",
+		"invalid_cursor":              "ページネーションのカーソルが正しくありません",
+		"invalid_sort":                "並び順が正しくありません",
+		"unsupported_locale":          "この言語はサポートされていません",
+		"invalid_salary_range":        "salary_min は salary_max 以下である必要があります",
+		"unknown_notification_type":   "不明な通知の種類です",
+		"invalid_unsubscribe_token":   "配信停止リンクが正しくありません",
+		"totp_failed":                 "認証コードが正しくありません",
+		"multipart_upload_not_found":  "マルチパートアップロードが見つかりません",
+		"invalid_part_number":         "パート番号が正しくありません",
+		"unsupported_document":        "この文書形式はサポートされていません",
+		"legal_hold":                  "リーガルホールド中のため、ユーザーのデータは削除できません",
+		"erasure_request_reviewed":    "削除リクエストはすでに審査されています",
+		"invalid_last_event_id":       "Last-Event-ID が正しくありません",
+		"invalid_idempotency_key":     "冪等キーは 255 文字以下である必要があります",
+		"idempotency_key_mismatch":    "冪等キーは別のリクエストで使用されています",
+		"idempotency_key_in_progress": "この冪等キーのリクエストは処理中です",
+		"idempotency_body_too_large":  "冪等キー付きのリクエストの本文がサイズの上限を超えています",
+		"file_too_large":              "ファイルのサイズが上限を超えています",
+		"file_type_not_allowed":       "このファイル形式は許可されていません",
+		"multipart_required":          "ファイルが単一アップロードの上限を超えています。マルチパートアップロードを使用してください",
+		"multipart_not_required":      "ファイルがマルチパートアップロードのしきい値未満です。単一アップロードを使用してください",
+		"precondition_required":       "If-Match が必要です。リソースの ETag を使用してください",
+		"precondition_failed":         "リソースは変更されています。再取得してから再試行してください",
+		"unsupported_media_type":      "このリクエストの Content-Type はサポートされていません",
+		"job_closed":                  "この外部参照の求人はクローズされています",
+		"too_many_jobs":               "一度にインポートできる求人は 1000 件までです",
+		"duplicate_external_ref":      "この外部参照はインポートの前の求人で使用されています",
+		"invalid_selection":           "application_ids または filter のどちらか一方で応募を選択してください",
+		"bulk_tags_required":          "add_tags または remove_tags が必要です",
+		"too_many_tags":               "応募に付けられるタグは 20 個までです",
+		"bulk_action_forbidden":       "応募を一括で処理できるのは HR のみです",
+		"invalid_assignee":            "割り当て先は面接官である必要があります",
+		"invalid_reason_template":     "理由テンプレートが無効です",
+		"too_many_applications":       "フィルターで選択された応募が多すぎます",
+		"application_purged":          "この応募は削除されています",
+		"invalid_export_link":         "エクスポートのリンクが無効です",
+		"export_link_expired":         "エクスポートのリンクの有効期限が切れています",
+		"webhook_delivery_scrubbed":   "この配信のリクエストは削除されています",
+	},
+}
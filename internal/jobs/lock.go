@@ -0,0 +1,62 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var ErrLockNotAcquired = errors.New("lock is held by another owner")
+
+// releaseScript deletes the lock only when it's still held by the owner, it may have expired and been
+// acquired by another owner.
+var releaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// Locker acquires distributed locks with SET NX, a lock expires after its TTL in case its owner disappears.
+type Locker struct {
+	redisClient *redis.Client
+}
+
+func NewLocker(redisClient *redis.Client) *Locker {
+	return &Locker{redisClient}
+}
+
+type Lock struct {
+	redisClient *redis.Client
+	key         string
+	token       string
+}
+
+// Acquire acquires the lock of key, it fails with ErrLockNotAcquired when another owner holds it.
+func (l *Locker) Acquire(ctx context.Context, key string, ttl time.Duration) (*Lock, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+
+	lock := &Lock{l.redisClient, "{jobs}:lock:" + key, hex.EncodeToString(token)}
+
+	ok, err := l.redisClient.SetNX(ctx, lock.key, lock.token, ttl).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrLockNotAcquired
+	}
+
+	return lock, nil
+}
+
+// Release releases the lock if it's still held.
+func (lock *Lock) Release(ctx context.Context) error {
+	return releaseScript.Run(ctx, lock.redisClient, []string{lock.key}, lock.token).Err()
+}
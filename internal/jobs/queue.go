@@ -0,0 +1,177 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"fliqt/config"
+)
+
+const (
+	// Keys share the {jobs} hash tag, so scripts work on Redis Cluster as well.
+	scheduledKey = "{jobs}:scheduled"
+	readyKey     = "{jobs}:ready"
+	inflightKey  = "{jobs}:inflight"
+	deadKey      = "{jobs}:dead"
+
+	// Tasks whose worker disappeared are run again once their timeout plus this grace period has passed.
+	inflightGrace = 30 * time.Second
+	moveBatchSize = 100
+	maxBackoff    = time.Hour
+)
+
+// dequeueScript moves due tasks and abandoned in-flight tasks to the ready list, then pops a task and
+// marks it in-flight until its deadline, atomically.
+var dequeueScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+for _, key in ipairs({KEYS[1], KEYS[3]}) do
+	local due = redis.call('ZRANGEBYSCORE', key, '-inf', now, 'LIMIT', 0, ARGV[2])
+	for _, task in ipairs(due) do
+		redis.call('ZREM', key, task)
+		redis.call('LPUSH', KEYS[2], task)
+	end
+end
+
+local task = redis.call('RPOP', KEYS[2])
+if not task then
+	return false
+end
+
+local timeout = tonumber(cjson.decode(task)['timeout_ms']) or 0
+redis.call('ZADD', KEYS[3], now + timeout + tonumber(ARGV[3]), task)
+return task
+`)
+
+// Queue is a Redis-backed queue of tasks, tasks are delivered at least once.
+type Queue struct {
+	redisClient    *redis.Client
+	defaultTimeout time.Duration
+	defaultRetries int
+	retryBackoff   time.Duration
+}
+
+func NewQueue(cfg *config.Config, redisClient *redis.Client) *Queue {
+	return &Queue{
+		redisClient,
+		cfg.JobDefaultTimeout,
+		cfg.JobDefaultMaxRetries,
+		cfg.JobRetryBackoff,
+	}
+}
+
+// Enqueue enqueues a task, the trace context of ctx is carried along so the task links back to it.
+func (q *Queue) Enqueue(ctx context.Context, taskType string, payload interface{}, opts ...Option) (*Task, error) {
+	o := options{
+		maxRetries: q.defaultRetries,
+		timeout:    q.defaultTimeout,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	task, err := newTask(ctx, taskType, payload, o)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(task)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.runAt.After(time.Now()) {
+		return task, q.redisClient.ZAdd(ctx, scheduledKey, &redis.Z{Score: float64(o.runAt.UnixMilli()), Member: data}).Err()
+	}
+
+	return task, q.redisClient.LPush(ctx, readyKey, data).Err()
+}
+
+// Dequeue returns the next ready task, or nil when there's none.
+func (q *Queue) Dequeue(ctx context.Context) (*Task, error) {
+	raw, err := dequeueScript.Run(ctx, q.redisClient,
+		[]string{scheduledKey, readyKey, inflightKey},
+		time.Now().UnixMilli(), moveBatchSize, inflightGrace.Milliseconds(),
+	).Text()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var task Task
+	if err := json.Unmarshal([]byte(raw), &task); err != nil {
+		// It can never be run, keep it for inspection.
+		q.redisClient.ZRem(ctx, inflightKey, raw)
+		q.redisClient.LPush(ctx, deadKey, raw)
+		return nil, err
+	}
+	task.raw = raw
+
+	return &task, nil
+}
+
+// Ack removes a finished task.
+func (q *Queue) Ack(ctx context.Context, task *Task) error {
+	return q.redisClient.ZRem(ctx, inflightKey, task.raw).Err()
+}
+
+// Retry schedules the next attempt of a failed task with exponential backoff, tasks without retries left
+// are moved to the dead-letter list.
+func (q *Queue) Retry(ctx context.Context, task *Task) error {
+	next := *task
+	next.Attempt++
+
+	data, err := json.Marshal(next)
+	if err != nil {
+		return err
+	}
+
+	pipe := q.redisClient.TxPipeline()
+	pipe.ZRem(ctx, inflightKey, task.raw)
+	if task.Attempt > task.MaxRetries {
+		pipe.LPush(ctx, deadKey, data)
+	} else {
+		runAt := time.Now().Add(retryBackoff(q.retryBackoff, task.Attempt))
+		pipe.ZAdd(ctx, scheduledKey, &redis.Z{Score: float64(runAt.UnixMilli()), Member: data})
+	}
+	_, err = pipe.Exec(ctx)
+
+	return err
+}
+
+// Stats returns the number of tasks in each state.
+func (q *Queue) Stats(ctx context.Context) (map[string]int64, error) {
+	pipe := q.redisClient.Pipeline()
+	scheduled := pipe.ZCard(ctx, scheduledKey)
+	ready := pipe.LLen(ctx, readyKey)
+	inflight := pipe.ZCard(ctx, inflightKey)
+	dead := pipe.LLen(ctx, deadKey)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	return map[string]int64{
+		"scheduled": scheduled.Val(),
+		"ready":     ready.Val(),
+		"inflight":  inflight.Val(),
+		"dead":      dead.Val(),
+	}, nil
+}
+
+// retryBackoff doubles the backoff on every attempt, up to maxBackoff.
+func retryBackoff(base time.Duration, attempt int) time.Duration {
+	backoff := base
+	for i := 1; i < attempt && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+
+	if backoff > maxBackoff {
+		return maxBackoff
+	}
+
+	return backoff
+}
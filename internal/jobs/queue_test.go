@@ -0,0 +1,52 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestRetryBackoff(t *testing.T) {
+	for attempt, expected := range map[int]time.Duration{
+		1:  10 * time.Second,
+		2:  20 * time.Second,
+		4:  80 * time.Second,
+		30: maxBackoff,
+	} {
+		if backoff := retryBackoff(10*time.Second, attempt); backoff != expected {
+			t.Errorf("expected %s after attempt %d, got %s", expected, attempt, backoff)
+		}
+	}
+}
+
+func TestNewTaskCarriesTraceContext(t *testing.T) {
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	task, err := newTask(ctx, "retention.purge", map[string]string{"reason": "test"}, options{maxRetries: 3, timeout: time.Minute})
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+
+	if task.Attempt != 1 || task.MaxRetries != 3 || task.Timeout() != time.Minute {
+		t.Errorf("unexpected task %+v", task)
+	}
+
+	linked := trace.SpanContextFromContext(propagation.TraceContext{}.Extract(context.Background(), propagation.MapCarrier(task.TraceContext)))
+	if linked.TraceID() != traceID || linked.SpanID() != spanID {
+		t.Errorf("expected the task to carry the trace context of the request, got %v", task.TraceContext)
+	}
+
+	var payload map[string]string
+	if err := task.Decode(&payload); err != nil || payload["reason"] != "test" {
+		t.Errorf("unexpected payload %s, %v", task.Payload, err)
+	}
+}
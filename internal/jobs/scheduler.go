@@ -0,0 +1,75 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/rs/zerolog"
+)
+
+// Every replica runs the scheduler, the lock of an occurrence expires this early so the next one isn't blocked.
+const lockMargin = time.Second
+
+// Scheduler enqueues recurring tasks on cron expressions (with optional seconds, or descriptors like @every 1h).
+// Every replica runs it, and a lock makes sure each occurrence is enqueued by only one of them.
+type Scheduler struct {
+	cron   *cron.Cron
+	queue  *Queue
+	locker *Locker
+	logger *zerolog.Logger
+}
+
+func NewScheduler(queue *Queue, locker *Locker, logger *zerolog.Logger) *Scheduler {
+	return &Scheduler{
+		cron.New(cron.WithParser(cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor))),
+		queue,
+		locker,
+		logger,
+	}
+}
+
+// Add enqueues a task of the type on the cron expression.
+func (s *Scheduler) Add(spec string, taskType string, payload interface{}, opts ...Option) error {
+	var id cron.EntryID
+	var err error
+
+	id, err = s.cron.AddFunc(spec, func() {
+		ctx := context.Background()
+		now := time.Now()
+
+		// The lock lasts until the next occurrence, so replicas whose clocks or @every timers are off
+		// don't enqueue the same occurrence again.
+		ttl := s.cron.Entry(id).Schedule.Next(now).Sub(now) - lockMargin
+		if ttl < lockMargin {
+			ttl = lockMargin
+		}
+
+		if _, err := s.locker.Acquire(ctx, "cron:"+taskType, ttl); err != nil {
+			if !errors.Is(err, ErrLockNotAcquired) {
+				s.logger.Error().Err(err).Str("type", taskType).Msg("failed to acquire lock of recurring task")
+			}
+			return
+		}
+
+		task, err := s.queue.Enqueue(ctx, taskType, payload, opts...)
+		if err != nil {
+			s.logger.Error().Err(err).Str("type", taskType).Msg("failed to enqueue recurring task")
+			return
+		}
+
+		s.logger.Info().Str("type", taskType).Str("task_id", task.ID).Msg("recurring task enqueued")
+	})
+
+	return err
+}
+
+func (s *Scheduler) Start() {
+	s.cron.Start()
+}
+
+// Stop stops scheduling, the returned context is done once running jobs of the scheduler complete.
+func (s *Scheduler) Stop() context.Context {
+	return s.cron.Stop()
+}
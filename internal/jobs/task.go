@@ -0,0 +1,95 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/rs/xid"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// Task is a unit of background work.
+type Task struct {
+	ID      string          `json:"id"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload"`
+	// Attempt starts from 1
+	Attempt    int   `json:"attempt"`
+	MaxRetries int   `json:"max_retries"`
+	TimeoutMs  int64 `json:"timeout_ms"`
+	// TraceContext is the W3C trace context of the enqueuing request, the task's span links to it
+	TraceContext map[string]string `json:"trace_context,omitempty"`
+	EnqueuedAt   time.Time         `json:"enqueued_at"`
+
+	// raw is the task as stored in Redis, it identifies the task in the in-flight set
+	raw string
+}
+
+// Timeout is how long a single attempt of the task may run.
+func (t *Task) Timeout() time.Duration {
+	return time.Duration(t.TimeoutMs) * time.Millisecond
+}
+
+// Decode decodes the payload of the task.
+func (t *Task) Decode(v interface{}) error {
+	return json.Unmarshal(t.Payload, v)
+}
+
+type options struct {
+	runAt      time.Time
+	maxRetries int
+	timeout    time.Duration
+}
+
+// Option customizes an enqueued task.
+type Option func(*options)
+
+// Delay runs the task after d.
+func Delay(d time.Duration) Option {
+	return func(o *options) {
+		o.runAt = time.Now().Add(d)
+	}
+}
+
+// At runs the task at t.
+func At(t time.Time) Option {
+	return func(o *options) {
+		o.runAt = t
+	}
+}
+
+// MaxRetries is how many times the task is retried after it fails.
+func MaxRetries(n int) Option {
+	return func(o *options) {
+		o.maxRetries = n
+	}
+}
+
+// Timeout limits how long an attempt of the task may run.
+func Timeout(d time.Duration) Option {
+	return func(o *options) {
+		o.timeout = d
+	}
+}
+
+func newTask(ctx context.Context, taskType string, payload interface{}, o options) (*Task, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(ctx, carrier)
+
+	return &Task{
+		ID:           xid.New().String(),
+		Type:         taskType,
+		Payload:      data,
+		Attempt:      1,
+		MaxRetries:   o.maxRetries,
+		TimeoutMs:    o.timeout.Milliseconds(),
+		TraceContext: carrier,
+		EnqueuedAt:   time.Now(),
+	}, nil
+}
@@ -0,0 +1,137 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"fliqt/config"
+)
+
+var ErrUnknownTask = errors.New("unknown task type")
+
+var tracer = otel.Tracer("fliqt/jobs")
+
+// Handler runs a task, the task is retried when it returns an error.
+type Handler func(ctx context.Context, task *Task) error
+
+// Worker runs tasks of the queue with its registered handlers.
+type Worker struct {
+	queue        *Queue
+	logger       *zerolog.Logger
+	concurrency  int
+	pollInterval time.Duration
+	handlers     map[string]Handler
+}
+
+func NewWorker(cfg *config.Config, queue *Queue, logger *zerolog.Logger) *Worker {
+	return &Worker{
+		queue,
+		logger,
+		cfg.WorkerConcurrency,
+		cfg.JobPollInterval,
+		map[string]Handler{},
+	}
+}
+
+// Register registers the handler of a task type.
+func (w *Worker) Register(taskType string, handler Handler) {
+	w.handlers[taskType] = handler
+}
+
+// Run runs tasks until ctx is done, then waits for running tasks to finish.
+func (w *Worker) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < w.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.loop(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (w *Worker) loop(ctx context.Context) {
+	for ctx.Err() == nil {
+		task, err := w.queue.Dequeue(ctx)
+		if err != nil && ctx.Err() == nil {
+			w.logger.Error().Err(err).Msg("failed to dequeue task")
+		}
+
+		if task == nil {
+			select {
+			case <-ctx.Done():
+			case <-time.After(w.pollInterval):
+			}
+			continue
+		}
+
+		// A running task finishes even when the worker is stopping, its own timeout still applies.
+		w.process(context.WithoutCancel(ctx), task)
+	}
+}
+
+func (w *Worker) process(ctx context.Context, task *Task) {
+	// The task runs in its own trace which links back to the request that enqueued it.
+	linked := propagation.TraceContext{}.Extract(context.Background(), propagation.MapCarrier(task.TraceContext))
+	ctx, span := tracer.Start(ctx, "jobs."+task.Type,
+		trace.WithNewRoot(),
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithLinks(trace.LinkFromContext(linked)),
+		trace.WithAttributes(
+			attribute.String("task.id", task.ID),
+			attribute.String("task.type", task.Type),
+			attribute.Int("task.attempt", task.Attempt),
+		),
+	)
+	defer span.End()
+
+	logger := w.logger.With().Str("task_id", task.ID).Str("type", task.Type).Int("attempt", task.Attempt).Logger()
+
+	err := w.run(ctx, task)
+	if err == nil {
+		if err := w.queue.Ack(ctx, task); err != nil {
+			logger.Error().Err(err).Msg("failed to ack task")
+		}
+		return
+	}
+
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	logger.Error().Err(err).Msg("task failed")
+
+	if err := w.queue.Retry(ctx, task); err != nil {
+		logger.Error().Err(err).Msg("failed to retry task")
+	}
+}
+
+func (w *Worker) run(ctx context.Context, task *Task) (err error) {
+	handler, ok := w.handlers[task.Type]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownTask, task.Type)
+	}
+
+	if task.TimeoutMs > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, task.Timeout())
+		defer cancel()
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("task panicked: %v", r)
+		}
+	}()
+
+	return handler(ctx, task)
+}
@@ -0,0 +1,63 @@
+// Package mergepatch applies JSON merge patches (RFC 7396).
+package mergepatch
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// ContentType is the media type of merge patches.
+const ContentType = "application/merge-patch+json"
+
+// Apply applies patch to the JSON document target. Members of patch replace those of target, objects are
+// merged recursively, and null removes a member, so absent and null members of patch are told apart.
+func Apply(target []byte, patch []byte) ([]byte, error) {
+	patchValue, err := decode(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	var targetValue interface{}
+	if len(bytes.TrimSpace(target)) > 0 {
+		if targetValue, err = decode(target); err != nil {
+			return nil, err
+		}
+	}
+
+	return json.Marshal(merge(targetValue, patchValue))
+}
+
+// decode keeps numbers as they're written, so big integers don't lose precision as float64.
+func decode(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+func merge(target interface{}, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+			continue
+		}
+		targetObject[name] = merge(targetObject[name], value)
+	}
+
+	return targetObject
+}
@@ -0,0 +1,63 @@
+package mergepatch
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	// Examples of RFC 7396, appendix A
+	tests := []struct {
+		target   string
+		patch    string
+		expected string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+
+	for _, tt := range tests {
+		result, err := Apply([]byte(tt.target), []byte(tt.patch))
+		if err != nil {
+			t.Errorf("%s + %s: unexpected error %v", tt.target, tt.patch, err)
+			continue
+		}
+
+		var actual, expected interface{}
+		json.Unmarshal(result, &actual)
+		json.Unmarshal([]byte(tt.expected), &expected)
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("%s + %s: expected %s, got %s", tt.target, tt.patch, tt.expected, result)
+		}
+	}
+}
+
+func TestApplyKeepsNumbers(t *testing.T) {
+	result, err := Apply([]byte(`{"id":9007199254740993}`), []byte(`{"salary":1.50}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result) != `{"id":9007199254740993,"salary":1.50}` {
+		t.Errorf("expected numbers as they're written, got %s", result)
+	}
+}
+
+func TestApplyInvalidPatch(t *testing.T) {
+	if _, err := Apply([]byte(`{}`), []byte(`{`)); err == nil {
+		t.Error("expected an error for malformed JSON")
+	}
+}
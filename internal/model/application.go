@@ -1,5 +1,15 @@
 package model
 
+import "time"
+
+const (
+	ApplicationStatusAccepted  = "accepted"
+	ApplicationStatusPending   = "pending"
+	ApplicationStatusRejected  = "rejected"
+	ApplicationStatusWithdrawn = "withdrawn"
+	ApplicationStatusHired     = "hired"
+)
+
 type Application struct {
 	Base
 
@@ -7,6 +17,13 @@ type Application struct {
 	Job             Job    `gorm:"foreignKey:JobID"`
 	UserID          string `gorm:"not null;index:idx_application_user_id"`
 	User            User   `gorm:"foreignKey:UserID"`
-	Status          string `gorm:"type:enum('accepted', 'pending', 'rejected');default:'accepted';index:idx_application_status"`
+	Status          string `gorm:"type:enum('accepted', 'pending', 'rejected', 'withdrawn', 'hired');default:'accepted';index:idx_application_status"`
 	ResumeObjectKey string `gorm:"not null"`
+	// AssigneeID is the interviewer who reviews the application
+	AssigneeID *string    `gorm:"type:varchar(20);index:idx_application_assignee_id" json:",omitempty"`
+	Tags       StringList `gorm:"type:json"`
+	// PurgedAt is set when the resume is deleted and the application is anonymized by the retention policy
+	PurgedAt *time.Time `gorm:"index:idx_application_purged_at" json:",omitempty"`
+	// Version is incremented on every change, it's the ETag of the application
+	Version int64 `gorm:"not null;default:1"`
 }
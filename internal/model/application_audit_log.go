@@ -0,0 +1,19 @@
+package model
+
+import "encoding/json"
+
+// ApplicationAuditLog records an action on an application and who took it. Items of bulk operations are
+// recorded whether they succeeded or not, so the log also tells which items an operation has processed.
+type ApplicationAuditLog struct {
+	Base
+
+	ApplicationID string `gorm:"not null;type:varchar(20);index:idx_application_audit_log_application_id;uniqueIndex:idx_application_audit_log_bulk_item,priority:2"`
+	ActorID       string `gorm:"not null;type:varchar(20)"`
+	Action        string `gorm:"not null;type:varchar(32)"`
+	// BulkOperationID is set when the action is an item of a bulk operation
+	BulkOperationID *string         `gorm:"type:varchar(20);uniqueIndex:idx_application_audit_log_bulk_item,priority:1" json:",omitempty"`
+	Details         json.RawMessage `gorm:"type:json" json:",omitempty"`
+	Succeeded       bool            `gorm:"not null"`
+	// ErrorCode is the code of the apperror.Error the action failed with
+	ErrorCode string `gorm:"not null;type:varchar(64);default:''" json:",omitempty"`
+}
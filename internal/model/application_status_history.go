@@ -0,0 +1,13 @@
+package model
+
+// ApplicationStatusHistory records every status change of an application.
+type ApplicationStatusHistory struct {
+	Base
+
+	ApplicationID string `gorm:"not null;type:varchar(20);index:idx_application_status_history_application_id"`
+	FromStatus    string `gorm:"not null;type:varchar(32)"`
+	ToStatus      string `gorm:"not null;type:varchar(32)"`
+	ChangedBy     string `gorm:"not null;type:varchar(20)"`
+	// Reason is told to the candidate, e.g. why they're rejected
+	Reason string `gorm:"not null;type:text"`
+}
@@ -0,0 +1,56 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	BulkOperationPending   = "pending"
+	BulkOperationRunning   = "running"
+	BulkOperationCompleted = "completed"
+	BulkOperationFailed    = "failed"
+)
+
+// Actions of bulk operations on applications
+const (
+	BulkActionStatus = "status"
+	BulkActionReject = "reject"
+	BulkActionAssign = "assign"
+	BulkActionTag    = "tag"
+	BulkActionExport = "export"
+)
+
+// Formats of exports
+const (
+	ExportFormatCSV  = "csv"
+	ExportFormatXLSX = "xlsx"
+	ExportFormatZIP  = "zip"
+)
+
+// BulkOperation is an action on many applications which runs on the worker, it reports its progress as it goes.
+type BulkOperation struct {
+	Base
+
+	Action string `gorm:"not null;type:varchar(32)"`
+	// Params are the parameters of the action as requested
+	Params json.RawMessage `gorm:"not null;type:json"`
+	Status string          `gorm:"not null;type:varchar(16);default:'pending'"`
+	// ApplicationIDs are the applications the operation applies to, a filter is resolved once when it starts
+	ApplicationIDs StringList `gorm:"type:json" json:"-"`
+	Total          int        `gorm:"not null;default:0"`
+	Processed      int        `gorm:"not null;default:0"`
+	Succeeded      int        `gorm:"not null;default:0"`
+	Failed         int        `gorm:"not null;default:0"`
+	// ResultObjectKey is the file an export is written to
+	ResultObjectKey string     `gorm:"not null;type:varchar(1024);default:''" json:"-"`
+	Error           string     `gorm:"not null;type:varchar(1024);default:''"`
+	CreatedBy       string     `gorm:"not null;type:varchar(20);index:idx_bulk_operation_created_by"`
+	StartedAt       *time.Time `json:",omitempty"`
+	FinishedAt      *time.Time `json:",omitempty"`
+}
+
+// Finished reports whether the operation won't change anymore.
+func (o *BulkOperation) Finished() bool {
+	return o.Status == BulkOperationCompleted || o.Status == BulkOperationFailed
+}
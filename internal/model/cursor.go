@@ -0,0 +1,282 @@
+package model
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"fliqt/internal/apperror"
+)
+
+var (
+	ErrInvalidCursor = apperror.New(http.StatusBadRequest, "invalid_cursor", "invalid pagination cursor")
+	ErrInvalidSort   = apperror.New(http.StatusBadRequest, "invalid_sort", "invalid sort")
+)
+
+// cursorSecret signs cursors, so clients can't forge values of sort keys. It's set once on startup.
+var cursorSecret []byte
+
+func SetCursorSecret(secret string) {
+	cursorSecret = []byte(secret)
+}
+
+// SortKind tells how values of a sort key are decoded from cursors.
+type SortKind int
+
+const (
+	SortKindString SortKind = iota
+	SortKindInt
+	SortKindFloat
+	SortKindTime
+)
+
+// SortKey is a key list endpoints can be sorted by. Column is an SQL expression with Args as its
+// parameters, it mustn't be NULL, otherwise rows would be skipped between pages.
+type SortKey struct {
+	Column string
+	Args   []interface{}
+	Kind   SortKind
+}
+
+type sortField struct {
+	name string
+	key  SortKey
+	desc bool
+}
+
+// Sort is the order of a list, the ID always breaks ties so every row has a unique position.
+type Sort struct {
+	spec   string
+	fields []sortField
+}
+
+// ParseSort parses comma separated sort keys such as `salary_max,-created_at`, descending ones are
+// prefixed with `-`. An empty spec sorts by ID in descending order, so the newest come first.
+func ParseSort(spec string, keys map[string]SortKey, idColumn string) (*Sort, error) {
+	sort := &Sort{spec: spec}
+	seen := map[string]bool{}
+
+	if spec != "" {
+		for _, name := range strings.Split(spec, ",") {
+			desc := strings.HasPrefix(name, "-")
+			name = strings.TrimPrefix(name, "-")
+
+			key, ok := keys[name]
+			if !ok || seen[name] {
+				return nil, ErrInvalidSort
+			}
+			seen[name] = true
+
+			sort.fields = append(sort.fields, sortField{name, key, desc})
+		}
+	}
+
+	sort.fields = append(sort.fields, sortField{"id", SortKey{Column: idColumn, Kind: SortKindString}, true})
+
+	return sort, nil
+}
+
+// cursor is the position of an item in a sorted list, the sort is kept so it can't be used with another.
+type cursor struct {
+	Sort     string        `json:"s"`
+	Values   []interface{} `json:"v"`
+	Backward bool          `json:"b,omitempty"`
+}
+
+func signCursor(payload string) string {
+	mac := hmac.New(sha256.New, cursorSecret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
+
+func encodeCursor(c cursor) (string, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString(data)
+
+	return payload + "." + signCursor(payload), nil
+}
+
+func decodeCursor(token string, sort *Sort) (*cursor, error) {
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(signCursor(payload))) {
+		return nil, ErrInvalidCursor
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c cursor
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	// A cursor only makes sense with the sort it was created for
+	if c.Sort != sort.spec || len(c.Values) != len(sort.fields) {
+		return nil, ErrInvalidCursor
+	}
+
+	for i, field := range sort.fields {
+		value, err := decodeSortValue(c.Values[i], field.key.Kind)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		c.Values[i] = value
+	}
+
+	return &c, nil
+}
+
+func decodeSortValue(value interface{}, kind SortKind) (interface{}, error) {
+	switch kind {
+	case SortKindInt:
+		if n, ok := value.(json.Number); ok {
+			return n.Int64()
+		}
+	case SortKindFloat:
+		if n, ok := value.(json.Number); ok {
+			return n.Float64()
+		}
+	case SortKindTime:
+		if s, ok := value.(string); ok {
+			return time.Parse(time.RFC3339Nano, s)
+		}
+	default:
+		if s, ok := value.(string); ok {
+			return s, nil
+		}
+	}
+
+	return nil, ErrInvalidCursor
+}
+
+// Paginate fetches a page of query, which mustn't be ordered or limited yet, in the order of sort. Pages are
+// continued from the position of the cursor rather than an offset, so they stay stable while rows are added.
+// value returns the value of a sort key of an item, `id` is the ID.
+func Paginate[T any](query *gorm.DB, params PaginationParams, sort *Sort, value func(item T, key string) interface{}) (PaginationResponse[T], error) {
+	var result PaginationResponse[T]
+
+	if params.NextToken != "" && params.PrevToken != "" {
+		return result, ErrInvalidCursor
+	}
+
+	var position *cursor
+	for _, token := range []string{params.NextToken, params.PrevToken} {
+		if token == "" {
+			continue
+		}
+
+		var err error
+		if position, err = decodeCursor(token, sort); err != nil {
+			return result, err
+		}
+	}
+	backward := position != nil && position.Backward
+
+	if params.IncludeTotal {
+		var total int64
+		if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+			return result, err
+		}
+		result.Total = &total
+	}
+
+	// Pages before the cursor are fetched in reverse order, then reversed back.
+	orders := make([]string, 0, len(sort.fields))
+	var orderArgs []interface{}
+	for _, field := range sort.fields {
+		direction := " ASC"
+		if field.desc != backward {
+			direction = " DESC"
+		}
+		orders = append(orders, field.key.Column+direction)
+		orderArgs = append(orderArgs, field.key.Args...)
+	}
+
+	if position != nil {
+		// (a > ?) OR (a = ? AND b < ?) OR ..., row constructors can't mix directions
+		var conditions []string
+		var args []interface{}
+		for i, field := range sort.fields {
+			var terms []string
+			for j := 0; j < i; j++ {
+				terms = append(terms, sort.fields[j].key.Column+" = ?")
+				args = append(args, sort.fields[j].key.Args...)
+				args = append(args, position.Values[j])
+			}
+
+			operator := " > ?"
+			if field.desc != backward {
+				operator = " < ?"
+			}
+			terms = append(terms, field.key.Column+operator)
+			args = append(args, field.key.Args...)
+			args = append(args, position.Values[i])
+
+			conditions = append(conditions, "("+strings.Join(terms, " AND ")+")")
+		}
+
+		query = query.Where("("+strings.Join(conditions, " OR ")+")", args...)
+	}
+
+	var items []T
+	if err := query.
+		Order(clause.OrderBy{Expression: clause.Expr{SQL: strings.Join(orders, ", "), Vars: orderArgs, WithoutParentheses: true}}).
+		Limit(params.PageSize + 1).
+		Find(&items).Error; err != nil {
+		return result, err
+	}
+
+	more := len(items) > params.PageSize
+	if more {
+		items = items[:params.PageSize]
+	}
+	if backward {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+
+	result.Items = items
+	if len(items) == 0 {
+		return result, nil
+	}
+
+	cursorOf := func(item T, backward bool) (string, error) {
+		values := make([]interface{}, len(sort.fields))
+		for i, field := range sort.fields {
+			values[i] = value(item, field.name)
+		}
+		return encodeCursor(cursor{Sort: sort.spec, Values: values, Backward: backward})
+	}
+
+	// Going backward there's always a next page, the one we came from, and vice versa.
+	var err error
+	if more || backward {
+		if result.NextToken, err = cursorOf(items[len(items)-1], false); err != nil {
+			return result, err
+		}
+	}
+	if (more && backward) || (position != nil && !backward) {
+		if result.PrevToken, err = cursorOf(items[0], true); err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,14 @@
+package model
+
+// DownloadLog records every download of a file, its ID is embedded in watermarks so leaked
+// documents can be traced back to the download.
+type DownloadLog struct {
+	Base
+
+	ObjectKey   string `gorm:"not null;type:varchar(255);index:idx_download_log_object_key"`
+	UserID      string `gorm:"not null;type:varchar(20);index:idx_download_log_user_id"`
+	User        User   `gorm:"foreignKey:UserID"`
+	Watermarked bool   `gorm:"not null;default:false"`
+	ClientIP    string `gorm:"not null;type:varchar(45)"`
+	UserAgent   string `gorm:"not null;type:varchar(512)"`
+}
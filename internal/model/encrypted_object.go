@@ -0,0 +1,13 @@
+package model
+
+// EncryptedObject keeps the data key of an encrypted S3 object, the data key is wrapped by a
+// key-encryption key of the tenant, so it's never stored in plaintext.
+type EncryptedObject struct {
+	Base
+
+	ObjectKey  string `gorm:"not null;type:varchar(255);uniqueIndex:idx_encrypted_object_object_key"`
+	TenantID   string `gorm:"not null;type:varchar(20);index:idx_encrypted_object_tenant_id"`
+	KeyID      string `gorm:"not null;type:varchar(64);index:idx_encrypted_object_key_id"`
+	WrappedKey []byte `gorm:"not null;type:varbinary(128)"`
+	Algorithm  string `gorm:"not null;type:varchar(16)"`
+}
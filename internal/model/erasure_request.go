@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+const (
+	ErasureStatusPending   = "pending"
+	ErasureStatusRejected  = "rejected"
+	ErasureStatusCompleted = "completed"
+)
+
+// ErasureRequest is a candidate's request to erase their data, HR reviews it before anything is erased.
+type ErasureRequest struct {
+	Base
+
+	UserID          string     `gorm:"not null;type:varchar(20);index:idx_erasure_request_user_id"`
+	Status          string     `gorm:"type:enum('pending', 'rejected', 'completed');default:'pending';index:idx_erasure_request_status"`
+	ReviewerID      *string    `gorm:"type:varchar(20)" json:",omitempty"`
+	ReviewedAt      *time.Time `json:",omitempty"`
+	RejectionReason string     `gorm:"not null;default:''" json:",omitempty"`
+}
@@ -0,0 +1,10 @@
+package model
+
+type FilePurpose string
+
+const (
+	FilePurposeResume       FilePurpose = "resume"
+	FilePurposePortfolio    FilePurpose = "portfolio"
+	FilePurposeVideo        FilePurpose = "video"
+	FilePurposeDesignSample FilePurpose = "design_sample"
+)
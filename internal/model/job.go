@@ -11,10 +11,19 @@ const (
 type Job struct {
 	Base
 
-	// Title and Company have a full-text index
-	Title     string  `gorm:"not null"`
-	Company   string  `gorm:"not null"`
+	// Title and Company have a full-text index with the n-gram parser, so CJK can be searched
+	Title       string `gorm:"not null"`
+	Company     string `gorm:"not null"`
+	Description string `gorm:"not null;type:text"`
+	// Locale is the language of Title and Description, other languages are in JobTranslation
+	Locale    string  `gorm:"not null;type:varchar(16);default:'en'"`
 	JobType   JobType `gorm:"type:enum('full-time', 'part-time', 'contract');default:'full-time';index:idx_job_job_type"`
 	SalaryMin int     `gorm:"not null;index:idx_job_salary_min"`
 	SalaryMax int     `gorm:"not null;index:idx_job_salary_max"`
+	// Location is where the job is based, e.g. Taipei or Remote
+	Location string `gorm:"not null;type:varchar(255);default:'';index:idx_job_location"`
+	// ExternalRef identifies the job in the system it's imported from, e.g. the ID of a requisition
+	ExternalRef *string `gorm:"type:varchar(255);uniqueIndex:idx_job_external_ref"`
+	// Version is incremented on every change of the job or its translations, it's the ETag of the job
+	Version int64 `gorm:"not null;default:1"`
 }
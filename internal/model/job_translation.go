@@ -0,0 +1,12 @@
+package model
+
+// JobTranslation is the content of a job in another language than its own. Title and Description have
+// a full-text index with the n-gram parser, so CJK can be searched.
+type JobTranslation struct {
+	Base
+
+	JobID       string `gorm:"not null;type:varchar(20);uniqueIndex:idx_job_translation_job_locale"`
+	Locale      string `gorm:"not null;type:varchar(16);uniqueIndex:idx_job_translation_job_locale"`
+	Title       string `gorm:"not null"`
+	Description string `gorm:"not null;type:text"`
+}
@@ -0,0 +1,30 @@
+package migration
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+
+	"fliqt/internal/model"
+)
+
+func Migration0002() *gormigrate.Migration {
+	type EncryptedObject struct {
+		model.Base
+
+		ObjectKey  string `gorm:"not null;type:varchar(255);uniqueIndex:idx_encrypted_object_object_key"`
+		TenantID   string `gorm:"not null;type:varchar(20);index:idx_encrypted_object_tenant_id"`
+		KeyID      string `gorm:"not null;type:varchar(64);index:idx_encrypted_object_key_id"`
+		WrappedKey []byte `gorm:"not null;type:varbinary(128)"`
+		Algorithm  string `gorm:"not null;type:varchar(16)"`
+	}
+
+	return &gormigrate.Migration{
+		ID: "0002",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&EncryptedObject{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&EncryptedObject{})
+		},
+	}
+}
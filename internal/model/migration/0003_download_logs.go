@@ -0,0 +1,30 @@
+package migration
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+
+	"fliqt/internal/model"
+)
+
+func Migration0003() *gormigrate.Migration {
+	type DownloadLog struct {
+		model.Base
+
+		ObjectKey   string `gorm:"not null;type:varchar(255);index:idx_download_log_object_key"`
+		UserID      string `gorm:"not null;type:varchar(20);index:idx_download_log_user_id"`
+		Watermarked bool   `gorm:"not null;default:false"`
+		ClientIP    string `gorm:"not null;type:varchar(45)"`
+		UserAgent   string `gorm:"not null;type:varchar(512)"`
+	}
+
+	return &gormigrate.Migration{
+		ID: "0003",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&DownloadLog{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&DownloadLog{})
+		},
+	}
+}
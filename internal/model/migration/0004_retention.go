@@ -0,0 +1,81 @@
+package migration
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+
+	"fliqt/internal/model"
+)
+
+func Migration0004() *gormigrate.Migration {
+	type User struct {
+		model.Base
+
+		LegalHold       bool   `gorm:"not null;default:false"`
+		LegalHoldReason string `gorm:"not null;default:''"`
+		AnonymizedAt    *time.Time
+	}
+
+	type Application struct {
+		model.Base
+
+		PurgedAt *time.Time `gorm:"index:idx_application_purged_at"`
+	}
+
+	type Tombstone struct {
+		model.Base
+
+		SubjectType string    `gorm:"not null;type:varchar(32);index:idx_tombstone_subject"`
+		SubjectID   string    `gorm:"not null;type:varchar(20);index:idx_tombstone_subject"`
+		Reason      string    `gorm:"not null;type:varchar(64)"`
+		PurgedAt    time.Time `gorm:"not null"`
+	}
+
+	return &gormigrate.Migration{
+		ID: "0004",
+		Migrate: func(tx *gorm.DB) error {
+			if err := tx.Exec("ALTER TABLE applications MODIFY status enum('accepted', 'pending', 'rejected', 'withdrawn', 'hired') DEFAULT 'accepted'").Error; err != nil {
+				return err
+			}
+			for _, column := range []string{"LegalHold", "LegalHoldReason", "AnonymizedAt"} {
+				if err := tx.Migrator().AddColumn(&User{}, column); err != nil {
+					return err
+				}
+			}
+			if err := tx.Migrator().AddColumn(&Application{}, "PurgedAt"); err != nil {
+				return err
+			}
+			if err := tx.Migrator().CreateIndex(&Application{}, "idx_application_purged_at"); err != nil {
+				return err
+			}
+			if err := tx.Migrator().CreateTable(&Tombstone{}); err != nil {
+				return err
+			}
+
+			return nil
+		},
+		Rollback: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropTable(&Tombstone{}); err != nil {
+				return err
+			}
+			if err := tx.Migrator().DropIndex(&Application{}, "idx_application_purged_at"); err != nil {
+				return err
+			}
+			if err := tx.Migrator().DropColumn(&Application{}, "PurgedAt"); err != nil {
+				return err
+			}
+			for _, column := range []string{"LegalHold", "LegalHoldReason", "AnonymizedAt"} {
+				if err := tx.Migrator().DropColumn(&User{}, column); err != nil {
+					return err
+				}
+			}
+			if err := tx.Exec("ALTER TABLE applications MODIFY status enum('accepted', 'pending', 'rejected') DEFAULT 'accepted'").Error; err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+}
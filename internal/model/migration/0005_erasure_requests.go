@@ -0,0 +1,32 @@
+package migration
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+
+	"fliqt/internal/model"
+)
+
+func Migration0005() *gormigrate.Migration {
+	type ErasureRequest struct {
+		model.Base
+
+		UserID          string  `gorm:"not null;type:varchar(20);index:idx_erasure_request_user_id"`
+		Status          string  `gorm:"type:enum('pending', 'rejected', 'completed');default:'pending';index:idx_erasure_request_status"`
+		ReviewerID      *string `gorm:"type:varchar(20)"`
+		ReviewedAt      *time.Time
+		RejectionReason string `gorm:"not null;default:''"`
+	}
+
+	return &gormigrate.Migration{
+		ID: "0005",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&ErasureRequest{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&ErasureRequest{})
+		},
+	}
+}
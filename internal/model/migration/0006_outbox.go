@@ -0,0 +1,46 @@
+package migration
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+
+	"fliqt/internal/model"
+)
+
+type outboxEvent0006 struct {
+	model.Base
+
+	Type          string          `gorm:"not null;type:varchar(64)"`
+	AggregateType string          `gorm:"not null;type:varchar(32)"`
+	AggregateID   string          `gorm:"not null;type:varchar(20)"`
+	Payload       json.RawMessage `gorm:"not null;type:json"`
+	PublishedAt   *time.Time      `gorm:"index:idx_outbox_published_at"`
+}
+
+func (outboxEvent0006) TableName() string {
+	return "outbox"
+}
+
+func Migration0006() *gormigrate.Migration {
+	type ApplicationStatusHistory struct {
+		model.Base
+
+		ApplicationID string `gorm:"not null;type:varchar(20);index:idx_application_status_history_application_id"`
+		FromStatus    string `gorm:"not null;type:varchar(32)"`
+		ToStatus      string `gorm:"not null;type:varchar(32)"`
+		ChangedBy     string `gorm:"not null;type:varchar(20)"`
+	}
+
+	return &gormigrate.Migration{
+		ID: "0006",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&outboxEvent0006{}, &ApplicationStatusHistory{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&outboxEvent0006{}, &ApplicationStatusHistory{})
+		},
+	}
+}
@@ -0,0 +1,51 @@
+package migration
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+
+	"fliqt/internal/model"
+)
+
+func Migration0007() *gormigrate.Migration {
+	type Webhook struct {
+		model.Base
+
+		URL                 string           `gorm:"not null;type:varchar(2048)"`
+		EventTypes          model.StringList `gorm:"not null;type:json"`
+		Secret              string           `gorm:"not null;type:varchar(128)"`
+		Enabled             bool             `gorm:"not null;default:true;index:idx_webhook_enabled"`
+		ConsecutiveFailures int              `gorm:"not null;default:0"`
+		DisabledAt          *time.Time
+		CreatedBy           string `gorm:"not null;type:varchar(20)"`
+	}
+
+	type WebhookDelivery struct {
+		model.Base
+
+		WebhookID      string `gorm:"not null;type:varchar(20);index:idx_webhook_delivery_webhook_event"`
+		EventID        string `gorm:"not null;type:varchar(20);index:idx_webhook_delivery_webhook_event"`
+		EventType      string `gorm:"not null;type:varchar(64)"`
+		Attempt        int    `gorm:"not null"`
+		Manual         bool   `gorm:"not null;default:false"`
+		RequestHeaders string `gorm:"not null;type:text"`
+		RequestBody    string `gorm:"not null;type:mediumtext"`
+		ResponseStatus int    `gorm:"not null;default:0"`
+		ResponseBody   string `gorm:"not null;type:text"`
+		Error          string `gorm:"not null;type:varchar(1024);default:''"`
+		DurationMs     int64  `gorm:"not null"`
+		Succeeded      bool   `gorm:"not null;default:false"`
+	}
+
+	return &gormigrate.Migration{
+		ID: "0007",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&Webhook{}, &WebhookDelivery{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&Webhook{}, &WebhookDelivery{})
+		},
+	}
+}
@@ -0,0 +1,71 @@
+package migration
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+
+	"fliqt/internal/model"
+)
+
+func Migration0008() *gormigrate.Migration {
+	type User struct {
+		model.Base
+
+		Email  string `gorm:"not null;type:varchar(255);default:''"`
+		Name   string `gorm:"not null;type:varchar(255);default:''"`
+		Locale string `gorm:"not null;type:varchar(16);default:'en'"`
+	}
+
+	type NotificationPreference struct {
+		model.Base
+
+		UserID           string `gorm:"not null;type:varchar(20);uniqueIndex:idx_notification_preference_user_type"`
+		NotificationType string `gorm:"not null;type:varchar(64);uniqueIndex:idx_notification_preference_user_type"`
+		Enabled          bool   `gorm:"not null"`
+	}
+
+	type NotificationLog struct {
+		model.Base
+
+		EventID          string `gorm:"not null;type:varchar(20);uniqueIndex:idx_notification_log_event_user_type"`
+		UserID           string `gorm:"not null;type:varchar(20);uniqueIndex:idx_notification_log_event_user_type"`
+		NotificationType string `gorm:"not null;type:varchar(64);uniqueIndex:idx_notification_log_event_user_type"`
+	}
+
+	return &gormigrate.Migration{
+		ID: "0008",
+		Migrate: func(tx *gorm.DB) error {
+			for _, column := range []string{"Email", "Name", "Locale"} {
+				if err := tx.Migrator().AddColumn(&User{}, column); err != nil {
+					return err
+				}
+			}
+
+			// Seed users get addresses which end up in the local SMTP server
+			for id, email := range map[string]string{
+				"cqan84gcvavjif3csp4g": "hr@fliqt.local",
+				"cqanb5gcvavjneudu13g": "interviewer@fliqt.local",
+				"cqanbg8cvavjpljmh7pg": "candidate@fliqt.local",
+			} {
+				if err := tx.Model(&User{}).Where("id = ?", id).Update("email", email).Error; err != nil {
+					return err
+				}
+			}
+
+			return tx.Migrator().CreateTable(&NotificationPreference{}, &NotificationLog{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropTable(&NotificationPreference{}, &NotificationLog{}); err != nil {
+				return err
+			}
+
+			for _, column := range []string{"Email", "Name", "Locale"} {
+				if err := tx.Migrator().DropColumn(&User{}, column); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	}
+}
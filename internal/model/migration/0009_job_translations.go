@@ -0,0 +1,78 @@
+package migration
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+
+	"fliqt/internal/model"
+)
+
+func Migration0009() *gormigrate.Migration {
+	type Job struct {
+		model.Base
+
+		Description string `gorm:"not null;type:text"`
+		Locale      string `gorm:"not null;type:varchar(16);default:'en'"`
+	}
+
+	type JobTranslation struct {
+		model.Base
+
+		JobID       string `gorm:"not null;type:varchar(20);uniqueIndex:idx_job_translation_job_locale"`
+		Locale      string `gorm:"not null;type:varchar(16);uniqueIndex:idx_job_translation_job_locale"`
+		Title       string `gorm:"not null"`
+		Description string `gorm:"not null;type:text"`
+	}
+
+	return &gormigrate.Migration{
+		ID: "0009",
+		Migrate: func(tx *gorm.DB) error {
+			for _, column := range []string{"Description", "Locale"} {
+				if err := tx.Migrator().AddColumn(&Job{}, column); err != nil {
+					return err
+				}
+			}
+
+			// The default parser splits words by spaces, which CJK doesn't have. Jobs are searched by their
+			// descriptions like translations are, applications by the title and company of their jobs only.
+			for _, statement := range []string{
+				"DROP INDEX idx_job_title_company ON jobs",
+				"CREATE FULLTEXT INDEX idx_job_title_company ON jobs(title, company) WITH PARSER ngram",
+				"CREATE FULLTEXT INDEX idx_job_title_company_description ON jobs(title, company, description) WITH PARSER ngram",
+			} {
+				if err := tx.Exec(statement).Error; err != nil {
+					return err
+				}
+			}
+
+			if err := tx.Migrator().CreateTable(&JobTranslation{}); err != nil {
+				return err
+			}
+
+			return tx.Exec("CREATE FULLTEXT INDEX idx_job_translation_title_description ON job_translations(title, description) WITH PARSER ngram").Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropTable(&JobTranslation{}); err != nil {
+				return err
+			}
+
+			for _, statement := range []string{
+				"DROP INDEX idx_job_title_company_description ON jobs",
+				"DROP INDEX idx_job_title_company ON jobs",
+				"CREATE FULLTEXT INDEX idx_job_title_company ON jobs(title, company)",
+			} {
+				if err := tx.Exec(statement).Error; err != nil {
+					return err
+				}
+			}
+
+			for _, column := range []string{"Description", "Locale"} {
+				if err := tx.Migrator().DropColumn(&Job{}, column); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	}
+}
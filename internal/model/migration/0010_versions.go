@@ -0,0 +1,50 @@
+package migration
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+
+	"fliqt/internal/model"
+)
+
+func Migration0010() *gormigrate.Migration {
+	type Job struct {
+		model.Base
+
+		Version int64 `gorm:"not null;default:1"`
+	}
+
+	type Application struct {
+		model.Base
+
+		Version int64 `gorm:"not null;default:1"`
+	}
+
+	type Webhook struct {
+		model.Base
+
+		Version int64 `gorm:"not null;default:1"`
+	}
+
+	return &gormigrate.Migration{
+		ID: "0010",
+		Migrate: func(tx *gorm.DB) error {
+			for _, table := range []interface{}{&Job{}, &Application{}, &Webhook{}} {
+				if err := tx.Migrator().AddColumn(table, "Version"); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+		Rollback: func(tx *gorm.DB) error {
+			for _, table := range []interface{}{&Job{}, &Application{}, &Webhook{}} {
+				if err := tx.Migrator().DropColumn(table, "Version"); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	}
+}
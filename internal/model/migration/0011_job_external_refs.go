@@ -0,0 +1,34 @@
+package migration
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+
+	"fliqt/internal/model"
+)
+
+func Migration0011() *gormigrate.Migration {
+	type Job struct {
+		model.Base
+
+		ExternalRef *string `gorm:"type:varchar(255);uniqueIndex:idx_job_external_ref"`
+	}
+
+	return &gormigrate.Migration{
+		ID: "0011",
+		Migrate: func(tx *gorm.DB) error {
+			if err := tx.Migrator().AddColumn(&Job{}, "ExternalRef"); err != nil {
+				return err
+			}
+
+			return tx.Migrator().CreateIndex(&Job{}, "idx_job_external_ref")
+		},
+		Rollback: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropIndex(&Job{}, "idx_job_external_ref"); err != nil {
+				return err
+			}
+
+			return tx.Migrator().DropColumn(&Job{}, "ExternalRef")
+		},
+	}
+}
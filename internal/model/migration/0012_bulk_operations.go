@@ -0,0 +1,93 @@
+package migration
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+
+	"fliqt/internal/model"
+)
+
+func Migration0012() *gormigrate.Migration {
+	type Application struct {
+		model.Base
+
+		AssigneeID *string          `gorm:"type:varchar(20);index:idx_application_assignee_id"`
+		Tags       model.StringList `gorm:"type:json"`
+	}
+
+	type ApplicationStatusHistory struct {
+		model.Base
+
+		Reason string `gorm:"not null;type:text"`
+	}
+
+	type BulkOperation struct {
+		model.Base
+
+		Action          string           `gorm:"not null;type:varchar(32)"`
+		Params          json.RawMessage  `gorm:"not null;type:json"`
+		Status          string           `gorm:"not null;type:varchar(16);default:'pending'"`
+		ApplicationIDs  model.StringList `gorm:"type:json"`
+		Total           int              `gorm:"not null;default:0"`
+		Processed       int              `gorm:"not null;default:0"`
+		Succeeded       int              `gorm:"not null;default:0"`
+		Failed          int              `gorm:"not null;default:0"`
+		ResultObjectKey string           `gorm:"not null;type:varchar(1024);default:''"`
+		Error           string           `gorm:"not null;type:varchar(1024);default:''"`
+		CreatedBy       string           `gorm:"not null;type:varchar(20);index:idx_bulk_operation_created_by"`
+		StartedAt       *time.Time
+		FinishedAt      *time.Time
+	}
+
+	type ApplicationAuditLog struct {
+		model.Base
+
+		ApplicationID   string          `gorm:"not null;type:varchar(20);index:idx_application_audit_log_application_id;uniqueIndex:idx_application_audit_log_bulk_item,priority:2"`
+		ActorID         string          `gorm:"not null;type:varchar(20)"`
+		Action          string          `gorm:"not null;type:varchar(32)"`
+		BulkOperationID *string         `gorm:"type:varchar(20);uniqueIndex:idx_application_audit_log_bulk_item,priority:1"`
+		Details         json.RawMessage `gorm:"type:json"`
+		Succeeded       bool            `gorm:"not null"`
+		ErrorCode       string          `gorm:"not null;type:varchar(64);default:''"`
+	}
+
+	return &gormigrate.Migration{
+		ID: "0012",
+		Migrate: func(tx *gorm.DB) error {
+			for _, column := range []string{"AssigneeID", "Tags"} {
+				if err := tx.Migrator().AddColumn(&Application{}, column); err != nil {
+					return err
+				}
+			}
+			if err := tx.Migrator().CreateIndex(&Application{}, "idx_application_assignee_id"); err != nil {
+				return err
+			}
+			if err := tx.Migrator().AddColumn(&ApplicationStatusHistory{}, "Reason"); err != nil {
+				return err
+			}
+
+			return tx.Migrator().CreateTable(&BulkOperation{}, &ApplicationAuditLog{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropTable(&ApplicationAuditLog{}, &BulkOperation{}); err != nil {
+				return err
+			}
+			if err := tx.Migrator().DropColumn(&ApplicationStatusHistory{}, "Reason"); err != nil {
+				return err
+			}
+			if err := tx.Migrator().DropIndex(&Application{}, "idx_application_assignee_id"); err != nil {
+				return err
+			}
+			for _, column := range []string{"Tags", "AssigneeID"} {
+				if err := tx.Migrator().DropColumn(&Application{}, column); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	}
+}
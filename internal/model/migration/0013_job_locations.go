@@ -0,0 +1,34 @@
+package migration
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+
+	"fliqt/internal/model"
+)
+
+func Migration0013() *gormigrate.Migration {
+	type Job struct {
+		model.Base
+
+		Location string `gorm:"not null;type:varchar(255);default:'';index:idx_job_location"`
+	}
+
+	return &gormigrate.Migration{
+		ID: "0013",
+		Migrate: func(tx *gorm.DB) error {
+			if err := tx.Migrator().AddColumn(&Job{}, "Location"); err != nil {
+				return err
+			}
+
+			return tx.Migrator().CreateIndex(&Job{}, "idx_job_location")
+		},
+		Rollback: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropIndex(&Job{}, "idx_job_location"); err != nil {
+				return err
+			}
+
+			return tx.Migrator().DropColumn(&Job{}, "Location")
+		},
+	}
+}
@@ -0,0 +1,49 @@
+package migration
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+
+	"fliqt/internal/model"
+)
+
+func Migration0014() *gormigrate.Migration {
+	type WebhookDelivery struct {
+		model.Base
+
+		AggregateType string `gorm:"not null;type:varchar(32);default:'';index:idx_webhook_delivery_aggregate"`
+		AggregateID   string `gorm:"not null;type:varchar(20);default:'';index:idx_webhook_delivery_aggregate"`
+	}
+
+	return &gormigrate.Migration{
+		ID: "0014",
+		Migrate: func(tx *gorm.DB) error {
+			for _, column := range []string{"AggregateType", "AggregateID"} {
+				if err := tx.Migrator().AddColumn(&WebhookDelivery{}, column); err != nil {
+					return err
+				}
+			}
+
+			if err := tx.Migrator().CreateIndex(&WebhookDelivery{}, "idx_webhook_delivery_aggregate"); err != nil {
+				return err
+			}
+
+			// Deliveries of events still in the outbox are tied to their aggregates.
+			return tx.Exec("UPDATE webhook_deliveries JOIN outbox ON outbox.id = webhook_deliveries.event_id " +
+				"SET webhook_deliveries.aggregate_type = outbox.aggregate_type, webhook_deliveries.aggregate_id = outbox.aggregate_id").Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropIndex(&WebhookDelivery{}, "idx_webhook_delivery_aggregate"); err != nil {
+				return err
+			}
+
+			for _, column := range []string{"AggregateType", "AggregateID"} {
+				if err := tx.Migrator().DropColumn(&WebhookDelivery{}, column); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	}
+}
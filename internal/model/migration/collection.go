@@ -11,7 +11,20 @@ import (
 
 func AllMigrations() []*gormigrate.Migration {
 	return []*gormigrate.Migration{
-		Migration0001(),
+		Migration0001(),
+		Migration0002(),
+		Migration0003(),
+		Migration0004(),
+		Migration0005(),
+		Migration0006(),
+		Migration0007(),
+		Migration0008(),
+		Migration0009(),
+		Migration0010(),
+		Migration0011(),
+		Migration0012(),
+		Migration0013(),
+		Migration0014(),
 		// ... other migrations
 	}
 }
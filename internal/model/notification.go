@@ -0,0 +1,32 @@
+package model
+
+// Types of notifications, recipients can opt out of each of them
+const (
+	NotificationApplicationSubmitted     = "application_submitted"
+	NotificationApplicationReceived      = "application_received"
+	NotificationApplicationStatusChanged = "application_status_changed"
+)
+
+var NotificationTypes = []string{
+	NotificationApplicationSubmitted,
+	NotificationApplicationReceived,
+	NotificationApplicationStatusChanged,
+}
+
+// NotificationPreference is whether a user receives a type of notifications, they're enabled without a preference.
+type NotificationPreference struct {
+	Base
+
+	UserID           string `gorm:"not null;type:varchar(20);uniqueIndex:idx_notification_preference_user_type"`
+	NotificationType string `gorm:"not null;type:varchar(64);uniqueIndex:idx_notification_preference_user_type"`
+	Enabled          bool   `gorm:"not null"`
+}
+
+// NotificationLog records a notification sent for an event, so retries of the event don't send it twice.
+type NotificationLog struct {
+	Base
+
+	EventID          string `gorm:"not null;type:varchar(20);uniqueIndex:idx_notification_log_event_user_type"`
+	UserID           string `gorm:"not null;type:varchar(20);uniqueIndex:idx_notification_log_event_user_type"`
+	NotificationType string `gorm:"not null;type:varchar(64);uniqueIndex:idx_notification_log_event_user_type"`
+}
@@ -0,0 +1,22 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// OutboxEvent is a domain event, it's written in the same transaction as the change it describes
+// and published by the relay afterwards.
+type OutboxEvent struct {
+	Base
+
+	Type          string          `gorm:"not null;type:varchar(64)"`
+	AggregateType string          `gorm:"not null;type:varchar(32)"`
+	AggregateID   string          `gorm:"not null;type:varchar(20)"`
+	Payload       json.RawMessage `gorm:"not null;type:json"`
+	PublishedAt   *time.Time      `gorm:"index:idx_outbox_published_at"`
+}
+
+func (OutboxEvent) TableName() string {
+	return "outbox"
+}
@@ -3,12 +3,18 @@ package model
 type PaginationParams struct {
 	PageSize  int    `form:"page_size,omitempty"`
 	NextToken string `form:"next_token,omitempty"`
+	PrevToken string `form:"prev_token,omitempty"`
+	// Sort is comma separated sort keys, descending ones are prefixed with -
+	Sort string `form:"sort,omitempty"`
+	// IncludeTotal counts every matching item, it's expensive on large tables so it's only done on request
+	IncludeTotal bool `form:"include_total,omitempty"`
 }
 
 type PaginationResponse[T any] struct {
-	Total     int64  `json:"total"`
+	Total     *int64 `json:"total,omitempty"`
 	Items     []T    `json:"items"`
 	NextToken string `json:"next_token,omitempty"`
+	PrevToken string `json:"prev_token,omitempty"`
 }
 
 func (p *PaginationParams) Normalize() *PaginationParams {
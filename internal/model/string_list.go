@@ -0,0 +1,51 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// StringList is a list of strings stored as a JSON array.
+type StringList []string
+
+func (l *StringList) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*l = StringList{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported type %T of StringList", value)
+	}
+
+	return json.Unmarshal(data, l)
+}
+
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+
+	data, err := json.Marshal(l)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(data), nil
+}
+
+// Contains reports whether the list contains s.
+func (l StringList) Contains(s string) bool {
+	for _, item := range l {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
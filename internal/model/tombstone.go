@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+const (
+	TombstoneSubjectApplication = "application"
+	TombstoneSubjectUser        = "user"
+)
+
+// Tombstone records that the data of a subject has been purged, so we can prove it without keeping the data.
+type Tombstone struct {
+	Base
+
+	SubjectType string    `gorm:"not null;type:varchar(32);index:idx_tombstone_subject"`
+	SubjectID   string    `gorm:"not null;type:varchar(20);index:idx_tombstone_subject"`
+	Reason      string    `gorm:"not null;type:varchar(64)"`
+	PurgedAt    time.Time `gorm:"not null"`
+}
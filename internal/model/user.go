@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 type UserRole string
 
 const (
@@ -12,5 +14,13 @@ type User struct {
 	Base
 
 	Role       UserRole `gorm:"type:enum('hr', 'interviewer', 'candidate');default:'candidate';index:idx_user_role"`
-	TotpSecret string   `gorm:"not null"`
+	TotpSecret string   `gorm:"not null" json:"-"`
+	Email      string   `gorm:"not null;type:varchar(255);default:''"`
+	Name       string   `gorm:"not null;type:varchar(255);default:''"`
+	// Locale of notifications, e.g. en or zh-TW
+	Locale string `gorm:"not null;type:varchar(16);default:'en'"`
+	// Data of candidates on legal hold is never purged by the retention policy
+	LegalHold       bool       `gorm:"not null;default:false"`
+	LegalHoldReason string     `gorm:"not null;default:''"`
+	AnonymizedAt    *time.Time `json:",omitempty"`
 }
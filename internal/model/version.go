@@ -0,0 +1,16 @@
+package model
+
+// VersionMatches reports whether version is one of versions, any version matches when versions is nil.
+func VersionMatches(version int64, versions []int64) bool {
+	if versions == nil {
+		return true
+	}
+
+	for _, v := range versions {
+		if v == version {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,46 @@
+package model
+
+import "time"
+
+// Webhook pushes domain events to an external system, payloads are signed with its secret.
+type Webhook struct {
+	Base
+
+	URL string `gorm:"not null;type:varchar(2048)"`
+	// EventTypes the webhook subscribes to, it subscribes to every event when it's empty
+	EventTypes StringList `gorm:"not null;type:json"`
+	Secret     string     `gorm:"not null;type:varchar(128)" json:"-"`
+	Enabled    bool       `gorm:"not null;default:true;index:idx_webhook_enabled"`
+	// The webhook is disabled once ConsecutiveFailures reaches the limit
+	ConsecutiveFailures int        `gorm:"not null;default:0"`
+	DisabledAt          *time.Time `json:",omitempty"`
+	CreatedBy           string     `gorm:"not null;type:varchar(20)"`
+	// Version is incremented on every change but of ConsecutiveFailures, it's the ETag of the webhook
+	Version int64 `gorm:"not null;default:1"`
+}
+
+// Subscribes reports whether the webhook receives events of the type.
+func (w *Webhook) Subscribes(eventType string) bool {
+	return len(w.EventTypes) == 0 || w.EventTypes.Contains(eventType)
+}
+
+// WebhookDelivery records a request sent to a webhook and the response, they're scrubbed along with the
+// aggregate of the event, e.g. when an application is purged.
+type WebhookDelivery struct {
+	Base
+
+	WebhookID      string `gorm:"not null;type:varchar(20);index:idx_webhook_delivery_webhook_event"`
+	EventID        string `gorm:"not null;type:varchar(20);index:idx_webhook_delivery_webhook_event"`
+	EventType      string `gorm:"not null;type:varchar(64)"`
+	AggregateType  string `gorm:"not null;type:varchar(32);default:'';index:idx_webhook_delivery_aggregate"`
+	AggregateID    string `gorm:"not null;type:varchar(20);default:'';index:idx_webhook_delivery_aggregate"`
+	Attempt        int    `gorm:"not null"`
+	Manual         bool   `gorm:"not null;default:false"`
+	RequestHeaders string `gorm:"not null;type:text"`
+	RequestBody    string `gorm:"not null;type:mediumtext"`
+	ResponseStatus int    `gorm:"not null;default:0"`
+	ResponseBody   string `gorm:"not null;type:text"`
+	Error          string `gorm:"not null;type:varchar(1024);default:''"`
+	DurationMs     int64  `gorm:"not null"`
+	Succeeded      bool   `gorm:"not null;default:false"`
+}
@@ -0,0 +1,169 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fliqt/config"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+var ErrUnknownProvider = errors.New("unknown notification provider")
+
+// Message is a rendered email.
+type Message struct {
+	To      string
+	Subject string
+	HTML    string
+	Text    string
+	// Headers such as List-Unsubscribe
+	Headers map[string]string
+}
+
+// Provider sends messages, e.g. through SMTP or an email API.
+type Provider interface {
+	Send(ctx context.Context, message Message) error
+}
+
+func NewProvider(cfg *config.Config, logger *zerolog.Logger) (Provider, error) {
+	switch cfg.NotificationProvider {
+	case "smtp":
+		return NewSMTPProvider(cfg), nil
+	case "log":
+		return &LogProvider{logger}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, cfg.NotificationProvider)
+	}
+}
+
+// SMTPProvider sends messages to an SMTP server, STARTTLS is used when the server supports it.
+type SMTPProvider struct {
+	addr     string
+	host     string
+	username string
+	password string
+	from     string
+}
+
+func NewSMTPProvider(cfg *config.Config) *SMTPProvider {
+	return &SMTPProvider{
+		addr:     net.JoinHostPort(cfg.SMTPHost, strconv.Itoa(cfg.SMTPPort)),
+		host:     cfg.SMTPHost,
+		username: cfg.SMTPUsername,
+		password: cfg.SMTPPassword,
+		from:     cfg.SMTPFrom,
+	}
+}
+
+func (p *SMTPProvider) Send(ctx context.Context, message Message) error {
+	from, err := mail.ParseAddress(p.from)
+	if err != nil {
+		return err
+	}
+
+	data, err := encode(p.from, message)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if p.username != "" {
+		auth = smtp.PlainAuth("", p.username, p.password, p.host)
+	}
+
+	// net/smtp doesn't take a context, the send runs in a goroutine which is abandoned when ctx is done.
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(p.addr, auth, from.Address, []string{message.To}, data)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// LogProvider only logs messages, it's meant for development.
+type LogProvider struct {
+	logger *zerolog.Logger
+}
+
+func (p *LogProvider) Send(ctx context.Context, message Message) error {
+	p.logger.Info().Str("to", message.To).Str("subject", message.Subject).Msg(message.Text)
+	return nil
+}
+
+// encode encodes the message as a multipart/alternative MIME message with text and HTML parts.
+func encode(from string, message Message) ([]byte, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=UTF-8", message.Text},
+		{"text/html; charset=UTF-8", message.HTML},
+	} {
+		w, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	messageID := make([]byte, 16)
+	if _, err := rand.Read(messageID); err != nil {
+		return nil, err
+	}
+
+	var data bytes.Buffer
+	headers := map[string]string{
+		"From":         from,
+		"To":           message.To,
+		"Subject":      mime.QEncoding.Encode("UTF-8", message.Subject),
+		"Date":         time.Now().Format(time.RFC1123Z),
+		"Message-ID":   fmt.Sprintf("<%s@fliqt>", hex.EncodeToString(messageID)),
+		"MIME-Version": "1.0",
+		"Content-Type": fmt.Sprintf("multipart/alternative; boundary=%q", writer.Boundary()),
+	}
+	for k, v := range message.Headers {
+		headers[k] = v
+	}
+	for k, v := range headers {
+		fmt.Fprintf(&data, "%s: %s\r\n", k, v)
+	}
+	data.WriteString("\r\n")
+	data.Write(body.Bytes())
+
+	return data.Bytes(), nil
+}
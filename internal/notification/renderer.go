@@ -0,0 +1,105 @@
+package notification
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"strings"
+	texttemplate "text/template"
+)
+
+const DefaultLocale = "en"
+
+var ErrTemplateNotFound = errors.New("notification template not found")
+
+//go:embed templates
+var templateFS embed.FS
+
+// Renderer renders the localized templates of notifications. Every notification has an HTML and a text template
+// in templates/<locale>/, the text template defines the subject with {{define "subject"}}.
+type Renderer struct {
+	html map[string]*htmltemplate.Template
+	text map[string]*texttemplate.Template
+}
+
+func NewRenderer() (*Renderer, error) {
+	r := &Renderer{
+		html: map[string]*htmltemplate.Template{},
+		text: map[string]*texttemplate.Template{},
+	}
+
+	err := fs.WalkDir(templateFS, "templates", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		// templates/<locale>/<name>.<ext>
+		parts := strings.Split(strings.TrimPrefix(path, "templates/"), "/")
+		if len(parts) != 2 {
+			return nil
+		}
+		locale := parts[0]
+
+		switch {
+		case strings.HasSuffix(parts[1], ".html"):
+			t, err := htmltemplate.ParseFS(templateFS, path)
+			if err != nil {
+				return err
+			}
+			r.html[locale+"/"+strings.TrimSuffix(parts[1], ".html")] = t
+		case strings.HasSuffix(parts[1], ".txt"):
+			t, err := texttemplate.ParseFS(templateFS, path)
+			if err != nil {
+				return err
+			}
+			r.text[locale+"/"+strings.TrimSuffix(parts[1], ".txt")] = t
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Render renders a notification in the locale, it falls back to the default locale when there's no translation.
+func (r *Renderer) Render(locale string, name string, data interface{}) (subject string, html string, text string, err error) {
+	key := locale + "/" + name
+	if _, ok := r.text[key]; !ok {
+		key = DefaultLocale + "/" + name
+	}
+
+	textTemplate, ok := r.text[key]
+	if !ok {
+		return "", "", "", fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+	htmlTemplate, ok := r.html[key]
+	if !ok {
+		return "", "", "", fmt.Errorf("%w: %s.html", ErrTemplateNotFound, key)
+	}
+
+	var buf bytes.Buffer
+	if err := textTemplate.ExecuteTemplate(&buf, "subject", data); err != nil {
+		return "", "", "", err
+	}
+	subject = strings.TrimSpace(buf.String())
+
+	buf.Reset()
+	if err := textTemplate.Execute(&buf, data); err != nil {
+		return "", "", "", err
+	}
+	text = strings.TrimSpace(buf.String()) + "\n"
+
+	buf.Reset()
+	if err := htmlTemplate.Execute(&buf, data); err != nil {
+		return "", "", "", err
+	}
+	html = buf.String()
+
+	return subject, html, text, nil
+}
@@ -0,0 +1,61 @@
+package notification
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRendererRender(t *testing.T) {
+	r, err := NewRenderer()
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+
+	data := map[string]string{
+		"RecipientName":  "<b>Alice</b>",
+		"ApplicationID":  "cqanbg8cvavjpljmh7ph",
+		"JobTitle":       "Software Engineer",
+		"Company":        "Google",
+		"UnsubscribeURL": "http://localhost:8080/api/notifications/unsubscribe?token=abc",
+	}
+
+	subject, html, text, err := r.Render("en", "application_submitted", data)
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+
+	if subject != "We received your application for Software Engineer at Google" {
+		t.Errorf("unexpected subject %q", subject)
+	}
+	if !strings.Contains(html, "&lt;b&gt;Alice&lt;/b&gt;") {
+		t.Errorf("expected the HTML to be escaped, got %s", html)
+	}
+	if !strings.Contains(text, "<b>Alice</b>") || !strings.Contains(text, data["UnsubscribeURL"]) {
+		t.Errorf("unexpected text %s", text)
+	}
+
+	if subject, _, _, err := r.Render("zh-TW", "application_submitted", data); err != nil || !strings.HasPrefix(subject, "我們已收到") {
+		t.Errorf("expected the zh-TW subject, got %q, %v", subject, err)
+	}
+
+	// Locales without translations fall back to the default locale.
+	if subject, _, _, err := r.Render("ja", "application_status_changed", data); err != nil || !strings.HasPrefix(subject, "Your application") {
+		t.Errorf("expected the en subject, got %q, %v", subject, err)
+	}
+
+	if _, _, _, err := r.Render("en", "unknown", data); err == nil {
+		t.Error("expected ErrTemplateNotFound, got nil")
+	}
+}
+
+func TestUnsubscribeToken(t *testing.T) {
+	token := UnsubscribeToken("secret", "cqanbg8cvavjpljmh7pg", "application_submitted")
+
+	if err := VerifyUnsubscribeToken("secret", "cqanbg8cvavjpljmh7pg", "application_submitted", token); err != nil {
+		t.Errorf("expected nil, got %v", err)
+	}
+
+	if err := VerifyUnsubscribeToken("secret", "cqanbg8cvavjpljmh7pg", "application_received", token); err != ErrInvalidUnsubscribeToken {
+		t.Errorf("expected ErrInvalidUnsubscribeToken, got %v", err)
+	}
+}
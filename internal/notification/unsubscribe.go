@@ -0,0 +1,28 @@
+package notification
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fliqt/internal/apperror"
+	"net/http"
+)
+
+var ErrInvalidUnsubscribeToken = apperror.New(http.StatusForbidden, "invalid_unsubscribe_token", "invalid unsubscribe token")
+
+// UnsubscribeToken signs the user and the notification type, so unsubscribe links work without signing in.
+func UnsubscribeToken(secret string, userID string, notificationType string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(userID))
+	mac.Write([]byte{0})
+	mac.Write([]byte(notificationType))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func VerifyUnsubscribeToken(secret string, userID string, notificationType string, token string) error {
+	if !hmac.Equal([]byte(token), []byte(UnsubscribeToken(secret, userID, notificationType))) {
+		return ErrInvalidUnsubscribeToken
+	}
+
+	return nil
+}
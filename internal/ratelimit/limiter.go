@@ -0,0 +1,135 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var ErrInvalidLimit = errors.New("invalid rate limit, expected <requests>/<period> such as 60/1m")
+
+// gcraScript implements the generic cell rate algorithm. A key only stores the theoretical arrival time
+// of the next request, so limits are smooth without keeping a log of requests. Time is read from Redis
+// so replicas with skewed clocks share the same limits.
+var gcraScript = redis.NewScript(`
+redis.replicate_commands()
+
+local burst = tonumber(ARGV[1])
+local emission_interval = tonumber(ARGV[2])
+
+local now = redis.call('TIME')
+now = tonumber(now[1]) + tonumber(now[2]) / 1000000
+
+local tat = tonumber(redis.call('GET', KEYS[1])) or now
+tat = math.max(tat, now)
+
+local new_tat = tat + emission_interval
+local allow_at = new_tat - burst * emission_interval
+if allow_at > now then
+	return {0, 0, tostring(allow_at - now), tostring(tat - now)}
+end
+
+redis.call('SET', KEYS[1], tostring(new_tat), 'EX', math.ceil(new_tat - now))
+return {1, math.floor((now - allow_at) / emission_interval), '0', tostring(new_tat - now)}
+`)
+
+// Limit allows Requests per Period, all of them can be sent at once.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// IsZero reports whether the limit is unset, which means unlimited.
+func (l Limit) IsZero() bool {
+	return l.Requests <= 0 || l.Period <= 0
+}
+
+func (l Limit) String() string {
+	if l.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf("%d/%s", l.Requests, l.Period)
+}
+
+// ParseLimit parses limits such as 60/1m, an empty string is an unset limit.
+func ParseLimit(s string) (Limit, error) {
+	if s == "" {
+		return Limit{}, nil
+	}
+
+	requests, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, ErrInvalidLimit
+	}
+
+	n, err := strconv.Atoi(requests)
+	if err != nil || n <= 0 {
+		return Limit{}, ErrInvalidLimit
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return Limit{}, ErrInvalidLimit
+	}
+
+	return Limit{Requests: n, Period: d}, nil
+}
+
+// Result of a request against a limit.
+type Result struct {
+	Allowed   bool
+	Limit     Limit
+	Remaining int
+	// RetryAfter is how long until the request would be allowed, it's 0 when it's allowed
+	RetryAfter time.Duration
+	// ResetAfter is how long until the limit is fully available again
+	ResetAfter time.Duration
+}
+
+// Limiter is a GCRA rate limiter backed by Redis.
+type Limiter struct {
+	redisClient *redis.Client
+}
+
+func NewLimiter(redisClient *redis.Client) *Limiter {
+	return &Limiter{redisClient}
+}
+
+// Allow takes a request from the limit of key.
+func (l *Limiter) Allow(ctx context.Context, key string, limit Limit) (*Result, error) {
+	emissionInterval := limit.Period.Seconds() / float64(limit.Requests)
+
+	values, err := gcraScript.Run(ctx, l.redisClient, []string{"ratelimit:" + key}, limit.Requests, emissionInterval).Slice()
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != 4 {
+		return nil, fmt.Errorf("unexpected result of rate limit script: %v", values)
+	}
+
+	allowed, _ := values[0].(int64)
+	remaining, _ := values[1].(int64)
+
+	return &Result{
+		Allowed:    allowed == 1,
+		Limit:      limit,
+		Remaining:  int(remaining),
+		RetryAfter: parseSeconds(values[2]),
+		ResetAfter: parseSeconds(values[3]),
+	}, nil
+}
+
+func parseSeconds(value interface{}) time.Duration {
+	s, _ := value.(string)
+	seconds, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(seconds) {
+		return 0
+	}
+
+	return time.Duration(seconds * float64(time.Second))
+}
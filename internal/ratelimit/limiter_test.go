@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+func TestParseLimit(t *testing.T) {
+	limit, err := ParseLimit("60/1m")
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if limit.Requests != 60 || limit.Period != time.Minute {
+		t.Errorf("expected 60 requests per minute, got %+v", limit)
+	}
+
+	limit, err = ParseLimit("")
+	if err != nil || !limit.IsZero() {
+		t.Errorf("expected an unset limit, got %+v, %v", limit, err)
+	}
+
+	for _, s := range []string{"60", "0/1m", "60/0s", "abc/1m", "60/minute"} {
+		if _, err := ParseLimit(s); err != ErrInvalidLimit {
+			t.Errorf("expected ErrInvalidLimit for %q, got %v", s, err)
+		}
+	}
+}
+
+func TestLimiterAllow(t *testing.T) {
+	mr := miniredis.RunT(t)
+	limiter := NewLimiter(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	limit := Limit{Requests: 3, Period: time.Minute}
+
+	for i := 0; i < 3; i++ {
+		result, err := limiter.Allow(context.Background(), "test", limit)
+		if err != nil {
+			t.Fatalf("Error: %s", err)
+		}
+		if !result.Allowed || result.Remaining != 2-i {
+			t.Errorf("expected request %d to be allowed with %d remaining, got %+v", i, 2-i, result)
+		}
+	}
+
+	result, err := limiter.Allow(context.Background(), "test", limit)
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if result.Allowed {
+		t.Errorf("expected the request over the limit to be rejected")
+	}
+	if result.RetryAfter <= 0 || result.RetryAfter > 20*time.Second {
+		t.Errorf("expected to retry within the emission interval, got %s", result.RetryAfter)
+	}
+}
@@ -2,10 +2,16 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
+	"fliqt/internal/apperror"
+	"fliqt/internal/event"
 	"fliqt/internal/model"
+	"net/http"
+	"slices"
 
 	"github.com/rs/zerolog"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ApplicationRepository struct {
@@ -24,13 +30,15 @@ func NewApplicationRepository(
 }
 
 type ApplicationFilterParams struct {
-	model.PaginationParams
+	model.PaginationParams `json:"-"`
 
-	Status  string `form:"status,omitempty"`
-	Keyword string `form:"keyword,omitempty"`
+	Status     string `form:"status,omitempty" json:"status,omitempty"`
+	Keyword    string `form:"keyword,omitempty" json:"keyword,omitempty"`
+	AssigneeID string `form:"assignee_id,omitempty" json:"assignee_id,omitempty"`
+	Tag        string `form:"tag,omitempty" json:"tag,omitempty"`
 
-	UserID *string `form:",omitempty"`
-	JobID  *string `form:",omitempty"`
+	UserID *string `form:",omitempty" json:"-"`
+	JobID  *string `form:",omitempty" json:"job_id,omitempty"`
 }
 
 type ApplicationResponseDTO struct {
@@ -45,23 +53,45 @@ type ApplicationResponseDTO struct {
 	UpdatedAt       string `json:"updated_at"`
 }
 
-// ListApplications returns a list of applications
+// ListApplications returns a list of applications, it can be sorted by created_at and updated_at
 func (r *ApplicationRepository) ListApplications(ctx context.Context, filterParams ApplicationFilterParams) (model.PaginationResponse[ApplicationResponseDTO], error) {
-	var applications []ApplicationResponseDTO
 	query := r.db.WithContext(ctx).Model(&model.Application{}).
 		Select(`
 			applications.id,
 			applications.job_id,
 			jobs.title as job_title,
 			jobs.company as company,
+			applications.user_id,
 			applications.status,
 			applications.resume_object_key,
 			applications.created_at,
 			applications.updated_at
 		`).
-		Joins("JOIN jobs ON jobs.id =  applications.job_id").
-		Order("id DESC")
+		Joins("JOIN jobs ON jobs.id =  applications.job_id")
 
+	query = filterApplications(query, filterParams)
+
+	sort, err := model.ParseSort(filterParams.Sort, map[string]model.SortKey{
+		"created_at": {Column: "applications.created_at", Kind: model.SortKindTime},
+		"updated_at": {Column: "applications.updated_at", Kind: model.SortKindTime},
+	}, "applications.id")
+	if err != nil {
+		return model.PaginationResponse[ApplicationResponseDTO]{}, err
+	}
+
+	return model.Paginate(query, filterParams.PaginationParams, sort, func(application ApplicationResponseDTO, key string) interface{} {
+		switch key {
+		case "created_at":
+			return application.CreatedAt
+		case "updated_at":
+			return application.UpdatedAt
+		}
+		return application.ID
+	})
+}
+
+// filterApplications narrows query down to the applications which match filterParams, jobs must be joined.
+func filterApplications(query *gorm.DB, filterParams ApplicationFilterParams) *gorm.DB {
 	if filterParams.Status != "" {
 		query = query.Where("applications.status = ?", filterParams.Status)
 	}
@@ -71,6 +101,14 @@ func (r *ApplicationRepository) ListApplications(ctx context.Context, filterPara
 		query = query.Where("MATCH(jobs.title, jobs.company) AGAINST (?)", filterParams.Keyword)
 	}
 
+	if filterParams.AssigneeID != "" {
+		query = query.Where("applications.assignee_id = ?", filterParams.AssigneeID)
+	}
+
+	if filterParams.Tag != "" {
+		query = query.Where("JSON_CONTAINS(applications.tags, JSON_QUOTE(?))", filterParams.Tag)
+	}
+
 	if filterParams.UserID != nil {
 		query = query.Where("applications.user_id = ?", *filterParams.UserID)
 	}
@@ -79,27 +117,20 @@ func (r *ApplicationRepository) ListApplications(ctx context.Context, filterPara
 		query = query.Where("applications.job_id = ?", *filterParams.JobID)
 	}
 
-	var total int64
-	var result model.PaginationResponse[ApplicationResponseDTO]
-
-	if err := query.Count(&total).Error; err != nil {
-		return model.PaginationResponse[ApplicationResponseDTO]{}, err
-	}
-
-	if filterParams.NextToken != "" {
-		query = query.Where("id < ?", filterParams.NextToken)
-	}
+	return query
+}
 
-	query = query.Limit(filterParams.PageSize)
+// ListApplicationIDs returns the IDs of the applications which match filterParams in the order of their IDs,
+// at most limit of them. Pagination of filterParams is ignored.
+func (r *ApplicationRepository) ListApplicationIDs(ctx context.Context, filterParams ApplicationFilterParams, limit int) ([]string, error) {
+	query := r.db.WithContext(ctx).Model(&model.Application{}).Joins("JOIN jobs ON jobs.id = applications.job_id")
 
-	if err := query.Find(&applications).Error; err != nil {
-		return model.PaginationResponse[ApplicationResponseDTO]{}, err
+	var IDs []string
+	if err := filterApplications(query, filterParams).Order("applications.id").Limit(limit).Pluck("applications.id", &IDs).Error; err != nil {
+		return nil, err
 	}
 
-	result.Total = total
-	result.Items = applications
-
-	return result, nil
+	return IDs, nil
 }
 
 type CreateApplicationDTO struct {
@@ -113,10 +144,199 @@ func (r *ApplicationRepository) CreateApplication(ctx context.Context, dto Creat
 		JobID:           dto.JobID,
 		UserID:          dto.UserID,
 		ResumeObjectKey: dto.ResumeObjectKey,
+		Version:         1,
 	}
-	if err := r.db.WithContext(ctx).Create(&application).Error; err != nil {
+	if err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&application).Error; err != nil {
+			return err
+		}
+
+		return event.Record(tx, event.ApplicationSubmitted, event.AggregateApplication, application.ID, application)
+	}); err != nil {
 		return model.Application{}, err
 	}
 
 	return application, nil
 }
+
+type UpdateApplicationStatusDTO struct {
+	Status string `json:"status" binding:"required,oneof=accepted pending rejected withdrawn hired"`
+	// Reason is told to the candidate along with the new status
+	Reason    string `json:"reason" binding:"max=2000"`
+	ChangedBy string `json:"-"`
+}
+
+// GetApplicationByID returns an application by its ID
+func (r *ApplicationRepository) GetApplicationByID(ctx context.Context, ID string) (*model.Application, error) {
+	var application model.Application
+	if err := r.db.WithContext(ctx).Where("id = ?", ID).First(&application).Error; err != nil {
+		return nil, err
+	}
+
+	return &application, nil
+}
+
+// UpdateApplicationStatus changes the status of an application and records the change in its history. It fails
+// with ErrPreconditionFailed unless the application is at one of versions, nil versions update any version.
+func (r *ApplicationRepository) UpdateApplicationStatus(ctx context.Context, ID string, dto UpdateApplicationStatusDTO, versions []int64) (*model.Application, error) {
+	var application model.Application
+	if err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", ID).First(&application).Error; err != nil {
+			return err
+		}
+		if !model.VersionMatches(application.Version, versions) {
+			return apperror.ErrPreconditionFailed
+		}
+
+		if err := ChangeApplicationStatus(tx, &application, dto); err != nil {
+			return err
+		}
+
+		return recordApplicationAudit(tx, model.ApplicationAuditLog{
+			ApplicationID: application.ID,
+			ActorID:       dto.ChangedBy,
+			Action:        model.BulkActionStatus,
+			Succeeded:     true,
+		}, map[string]string{"status": dto.Status})
+	}); err != nil {
+		return nil, err
+	}
+
+	return &application, nil
+}
+
+// ChangeApplicationStatus changes the status of the application locked by tx, and records the change in its
+// history and as an event. Nothing happens when the application already has the status.
+func ChangeApplicationStatus(tx *gorm.DB, application *model.Application, dto UpdateApplicationStatusDTO) error {
+	if application.Status == dto.Status {
+		return nil
+	}
+
+	history := model.ApplicationStatusHistory{
+		ApplicationID: application.ID,
+		FromStatus:    application.Status,
+		ToStatus:      dto.Status,
+		ChangedBy:     dto.ChangedBy,
+		Reason:        dto.Reason,
+	}
+
+	if err := tx.Model(application).Updates(map[string]interface{}{
+		"status":  dto.Status,
+		"version": gorm.Expr("version + 1"),
+	}).Error; err != nil {
+		return err
+	}
+	application.Status = dto.Status
+	application.Version++
+
+	if err := tx.Create(&history).Error; err != nil {
+		return err
+	}
+
+	payload := event.ApplicationStatusChangedPayload{
+		ApplicationID: application.ID,
+		JobID:         application.JobID,
+		UserID:        application.UserID,
+		FromStatus:    history.FromStatus,
+		ToStatus:      history.ToStatus,
+		ChangedBy:     history.ChangedBy,
+		Reason:        history.Reason,
+	}
+	if application.AssigneeID != nil {
+		payload.AssigneeID = *application.AssigneeID
+	}
+
+	return event.Record(tx, event.ApplicationStatusChanged, event.AggregateApplication, application.ID, payload)
+}
+
+// AssignApplication assigns the application locked by tx to an interviewer.
+func AssignApplication(tx *gorm.DB, application *model.Application, assigneeID string) error {
+	if application.AssigneeID != nil && *application.AssigneeID == assigneeID {
+		return nil
+	}
+
+	if err := tx.Model(application).Updates(map[string]interface{}{
+		"assignee_id": assigneeID,
+		"version":     gorm.Expr("version + 1"),
+	}).Error; err != nil {
+		return err
+	}
+	application.AssigneeID = &assigneeID
+	application.Version++
+
+	return nil
+}
+
+// maxApplicationTags is the number of tags an application can have.
+const maxApplicationTags = 20
+
+var ErrTooManyTags = apperror.New(http.StatusBadRequest, "too_many_tags", "an application can have at most 20 tags")
+
+// TagApplication adds and removes tags of the application locked by tx, tags are kept in the order they're added.
+func TagApplication(tx *gorm.DB, application *model.Application, add []string, remove []string) error {
+	tags := model.StringList{}
+	for _, tag := range application.Tags {
+		if !slices.Contains(remove, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	for _, tag := range add {
+		if !tags.Contains(tag) && !slices.Contains(remove, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) > maxApplicationTags {
+		return ErrTooManyTags
+	}
+	if slices.Equal(tags, application.Tags) {
+		return nil
+	}
+
+	if err := tx.Model(application).Updates(map[string]interface{}{
+		"tags":    tags,
+		"version": gorm.Expr("version + 1"),
+	}).Error; err != nil {
+		return err
+	}
+	application.Tags = tags
+	application.Version++
+
+	return nil
+}
+
+// recordApplicationAudit records an action on an application, details are the parameters of the action.
+func recordApplicationAudit(tx *gorm.DB, log model.ApplicationAuditLog, details interface{}) error {
+	if details != nil {
+		data, err := json.Marshal(details)
+		if err != nil {
+			return err
+		}
+		log.Details = data
+	}
+
+	return tx.Create(&log).Error
+}
+
+// ListApplicationAuditLogs returns the audit log of an application in chronological order
+func (r *ApplicationRepository) ListApplicationAuditLogs(ctx context.Context, applicationID string) ([]model.ApplicationAuditLog, error) {
+	logs := []model.ApplicationAuditLog{}
+	if err := r.db.WithContext(ctx).Where("application_id = ?", applicationID).Order("id ASC").Find(&logs).Error; err != nil {
+		return nil, err
+	}
+
+	return logs, nil
+}
+
+// ListApplicationStatusHistories returns the status changes of applications in chronological order
+func (r *ApplicationRepository) ListApplicationStatusHistories(ctx context.Context, applicationIDs []string) ([]model.ApplicationStatusHistory, error) {
+	var histories []model.ApplicationStatusHistory
+	if len(applicationIDs) == 0 {
+		return histories, nil
+	}
+
+	if err := r.db.WithContext(ctx).Where("application_id IN ?", applicationIDs).Order("id ASC").Find(&histories).Error; err != nil {
+		return nil, err
+	}
+
+	return histories, nil
+}
@@ -25,8 +25,8 @@ func TestApplicationRepositroyListApplications(t *testing.T) {
 				sqlmock.NewRows([]string{"count(*)"}).AddRow(1),
 			)
 
-		mock.ExpectQuery("SELECT applications.id, applications.job_id, jobs.title as job_title, jobs.company as company, applications.status, applications.resume_object_key, applications.created_at, applications.updated_at FROM `applications` JOIN jobs ON jobs.id =  applications.job_id WHERE MATCH\\(jobs\\.title, jobs\\.company\\) AGAINST \\(\\?\\) AND `applications`\\.`deleted_at` IS NULL ORDER BY id DESC LIMIT \\?").
-			WithArgs("Hello World", 10).
+		mock.ExpectQuery("SELECT applications.id, applications.job_id, jobs.title as job_title, jobs.company as company, applications.user_id, applications.status, applications.resume_object_key, applications.created_at, applications.updated_at FROM `applications` JOIN jobs ON jobs.id =  applications.job_id WHERE MATCH\\(jobs\\.title, jobs\\.company\\) AGAINST \\(\\?\\) AND `applications`\\.`deleted_at` IS NULL ORDER BY applications.id DESC LIMIT \\?").
+			WithArgs("Hello World", 11).
 			WillReturnRows(
 				sqlmock.
 					NewRows([]string{"id", "job_id", "job_title", "company", "status", "resume_object_key", "created_at", "updated_at"}).
@@ -36,7 +36,8 @@ func TestApplicationRepositroyListApplications(t *testing.T) {
 		result, err := repo.ListApplications(context.TODO(), ApplicationFilterParams{
 			Keyword: "Hello World",
 			PaginationParams: model.PaginationParams{
-				PageSize: 10,
+				PageSize:     10,
+				IncludeTotal: true,
 			},
 		})
 		if err != nil {
@@ -51,20 +52,18 @@ func TestApplicationRepositroyListApplications(t *testing.T) {
 			t.Errorf("Expected company to be 'Hello World', got %s", result.Items)
 		}
 
+		if result.Total == nil || *result.Total != 1 {
+			t.Errorf("Expected total to be 1, got %v", result.Total)
+		}
+
 		if err := mock.ExpectationsWereMet(); err != nil {
 			t.Errorf("There were unfulfilled expectations: %s", err)
 		}
 	})
 
 	t.Run("StatusFilter", func(t *testing.T) {
-		mock.ExpectQuery("SELECT count\\(\\*\\) FROM `applications` JOIN jobs ON jobs.id =  applications.job_id WHERE applications\\.status = \\? AND `applications`\\.`deleted_at` IS NULL").
-			WithArgs("applied").
-			WillReturnRows(
-				sqlmock.NewRows([]string{"count(*)"}).AddRow(1),
-			)
-
-		mock.ExpectQuery("SELECT applications.id, applications.job_id, jobs.title as job_title, jobs.company as company, applications.status, applications.resume_object_key, applications.created_at, applications.updated_at FROM `applications` JOIN jobs ON jobs.id =  applications.job_id WHERE applications.status = \\? AND `applications`\\.`deleted_at` IS NULL ORDER BY id DESC LIMIT \\?").
-			WithArgs("applied", 10).
+		mock.ExpectQuery("SELECT applications.id, applications.job_id, jobs.title as job_title, jobs.company as company, applications.user_id, applications.status, applications.resume_object_key, applications.created_at, applications.updated_at FROM `applications` JOIN jobs ON jobs.id =  applications.job_id WHERE applications.status = \\? AND `applications`\\.`deleted_at` IS NULL ORDER BY applications.id DESC LIMIT \\?").
+			WithArgs("applied", 11).
 			WillReturnRows(
 				sqlmock.
 					NewRows([]string{"id", "job_id", "job_title", "company", "status", "resume_object_key", "created_at", "updated_at"}).
@@ -89,6 +88,10 @@ func TestApplicationRepositroyListApplications(t *testing.T) {
 			t.Errorf("Expected company to be 'Hello World', got %s", result.Items)
 		}
 
+		if result.Total != nil {
+			t.Errorf("Expected total not to be counted, got %d", *result.Total)
+		}
+
 		if err := mock.ExpectationsWereMet(); err != nil {
 			t.Errorf("There were unfulfilled expectations: %s", err)
 		}
@@ -0,0 +1,247 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fliqt/internal/apperror"
+	"fliqt/internal/model"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrBulkSelection    = apperror.New(http.StatusBadRequest, "invalid_selection", "select applications by either application_ids or filter")
+	ErrBulkTagsRequired = apperror.New(http.StatusBadRequest, "bulk_tags_required", "add_tags or remove_tags is required")
+)
+
+// BulkApplicationDTO is an action on applications, which are selected by either their IDs or a filter. Exports
+// can't be requested with it, they're started by the export of a job's applications which verifies TOTP.
+type BulkApplicationDTO struct {
+	Action         string                   `json:"action" binding:"required,oneof=status reject assign tag"`
+	ApplicationIDs []string                 `json:"application_ids,omitempty" binding:"max=1000"`
+	Filter         *ApplicationFilterParams `json:"filter,omitempty"`
+
+	// Status is the new status of a status action
+	Status string `json:"status,omitempty" binding:"required_if=Action status,omitempty,oneof=accepted pending rejected withdrawn hired"`
+	// ReasonTemplate is rendered for every application into the reason told to the candidate, it's a Go template
+	// with .CandidateName, .JobTitle and .Company. It's required to reject.
+	ReasonTemplate string `json:"reason_template,omitempty" binding:"required_if=Action reject,max=2000"`
+	// AssigneeID is the interviewer of an assign action
+	AssigneeID string   `json:"assignee_id,omitempty" binding:"required_if=Action assign"`
+	AddTags    []string `json:"add_tags,omitempty" binding:"max=20,dive,required,max=64"`
+	RemoveTags []string `json:"remove_tags,omitempty" binding:"max=20,dive,required,max=64"`
+	// Format of an export, csv unless it's set
+	Format string `json:"format,omitempty" binding:"omitempty,oneof=csv xlsx zip"`
+}
+
+func (dto *BulkApplicationDTO) Validate() error {
+	if (len(dto.ApplicationIDs) == 0) == (dto.Filter == nil) {
+		return ErrBulkSelection
+	}
+
+	if dto.Action == model.BulkActionTag && len(dto.AddTags) == 0 && len(dto.RemoveTags) == 0 {
+		return ErrBulkTagsRequired
+	}
+
+	return nil
+}
+
+type BulkOperationRepository struct {
+	db     *gorm.DB
+	logger *zerolog.Logger
+}
+
+func NewBulkOperationRepository(
+	db *gorm.DB,
+	logger *zerolog.Logger,
+) *BulkOperationRepository {
+	return &BulkOperationRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// CreateBulkOperation creates a pending bulk operation
+func (r *BulkOperationRepository) CreateBulkOperation(ctx context.Context, operation *model.BulkOperation) error {
+	return r.db.WithContext(ctx).Create(operation).Error
+}
+
+// GetBulkOperationByID returns a bulk operation by its ID
+func (r *BulkOperationRepository) GetBulkOperationByID(ctx context.Context, ID string) (*model.BulkOperation, error) {
+	var operation model.BulkOperation
+	if err := r.db.WithContext(ctx).Where("id = ?", ID).First(&operation).Error; err != nil {
+		return nil, err
+	}
+
+	return &operation, nil
+}
+
+// StartBulkOperation marks a pending operation as running on the applications, they're kept so a retried
+// operation applies to the same ones.
+func (r *BulkOperationRepository) StartBulkOperation(ctx context.Context, operation *model.BulkOperation, applicationIDs []string) error {
+	now := time.Now()
+	if err := r.db.WithContext(ctx).Model(operation).Where("status = ?", model.BulkOperationPending).Updates(map[string]interface{}{
+		"status":          model.BulkOperationRunning,
+		"application_ids": model.StringList(applicationIDs),
+		"total":           len(applicationIDs),
+		"started_at":      now,
+	}).Error; err != nil {
+		return err
+	}
+
+	operation.Status = model.BulkOperationRunning
+	operation.ApplicationIDs = applicationIDs
+	operation.Total = len(applicationIDs)
+	operation.StartedAt = &now
+
+	return nil
+}
+
+// FinishBulkOperation marks an operation as completed or failed, errorCode tells why it failed.
+func (r *BulkOperationRepository) FinishBulkOperation(ctx context.Context, operation *model.BulkOperation, status string, errorCode string, resultObjectKey string) error {
+	now := time.Now()
+	if err := r.db.WithContext(ctx).Model(operation).Updates(map[string]interface{}{
+		"status":            status,
+		"error":             errorCode,
+		"result_object_key": resultObjectKey,
+		"finished_at":       now,
+	}).Error; err != nil {
+		return err
+	}
+
+	operation.Status = status
+	operation.Error = errorCode
+	operation.ResultObjectKey = resultObjectKey
+	operation.FinishedAt = &now
+
+	return nil
+}
+
+// ListExpiredExports returns at most limit exports which were completed before finishedBefore and whose files
+// haven't been deleted yet
+func (r *BulkOperationRepository) ListExpiredExports(ctx context.Context, finishedBefore time.Time, limit int) ([]model.BulkOperation, error) {
+	var operations []model.BulkOperation
+	if err := r.db.WithContext(ctx).
+		Where("action = ? AND result_object_key <> '' AND finished_at < ?", model.BulkActionExport, finishedBefore).
+		Order("finished_at ASC").
+		Limit(limit).
+		Find(&operations).Error; err != nil {
+		return nil, err
+	}
+
+	return operations, nil
+}
+
+// ClearExportResult forgets the file of an export once it's deleted
+func (r *BulkOperationRepository) ClearExportResult(ctx context.Context, operation *model.BulkOperation) error {
+	if err := r.db.WithContext(ctx).Model(operation).Update("result_object_key", "").Error; err != nil {
+		return err
+	}
+
+	operation.ResultObjectKey = ""
+
+	return nil
+}
+
+// ListProcessedApplicationIDs returns the applications an operation has processed, whether they succeeded or not
+func (r *BulkOperationRepository) ListProcessedApplicationIDs(ctx context.Context, operationID string) ([]string, error) {
+	var IDs []string
+	if err := r.db.WithContext(ctx).Model(&model.ApplicationAuditLog{}).Where("bulk_operation_id = ?", operationID).Pluck("application_id", &IDs).Error; err != nil {
+		return nil, err
+	}
+
+	return IDs, nil
+}
+
+// ListBulkOperationFailures returns the items an operation failed on, at most limit of them
+func (r *BulkOperationRepository) ListBulkOperationFailures(ctx context.Context, operationID string, limit int) ([]model.ApplicationAuditLog, error) {
+	logs := []model.ApplicationAuditLog{}
+	if err := r.db.WithContext(ctx).
+		Where("bulk_operation_id = ? AND succeeded = ?", operationID, false).
+		Order("id ASC").
+		Limit(limit).
+		Find(&logs).Error; err != nil {
+		return nil, err
+	}
+
+	return logs, nil
+}
+
+// GetBulkItem returns an application of an operation along with its job and candidate
+func (r *BulkOperationRepository) GetBulkItem(ctx context.Context, applicationID string) (*model.Application, error) {
+	var application model.Application
+	if err := r.db.WithContext(ctx).Preload("Job").Preload("User").Where("id = ?", applicationID).First(&application).Error; err != nil {
+		return nil, err
+	}
+
+	return &application, nil
+}
+
+// ProcessBulkItem applies an action of an operation to an application, which is locked along with its job and
+// candidate. The item is recorded in the audit log and the progress of the operation in the same transaction.
+// Items which fail with an apperror.Error, or whose application doesn't exist, are recorded as failed, other
+// errors are returned so the item is retried.
+func (r *BulkOperationRepository) ProcessBulkItem(ctx context.Context, operation *model.BulkOperation, applicationID string, apply func(tx *gorm.DB, application *model.Application) error) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var application model.Application
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Job").Preload("User").Where("id = ?", applicationID).First(&application).Error; err != nil {
+			return err
+		}
+
+		if err := apply(tx, &application); err != nil {
+			return err
+		}
+
+		return recordBulkItem(tx, operation, applicationID, nil)
+	})
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = apperror.ErrNotFound
+	}
+	if _, ok := apperror.As(err); !ok {
+		return err
+	}
+
+	return r.RecordBulkItem(ctx, operation, applicationID, err)
+}
+
+// RecordBulkItem records that an operation has processed an application, failed with err unless it's nil.
+func (r *BulkOperationRepository) RecordBulkItem(ctx context.Context, operation *model.BulkOperation, applicationID string, err error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return recordBulkItem(tx, operation, applicationID, err)
+	})
+}
+
+func recordBulkItem(tx *gorm.DB, operation *model.BulkOperation, applicationID string, err error) error {
+	log := model.ApplicationAuditLog{
+		ApplicationID:   applicationID,
+		ActorID:         operation.CreatedBy,
+		Action:          operation.Action,
+		BulkOperationID: &operation.ID,
+		Details:         operation.Params,
+		Succeeded:       err == nil,
+	}
+	counter := "succeeded"
+	if err != nil {
+		counter = "failed"
+		if appErr, ok := apperror.As(err); ok {
+			log.ErrorCode = appErr.Code
+		}
+	}
+
+	if err := tx.Create(&log).Error; err != nil {
+		return err
+	}
+
+	return tx.Model(&model.BulkOperation{}).Where("id = ?", operation.ID).Updates(map[string]interface{}{
+		"processed": gorm.Expr("processed + 1"),
+		counter:     gorm.Expr(counter + " + 1"),
+	}).Error
+}
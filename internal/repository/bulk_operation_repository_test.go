@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+
+	"fliqt/internal/model"
+	"fliqt/internal/util"
+)
+
+func TestBulkApplicationDTOValidate(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		dto  BulkApplicationDTO
+		err  error
+	}{
+		{"ids", BulkApplicationDTO{Action: model.BulkActionExport, ApplicationIDs: []string{"1"}}, nil},
+		{"filter", BulkApplicationDTO{Action: model.BulkActionExport, Filter: &ApplicationFilterParams{}}, nil},
+		{"neither", BulkApplicationDTO{Action: model.BulkActionExport}, ErrBulkSelection},
+		{"both", BulkApplicationDTO{Action: model.BulkActionExport, ApplicationIDs: []string{"1"}, Filter: &ApplicationFilterParams{}}, ErrBulkSelection},
+		{"no tags", BulkApplicationDTO{Action: model.BulkActionTag, ApplicationIDs: []string{"1"}}, ErrBulkTagsRequired},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.dto.Validate(); !errors.Is(err, tc.err) {
+				t.Errorf("Expected error %v, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestBulkOperationRepositoryProcessBulkItem(t *testing.T) {
+	db, mock, cleanup := util.SetupMockDB(t)
+	defer cleanup()
+
+	logger := zerolog.New(zerolog.ConsoleWriter{Out: nil})
+
+	repo := NewBulkOperationRepository(db, &logger)
+	operation := &model.BulkOperation{
+		Base:      model.Base{ID: "cqanbg8cvavjpljmh7pg"},
+		Action:    model.BulkActionTag,
+		Params:    []byte(`{"action":"tag","add_tags":["urgent"]}`),
+		CreatedBy: "hr",
+	}
+
+	t.Run("NotFound", func(t *testing.T) {
+		// The application is gone, so the item is recorded as failed rather than retried.
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT \\* FROM `applications` WHERE id = \\? AND `applications`\\.`deleted_at` IS NULL ORDER BY `applications`\\.`id` LIMIT \\? FOR UPDATE").
+			WithArgs("missing", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectRollback()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO `application_audit_logs`").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE `bulk_operations` SET `failed`=failed \\+ 1,`processed`=processed \\+ 1,`updated_at`=\\? WHERE id = \\? AND `bulk_operations`\\.`deleted_at` IS NULL").
+			WithArgs(sqlmock.AnyArg(), operation.ID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err := repo.ProcessBulkItem(context.TODO(), operation, "missing", func(tx *gorm.DB, application *model.Application) error {
+			t.Fatal("Expected the action not to be applied")
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Error: %s", err)
+		}
+
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("There were unfulfilled expectations: %s", err)
+		}
+	})
+
+	t.Run("TransientError", func(t *testing.T) {
+		// Errors which aren't the item's fault are returned, so the task is retried.
+		transient := errors.New("connection reset")
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT \\* FROM `applications`").
+			WithArgs("cqanbg8cvavjpljmh7ph", 1).
+			WillReturnError(transient)
+		mock.ExpectRollback()
+
+		err := repo.ProcessBulkItem(context.TODO(), operation, "cqanbg8cvavjpljmh7ph", func(tx *gorm.DB, application *model.Application) error {
+			return nil
+		})
+		if !errors.Is(err, transient) {
+			t.Errorf("Expected %v, got %v", transient, err)
+		}
+
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("There were unfulfilled expectations: %s", err)
+		}
+	})
+}
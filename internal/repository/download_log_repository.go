@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+	"fliqt/internal/model"
+
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+)
+
+type DownloadLogRepository struct {
+	db     *gorm.DB
+	logger *zerolog.Logger
+}
+
+func NewDownloadLogRepository(
+	db *gorm.DB,
+	logger *zerolog.Logger,
+) *DownloadLogRepository {
+	return &DownloadLogRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// CreateDownloadLog records a download, the ID of the log is kept when it's set
+func (r *DownloadLogRepository) CreateDownloadLog(ctx context.Context, log *model.DownloadLog) error {
+	return r.db.WithContext(ctx).Create(log).Error
+}
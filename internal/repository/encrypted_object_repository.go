@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"fliqt/internal/model"
+
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+)
+
+type EncryptedObjectRepository struct {
+	db     *gorm.DB
+	logger *zerolog.Logger
+}
+
+func NewEncryptedObjectRepository(
+	db *gorm.DB,
+	logger *zerolog.Logger,
+) *EncryptedObjectRepository {
+	return &EncryptedObjectRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// CreateEncryptedObject stores the wrapped data key of an object
+func (r *EncryptedObjectRepository) CreateEncryptedObject(ctx context.Context, object *model.EncryptedObject) error {
+	return r.db.WithContext(ctx).Create(object).Error
+}
+
+// GetEncryptedObjectByObjectKey returns the wrapped data key of an object
+func (r *EncryptedObjectRepository) GetEncryptedObjectByObjectKey(ctx context.Context, objectKey string) (*model.EncryptedObject, error) {
+	var object model.EncryptedObject
+	if err := r.db.WithContext(ctx).Where("object_key = ?", objectKey).First(&object).Error; err != nil {
+		return nil, err
+	}
+
+	return &object, nil
+}
+
+// DeleteEncryptedObject deletes the wrapped data key of an object for good, so the object can't be decrypted anymore
+func (r *EncryptedObjectRepository) DeleteEncryptedObject(ctx context.Context, objectKey string) error {
+	return r.db.WithContext(ctx).Unscoped().Where("object_key = ?", objectKey).Delete(&model.EncryptedObject{}).Error
+}
+
+// ListStaleEncryptedObjects returns objects of a tenant whose data keys aren't wrapped by the given key
+func (r *EncryptedObjectRepository) ListStaleEncryptedObjects(ctx context.Context, tenantID string, keyID string, limit int) ([]model.EncryptedObject, error) {
+	var objects []model.EncryptedObject
+	if err := r.db.WithContext(ctx).
+		Where("tenant_id = ? AND key_id <> ?", tenantID, keyID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&objects).Error; err != nil {
+		return nil, err
+	}
+
+	return objects, nil
+}
+
+// ListTenantIDs returns every tenant which owns encrypted objects
+func (r *EncryptedObjectRepository) ListTenantIDs(ctx context.Context) ([]string, error) {
+	var tenantIDs []string
+	if err := r.db.WithContext(ctx).Model(&model.EncryptedObject{}).Distinct().Pluck("tenant_id", &tenantIDs).Error; err != nil {
+		return nil, err
+	}
+
+	return tenantIDs, nil
+}
+
+// UpdateWrappedKey replaces the wrapped data key, the object itself is untouched
+func (r *EncryptedObjectRepository) UpdateWrappedKey(ctx context.Context, ID string, keyID string, wrappedKey []byte) error {
+	return r.db.WithContext(ctx).Model(&model.EncryptedObject{}).Where("id = ?", ID).Updates(map[string]interface{}{
+		"key_id":      keyID,
+		"wrapped_key": wrappedKey,
+	}).Error
+}
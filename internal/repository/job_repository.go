@@ -3,29 +3,46 @@ package repository
 import (
 	"context"
 	"errors"
+	"fliqt/internal/apperror"
+	"fliqt/internal/event"
+	"fliqt/internal/i18n"
 	"fliqt/internal/model"
+	"fliqt/internal/search"
+	"net/http"
+	"sort"
+	"strings"
 
 	"github.com/rs/zerolog"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type JobRepository struct {
-	db     *gorm.DB
-	logger *zerolog.Logger
+	db          *gorm.DB
+	logger      *zerolog.Logger
+	searchIndex search.JobSearchIndex
 }
 
 func NewJobRepository(
 	db *gorm.DB,
 	logger *zerolog.Logger,
+	searchIndex search.JobSearchIndex,
 ) *JobRepository {
 	return &JobRepository{
-		db:     db,
-		logger: logger,
+		db:          db,
+		logger:      logger,
+		searchIndex: searchIndex,
 	}
 }
 
+// jobExportBatchSize is the number of jobs ExportJobs fetches at once.
+const jobExportBatchSize = 500
+
 var (
-	ErrJobSalaryRange = errors.New("salary_min must be less than or equal to salary_max")
+	ErrJobSalaryRange      = apperror.New(http.StatusBadRequest, "invalid_salary_range", "salary_min must be less than or equal to salary_max")
+	ErrUnsupportedLocale   = apperror.New(http.StatusBadRequest, "unsupported_locale", "the locale is not supported")
+	ErrJobLocaleTranslated = apperror.New(http.StatusConflict, "job_locale_translation", "the job is written in the locale, update the job instead")
+	ErrJobClosed           = apperror.New(http.StatusConflict, "job_closed", "the job of the external reference is closed")
 )
 
 type JobFilterParams struct {
@@ -35,6 +52,13 @@ type JobFilterParams struct {
 	SalaryMin int    `form:"salary_min,omitempty"`
 	SalaryMax int    `form:"salary_max,omitempty"`
 	JobType   string `form:"job_type,omitempty"`
+	Company   string `form:"company,omitempty"`
+	Location  string `form:"location,omitempty"`
+	// Facets counts the matching jobs by the values of each filter along with the page
+	Facets bool `form:"facets,omitempty"`
+
+	// Locales is the fallback chain of languages jobs are searched and shown in, it's negotiated with Accept-Language
+	Locales []string `form:"-"`
 }
 
 type JobResponseDTO struct {
@@ -44,17 +68,131 @@ type JobResponseDTO struct {
 	JobType   string `json:"job_type"`
 	SalaryMin int    `json:"salary_min"`
 	SalaryMax int    `json:"salary_max"`
+	Location  string `json:"location"`
 	CreatedAt string `json:"created_at"`
 	UpdatedAt string `json:"updated_at"`
+	// Locale is the language of Title, it's the first of the requested locales the job is available in
+	Locale string `json:"locale"`
+	// Relevance is the full-text score of the keyword
+	Relevance float64 `json:"relevance,omitempty"`
+	// Highlights are snippets of the fields which matched the keyword, matches are wrapped in <mark>
+	Highlights map[string][]string `json:"highlights,omitempty"`
 }
 
-// ListJobs returns a list of jobs
+// ListJobs returns a list of jobs in the requested locales, it can be sorted by created_at, salary_min, salary_max
+// and relevance when searching by keyword. Keywords are searched by the search index in the job's own language
+// and in its translations of the requested locales.
 func (r *JobRepository) ListJobs(ctx context.Context, filterParams JobFilterParams) (model.PaginationResponse[JobResponseDTO], error) {
-	var jobs []JobResponseDTO
-	query := r.db.WithContext(ctx).Model(&model.Job{}).Order("id DESC")
+	locales := i18n.NewLocalizer(filterParams.Locales...).Locales()
+
+	// relevance only exists when searching by keyword, so columns are selected explicitly
+	columns := "jobs.id, jobs.title, jobs.company, jobs.job_type, jobs.salary_min, jobs.salary_max, jobs.location, jobs.locale, jobs.created_at, jobs.updated_at"
+	query := r.db.WithContext(ctx).Model(&model.Job{}).Select(columns)
+
+	sortKeys := map[string]model.SortKey{
+		"created_at": {Column: "jobs.created_at", Kind: model.SortKindTime},
+		"salary_min": {Column: "jobs.salary_min", Kind: model.SortKindInt},
+		"salary_max": {Column: "jobs.salary_max", Kind: model.SortKindInt},
+	}
+
+	// Only the best matches are needed when they're ranked by relevance, other orders need every match
+	request := jobSearchRequest(filterParams)
+	request.All = !strings.HasPrefix(filterParams.Sort, "-relevance")
+	request.Highlight = true
+	keywordSearch, err := r.searchJobs(ctx, request)
+	if err != nil {
+		return model.PaginationResponse[JobResponseDTO]{}, err
+	}
+	if keywordSearch != nil {
+		query = query.Select(columns+", "+keywordSearch.Relevance+" AS relevance", keywordSearch.RelevanceArgs...)
+		sortKeys["relevance"] = model.SortKey{Column: keywordSearch.Relevance, Args: keywordSearch.RelevanceArgs, Kind: model.SortKindFloat}
+	}
+	query = filterJobs(query, filterParams, keywordSearch)
+
+	sort, err := model.ParseSort(filterParams.Sort, sortKeys, "jobs.id")
+	if err != nil {
+		return model.PaginationResponse[JobResponseDTO]{}, err
+	}
+
+	result, err := model.Paginate(query, filterParams.PaginationParams, sort, func(job JobResponseDTO, key string) interface{} {
+		switch key {
+		case "created_at":
+			return job.CreatedAt
+		case "salary_min":
+			return job.SalaryMin
+		case "salary_max":
+			return job.SalaryMax
+		case "relevance":
+			return job.Relevance
+		}
+		return job.ID
+	})
+	if err != nil || len(result.Items) == 0 {
+		return result, err
+	}
+	if keywordSearch != nil {
+		for i, job := range result.Items {
+			if score, ok := keywordSearch.Scores[job.ID]; ok {
+				result.Items[i].Relevance = score
+			}
+			result.Items[i].Highlights = keywordSearch.Highlights[job.ID]
+		}
+	}
 
-	if filterParams.Keyword != "" {
-		query = query.Where("MATCH(title, company) AGAINST (?)", filterParams.Keyword)
+	// Cursors are made of the untranslated jobs, none of the sort keys are translated anyway. Jobs written
+	// in the preferred locale don't need translations.
+	var jobIDs []string
+	for _, job := range result.Items {
+		if job.Locale != locales[0] {
+			jobIDs = append(jobIDs, job.ID)
+		}
+	}
+	if len(jobIDs) == 0 {
+		return result, nil
+	}
+	translations, err := r.findTranslations(ctx, jobIDs, locales)
+	if err != nil {
+		return result, err
+	}
+	for i, job := range result.Items {
+		if translation := pickTranslation(job.Locale, translations[job.ID], locales); translation != nil {
+			result.Items[i].Title = translation.Title
+			result.Items[i].Locale = translation.Locale
+		}
+	}
+
+	return result, nil
+}
+
+// searchJobs searches the keyword of a request, there's no search without a keyword.
+func (r *JobRepository) searchJobs(ctx context.Context, request search.JobSearchRequest) (*search.JobSearch, error) {
+	if request.Keyword == "" {
+		return nil, nil
+	}
+
+	return r.searchIndex.Search(ctx, request)
+}
+
+// jobSearchRequest searches the keyword of filterParams in its locales along with its filters.
+func jobSearchRequest(filterParams JobFilterParams) search.JobSearchRequest {
+	return search.JobSearchRequest{
+		Keyword: filterParams.Keyword,
+		Locales: i18n.NewLocalizer(filterParams.Locales...).Locales(),
+		Filters: search.JobSearchFilters{
+			JobType:   filterParams.JobType,
+			Company:   filterParams.Company,
+			Location:  filterParams.Location,
+			SalaryMin: filterParams.SalaryMin,
+			SalaryMax: filterParams.SalaryMax,
+		},
+	}
+}
+
+// filterJobs narrows query down to the jobs which match filterParams, keywordSearch finds the ones which match
+// its keyword.
+func filterJobs(query *gorm.DB, filterParams JobFilterParams, keywordSearch *search.JobSearch) *gorm.DB {
+	if keywordSearch != nil {
+		query = query.Where(keywordSearch.Condition, keywordSearch.Args...)
 	}
 	if filterParams.SalaryMin != 0 {
 		query = query.Where("salary_min >= ?", filterParams.SalaryMin)
@@ -65,34 +203,233 @@ func (r *JobRepository) ListJobs(ctx context.Context, filterParams JobFilterPara
 	if filterParams.JobType != "" {
 		query = query.Where("job_type = ?", filterParams.JobType)
 	}
+	if filterParams.Company != "" {
+		query = query.Where("company = ?", filterParams.Company)
+	}
+	if filterParams.Location != "" {
+		query = query.Where("location = ?", filterParams.Location)
+	}
 
-	var total int64
-	var result model.PaginationResponse[JobResponseDTO]
+	return query
+}
 
-	if err := query.Count(&total).Error; err != nil {
-		return result, err
+// maxFacetValues is the number of values JobFacets counts for companies and locations, the ones with most jobs.
+const maxFacetValues = 20
+
+// JobFacets are the numbers of jobs by the values of each filter, so filter UIs can tell what they'd find.
+type JobFacets struct {
+	JobTypes      []FacetCount        `json:"job_types"`
+	Companies     []FacetCount        `json:"companies"`
+	Locations     []FacetCount        `json:"locations"`
+	SalaryBuckets []SalaryBucketCount `json:"salary_buckets"`
+}
+
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// SalaryBucketCount is the number of jobs whose salary_min is at least From and less than To, the first bucket
+// has no From and the last has no To.
+type SalaryBucketCount struct {
+	From  *int  `json:"from,omitempty"`
+	To    *int  `json:"to,omitempty"`
+	Count int64 `json:"count"`
+}
+
+// JobFacets counts the jobs which match filterParams by job type, company, location and salary. Each facet is
+// counted without its own filter, so choosing another value of it finds as many jobs as it says. Salaries are
+// counted in buckets between salaryBounds. Sorting and pagination of filterParams are ignored.
+func (r *JobRepository) JobFacets(ctx context.Context, filterParams JobFilterParams, salaryBounds []int) (*JobFacets, error) {
+	// The keyword is never left out, so it's searched once for every match. Filters are left to SQL, since each
+	// facet leaves its own out.
+	request := jobSearchRequest(JobFilterParams{Keyword: filterParams.Keyword, Locales: filterParams.Locales})
+	request.All = true
+	keywordSearch, err := r.searchJobs(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	facets := &JobFacets{}
+
+	params := filterParams
+	params.JobType = ""
+	if err := r.countJobFacet(ctx, params, keywordSearch, "job_type", 0, &facets.JobTypes); err != nil {
+		return nil, err
 	}
 
-	if filterParams.NextToken != "" {
-		query = query.Where("id < ?", filterParams.NextToken)
+	params = filterParams
+	params.Company = ""
+	if err := r.countJobFacet(ctx, params, keywordSearch, "company", maxFacetValues, &facets.Companies); err != nil {
+		return nil, err
 	}
 
-	query = query.Limit(filterParams.PageSize)
+	params = filterParams
+	params.Location = ""
+	if err := r.countJobFacet(ctx, params, keywordSearch, "location", maxFacetValues, &facets.Locations); err != nil {
+		return nil, err
+	}
 
-	if err := query.Find(&jobs).Error; err != nil {
-		return result, err
+	params = filterParams
+	params.SalaryMin, params.SalaryMax = 0, 0
+	buckets, err := r.countSalaryBuckets(ctx, params, keywordSearch, salaryBounds)
+	if err != nil {
+		return nil, err
+	}
+	facets.SalaryBuckets = buckets
+
+	return facets, nil
+}
+
+// countJobFacet counts the jobs which match filterParams by the values of column, the most frequent first. Jobs
+// without a value aren't counted, limit is the number of values unless it's 0.
+func (r *JobRepository) countJobFacet(ctx context.Context, filterParams JobFilterParams, keywordSearch *search.JobSearch, column string, limit int, counts *[]FacetCount) error {
+	query := filterJobs(r.db.WithContext(ctx).Model(&model.Job{}), filterParams, keywordSearch).
+		Select("jobs."+column+" AS value, COUNT(*) AS count").
+		Where("jobs."+column+" <> ?", "").
+		Group("jobs." + column).
+		Order("count DESC, value ASC")
+	if limit > 0 {
+		query = query.Limit(limit)
 	}
 
-	result.Total = total
-	result.Items = jobs
+	*counts = []FacetCount{}
+	return query.Scan(counts).Error
+}
+
+// countSalaryBuckets counts the jobs which match filterParams by the bucket their salary_min is in, buckets
+// without jobs are counted as well.
+func (r *JobRepository) countSalaryBuckets(ctx context.Context, filterParams JobFilterParams, keywordSearch *search.JobSearch, bounds []int) ([]SalaryBucketCount, error) {
+	if len(bounds) == 0 {
+		return []SalaryBucketCount{}, nil
+	}
+	// INTERVAL needs ascending bounds, it returns the number of bounds which are less than or equal to salary_min
+	bounds = append([]int(nil), bounds...)
+	sort.Ints(bounds)
+
+	args := make([]interface{}, len(bounds))
+	for i, bound := range bounds {
+		args[i] = bound
+	}
+	bucket := "INTERVAL(jobs.salary_min" + strings.Repeat(", ?", len(bounds)) + ")"
+
+	var rows []struct {
+		Bucket int
+		Count  int64
+	}
+	if err := filterJobs(r.db.WithContext(ctx).Model(&model.Job{}), filterParams, keywordSearch).
+		Select(bucket+" AS bucket, COUNT(*) AS count", args...).
+		Group("bucket").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	buckets := make([]SalaryBucketCount, len(bounds)+1)
+	for i := range bounds {
+		buckets[i].To = &bounds[i]
+		buckets[i+1].From = &bounds[i]
+	}
+	for _, row := range rows {
+		if row.Bucket >= 0 && row.Bucket < len(buckets) {
+			buckets[row.Bucket].Count = row.Count
+		}
+	}
+
+	return buckets, nil
+}
+
+// ExportJobs calls fn with every job which matches filterParams in the order of their IDs. Jobs are fetched in
+// batches, so they don't have to fit in memory. Sorting and pagination of filterParams are ignored.
+func (r *JobRepository) ExportJobs(ctx context.Context, filterParams JobFilterParams, fn func(job model.Job) error) error {
+	request := jobSearchRequest(filterParams)
+	request.All = true
+	keywordSearch, err := r.searchJobs(ctx, request)
+	if err != nil {
+		return err
+	}
+	query := filterJobs(r.db.WithContext(ctx).Model(&model.Job{}), filterParams, keywordSearch)
+
+	var batch []model.Job
+	return query.FindInBatches(&batch, jobExportBatchSize, func(tx *gorm.DB, _ int) error {
+		for _, job := range batch {
+			if err := fn(job); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+}
+
+// GetJobDocument returns a job along with all of its translations, as they're indexed for search.
+func (r *JobRepository) GetJobDocument(ctx context.Context, ID string) (*search.JobDocument, error) {
+	var job model.Job
+	if err := r.db.WithContext(ctx).Where("id = ?", ID).First(&job).Error; err != nil {
+		return nil, err
+	}
+
+	document := &search.JobDocument{Job: job}
+	if err := r.db.WithContext(ctx).Where("job_id = ?", ID).Find(&document.Translations).Error; err != nil {
+		return nil, err
+	}
+
+	return document, nil
+}
+
+// ListJobDocuments calls fn with batches of every job along with all of its translations, in the order of their
+// IDs.
+func (r *JobRepository) ListJobDocuments(ctx context.Context, fn func(documents []search.JobDocument) error) error {
+	var batch []model.Job
+	return r.db.WithContext(ctx).Model(&model.Job{}).FindInBatches(&batch, jobExportBatchSize, func(tx *gorm.DB, _ int) error {
+		jobIDs := make([]string, len(batch))
+		for i, job := range batch {
+			jobIDs[i] = job.ID
+		}
+		translations, err := r.findTranslations(ctx, jobIDs, i18n.SupportedLocales)
+		if err != nil {
+			return err
+		}
+
+		documents := make([]search.JobDocument, len(batch))
+		for i, job := range batch {
+			documents[i] = search.JobDocument{Job: job, Translations: translations[job.ID]}
+		}
+		return fn(documents)
+	}).Error
+}
+
+// findTranslations returns translations of jobs in the locales by job ID.
+func (r *JobRepository) findTranslations(ctx context.Context, jobIDs []string, locales []string) (map[string][]model.JobTranslation, error) {
+	var translations []model.JobTranslation
+	if err := r.db.WithContext(ctx).
+		Where("job_id IN ? AND locale IN ?", jobIDs, locales).
+		Find(&translations).Error; err != nil {
+		return nil, err
+	}
 
-	if len(jobs) > 0 && len(jobs) == filterParams.PageSize {
-		result.NextToken = jobs[len(jobs)-1].ID
+	result := map[string][]model.JobTranslation{}
+	for _, translation := range translations {
+		result[translation.JobID] = append(result[translation.JobID], translation)
 	}
 
 	return result, nil
 }
 
+// pickTranslation returns the translation of the first locale of the fallback chain the job is available in, it's
+// nil when that's the job's own language or there's no translation at all.
+func pickTranslation(jobLocale string, translations []model.JobTranslation, locales []string) *model.JobTranslation {
+	for _, locale := range locales {
+		if locale == jobLocale {
+			return nil
+		}
+		for i := range translations {
+			if translations[i].Locale == locale {
+				return &translations[i]
+			}
+		}
+	}
+
+	return nil
+}
+
 // GetJobByID returns a job by its ID
 func (r *JobRepository) GetJobByID(ctx context.Context, ID string) (*model.Job, error) {
 	var job model.Job
@@ -103,16 +440,138 @@ func (r *JobRepository) GetJobByID(ctx context.Context, ID string) (*model.Job,
 	return &job, nil
 }
 
+// GetLocalizedJob returns a job by its ID with its title and description in the first of the locales it's
+// available in, Locale tells which one it is.
+func (r *JobRepository) GetLocalizedJob(ctx context.Context, ID string, locales []string) (*model.Job, error) {
+	job, err := r.GetJobByID(ctx, ID)
+	if err != nil {
+		return nil, err
+	}
+
+	locales = i18n.NewLocalizer(locales...).Locales()
+	translations, err := r.findTranslations(ctx, []string{job.ID}, locales)
+	if err != nil {
+		return nil, err
+	}
+
+	if translation := pickTranslation(job.Locale, translations[job.ID], locales); translation != nil {
+		job.Title = translation.Title
+		job.Description = translation.Description
+		job.Locale = translation.Locale
+	}
+
+	return job, nil
+}
+
+// ListJobTranslations returns every translation of a job
+func (r *JobRepository) ListJobTranslations(ctx context.Context, jobID string) ([]model.JobTranslation, error) {
+	if _, err := r.GetJobByID(ctx, jobID); err != nil {
+		return nil, err
+	}
+
+	translations := []model.JobTranslation{}
+	if err := r.db.WithContext(ctx).Where("job_id = ?", jobID).Order("locale").Find(&translations).Error; err != nil {
+		return nil, err
+	}
+
+	return translations, nil
+}
+
+type PutJobTranslationDTO struct {
+	Title       string `json:"title" binding:"required,max=255"`
+	Description string `json:"description"`
+}
+
+// PutJobTranslation creates or replaces the translation of a job in the locale, and returns it with the new
+// version of the job. Like UpdateJob, it fails with ErrPreconditionFailed unless the job is at one of versions.
+func (r *JobRepository) PutJobTranslation(ctx context.Context, jobID string, locale string, dto PutJobTranslationDTO, versions []int64) (*model.JobTranslation, int64, error) {
+	if !i18n.IsSupported(locale) {
+		return nil, 0, ErrUnsupportedLocale
+	}
+
+	translation := model.JobTranslation{
+		JobID:       jobID,
+		Locale:      locale,
+		Title:       dto.Title,
+		Description: dto.Description,
+	}
+
+	var job model.Job
+	if err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", jobID).First(&job).Error; err != nil {
+			return err
+		}
+		if !model.VersionMatches(job.Version, versions) {
+			return apperror.ErrPreconditionFailed
+		}
+		if job.Locale == locale {
+			return ErrJobLocaleTranslated
+		}
+
+		if err := tx.Clauses(clause.OnConflict{
+			DoUpdates: clause.AssignmentColumns([]string{"title", "description", "updated_at"}),
+		}).Create(&translation).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("job_id = ? AND locale = ?", jobID, locale).First(&translation).Error; err != nil {
+			return err
+		}
+
+		// Translations are part of the job's representation
+		if err := tx.Model(&job).UpdateColumn("version", gorm.Expr("version + 1")).Error; err != nil {
+			return err
+		}
+		job.Version++
+
+		// Consumers keep their copies of jobs up to date with job.updated
+		return event.Record(tx, event.JobUpdated, event.AggregateJob, job.ID, job)
+	}); err != nil {
+		return nil, 0, err
+	}
+
+	return &translation, job.Version, nil
+}
+
+// DeleteJobTranslation deletes the translation of a job in the locale
+func (r *JobRepository) DeleteJobTranslation(ctx context.Context, jobID string, locale string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var job model.Job
+		if err := tx.Where("id = ?", jobID).First(&job).Error; err != nil {
+			return err
+		}
+
+		// Translations are deleted for good, so the locale can be translated again
+		result := tx.Unscoped().Where("job_id = ? AND locale = ?", jobID, locale).Delete(&model.JobTranslation{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		if err := tx.Model(&job).UpdateColumn("version", gorm.Expr("version + 1")).Error; err != nil {
+			return err
+		}
+		job.Version++
+
+		return event.Record(tx, event.JobUpdated, event.AggregateJob, job.ID, job)
+	})
+}
+
 type JobValidator interface {
 	Validate() error
 }
 
 type CreateJobDTO struct {
-	Title     string `json:"title" binding:"required"`
-	Company   string `json:"company" binding:"required"`
-	JobType   string `json:"job_type" binding:"required,oneof=full-time part-time contract"`
-	SalaryMin int    `json:"salary_min" binding:"required"`
-	SalaryMax int    `json:"salary_max" binding:"required"`
+	Title       string `json:"title" binding:"required"`
+	Company     string `json:"company" binding:"required"`
+	Description string `json:"description"`
+	Locale      string `json:"locale" binding:"omitempty,oneof=en zh-TW ja"`
+	JobType     string `json:"job_type" binding:"required,oneof=full-time part-time contract"`
+	SalaryMin   int    `json:"salary_min" binding:"required"`
+	SalaryMax   int    `json:"salary_max" binding:"required"`
+	Location    string `json:"location" binding:"max=255"`
 }
 
 func (dto CreateJobDTO) Validate() error {
@@ -126,14 +585,27 @@ func (dto CreateJobDTO) Validate() error {
 // CreateJob creates a new job
 func (r *JobRepository) CreateJob(ctx context.Context, dto CreateJobDTO) (*model.Job, error) {
 	job := model.Job{
-		Title:     dto.Title,
-		Company:   dto.Company,
-		JobType:   model.JobType(dto.JobType),
-		SalaryMin: dto.SalaryMin,
-		SalaryMax: dto.SalaryMax,
+		Title:       dto.Title,
+		Company:     dto.Company,
+		Description: dto.Description,
+		Locale:      dto.Locale,
+		JobType:     model.JobType(dto.JobType),
+		SalaryMin:   dto.SalaryMin,
+		SalaryMax:   dto.SalaryMax,
+		Location:    dto.Location,
+		Version:     1,
+	}
+	if job.Locale == "" {
+		job.Locale = i18n.DefaultLocale
 	}
 
-	if err := r.db.WithContext(ctx).Create(&job).Error; err != nil {
+	if err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&job).Error; err != nil {
+			return err
+		}
+
+		return event.Record(tx, event.JobCreated, event.AggregateJob, job.ID, job)
+	}); err != nil {
 		return nil, err
 	}
 
@@ -141,11 +613,14 @@ func (r *JobRepository) CreateJob(ctx context.Context, dto CreateJobDTO) (*model
 }
 
 type UpdateJobDTO struct {
-	Title     string `json:"title" binding:"required"`
-	Company   string `json:"company" binding:"required"`
-	JobType   string `json:"job_type" binding:"required,oneof=full-time part-time contract"`
-	SalaryMin int    `json:"salary_min" binding:"required"`
-	SalaryMax int    `json:"salary_max" binding:"required"`
+	Title       string `json:"title" binding:"required"`
+	Company     string `json:"company" binding:"required"`
+	Description string `json:"description"`
+	Locale      string `json:"locale" binding:"omitempty,oneof=en zh-TW ja"`
+	JobType     string `json:"job_type" binding:"required,oneof=full-time part-time contract"`
+	SalaryMin   int    `json:"salary_min" binding:"required"`
+	SalaryMax   int    `json:"salary_max" binding:"required"`
+	Location    string `json:"location" binding:"max=255"`
 }
 
 func (dto UpdateJobDTO) Validate() error {
@@ -156,20 +631,267 @@ func (dto UpdateJobDTO) Validate() error {
 	return nil
 }
 
-// UpdateJob updates a job
-func (r *JobRepository) UpdateJob(ctx context.Context, ID string, dto UpdateJobDTO) (*model.Job, error) {
-	if err := r.db.WithContext(ctx).Model(&model.Job{}).Where("id = ?", ID).UpdateColumns(dto).Error; err != nil {
+// UpdateJob updates a job, it fails with ErrPreconditionFailed unless the job is at one of versions.
+// nil versions update any version.
+func (r *JobRepository) UpdateJob(ctx context.Context, ID string, dto UpdateJobDTO, versions []int64) (*model.Job, error) {
+	var job model.Job
+	if err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", ID).First(&job).Error; err != nil {
+			return err
+		}
+		if !model.VersionMatches(job.Version, versions) {
+			return apperror.ErrPreconditionFailed
+		}
+
+		columns := map[string]interface{}{
+			"title":       dto.Title,
+			"company":     dto.Company,
+			"description": dto.Description,
+			"job_type":    dto.JobType,
+			"salary_min":  dto.SalaryMin,
+			"salary_max":  dto.SalaryMax,
+			"location":    dto.Location,
+			"version":     gorm.Expr("version + 1"),
+		}
+		if dto.Locale != "" {
+			columns["locale"] = dto.Locale
+		}
+
+		if err := tx.Model(&model.Job{}).Where("id = ?", ID).Updates(columns).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("id = ?", ID).First(&job).Error; err != nil {
+			return err
+		}
+
+		return event.Record(tx, event.JobUpdated, event.AggregateJob, job.ID, job)
+	}); err != nil {
 		return nil, err
 	}
 
-	return r.GetJobByID(ctx, ID)
+	return &job, nil
 }
 
-// DeleteJob deletes a job
-func (r *JobRepository) DeleteJob(ctx context.Context, ID string) error {
-	if err := r.db.WithContext(ctx).Where("id = ?", ID).Delete(&model.Job{}).Error; err != nil {
-		return err
+// PatchJob changes a job with patch, which gets the job as an UpdateJobDTO to change in place. Only changed
+// columns are written and the version isn't bumped when nothing changed. Like UpdateJob, it fails with
+// ErrPreconditionFailed unless the job is at one of versions.
+func (r *JobRepository) PatchJob(ctx context.Context, ID string, patch func(dto *UpdateJobDTO) error, versions []int64) (*model.Job, error) {
+	var job model.Job
+	if err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", ID).First(&job).Error; err != nil {
+			return err
+		}
+		if !model.VersionMatches(job.Version, versions) {
+			return apperror.ErrPreconditionFailed
+		}
+
+		dto := UpdateJobDTO{
+			Title:       job.Title,
+			Company:     job.Company,
+			Description: job.Description,
+			Locale:      job.Locale,
+			JobType:     string(job.JobType),
+			SalaryMin:   job.SalaryMin,
+			SalaryMax:   job.SalaryMax,
+			Location:    job.Location,
+		}
+		if err := patch(&dto); err != nil {
+			return err
+		}
+		// Jobs are written in English unless told otherwise, as in CreateJob
+		if dto.Locale == "" {
+			dto.Locale = i18n.DefaultLocale
+		}
+
+		columns := changedJobColumns(job, dto)
+		if len(columns) == 0 {
+			return nil
+		}
+		columns["version"] = gorm.Expr("version + 1")
+
+		if err := tx.Model(&model.Job{}).Where("id = ?", ID).Updates(columns).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("id = ?", ID).First(&job).Error; err != nil {
+			return err
+		}
+
+		return event.Record(tx, event.JobUpdated, event.AggregateJob, job.ID, job)
+	}); err != nil {
+		return nil, err
 	}
 
-	return nil
+	return &job, nil
+}
+
+// changedJobColumns returns the columns of job which dto changes.
+func changedJobColumns(job model.Job, dto UpdateJobDTO) map[string]interface{} {
+	columns := map[string]interface{}{}
+	for column, values := range map[string][2]interface{}{
+		"title":       {job.Title, dto.Title},
+		"company":     {job.Company, dto.Company},
+		"description": {job.Description, dto.Description},
+		"locale":      {job.Locale, dto.Locale},
+		"job_type":    {string(job.JobType), dto.JobType},
+		"salary_min":  {job.SalaryMin, dto.SalaryMin},
+		"salary_max":  {job.SalaryMax, dto.SalaryMax},
+		"location":    {job.Location, dto.Location},
+	} {
+		if values[0] != values[1] {
+			columns[column] = values[1]
+		}
+	}
+
+	return columns
+}
+
+// ImportJobDTO is a job of an import, jobs are matched by ExternalRef.
+type ImportJobDTO struct {
+	ExternalRef string `json:"external_ref" binding:"required,max=255"`
+	Title       string `json:"title" binding:"required"`
+	Company     string `json:"company" binding:"required"`
+	Description string `json:"description"`
+	Locale      string `json:"locale" binding:"omitempty,oneof=en zh-TW ja"`
+	JobType     string `json:"job_type" binding:"required,oneof=full-time part-time contract"`
+	SalaryMin   int    `json:"salary_min" binding:"required"`
+	SalaryMax   int    `json:"salary_max" binding:"required"`
+	Location    string `json:"location" binding:"max=255"`
+}
+
+// CreateJobDTO is the job to create when no job has the external reference yet.
+func (dto ImportJobDTO) CreateJobDTO() CreateJobDTO {
+	return CreateJobDTO{
+		Title:       dto.Title,
+		Company:     dto.Company,
+		Description: dto.Description,
+		Locale:      dto.Locale,
+		JobType:     dto.JobType,
+		SalaryMin:   dto.SalaryMin,
+		SalaryMax:   dto.SalaryMax,
+		Location:    dto.Location,
+	}
+}
+
+func (dto ImportJobDTO) Validate() error {
+	return dto.CreateJobDTO().Validate()
+}
+
+const (
+	JobImportCreated   = "created"
+	JobImportUpdated   = "updated"
+	JobImportUnchanged = "unchanged"
+	JobImportFailed    = "failed"
+)
+
+// ImportJobResult is the outcome of importing a job, Err tells why it failed.
+type ImportJobResult struct {
+	Status string
+	Job    *model.Job
+	Err    error
+}
+
+// errJobImportDryRun rolls back dry runs of ImportJobs.
+var errJobImportDryRun = errors.New("dry run")
+
+// ImportJobs creates jobs whose external references are new and updates the others, in the order of dtos. Each
+// job is imported on its own, a job which fails doesn't stop the others. A dry run reports what would happen
+// without changing anything, created jobs don't exist then.
+func (r *JobRepository) ImportJobs(ctx context.Context, dtos []ImportJobDTO, dryRun bool) ([]ImportJobResult, error) {
+	results := make([]ImportJobResult, len(dtos))
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i, dto := range dtos {
+			// Each job is imported in a savepoint, so a failed one is rolled back alone.
+			if err := tx.Transaction(func(tx *gorm.DB) error {
+				status, job, err := importJob(tx, dto)
+				results[i] = ImportJobResult{Status: status, Job: job}
+				return err
+			}); err != nil {
+				r.logger.Debug().Err(err).Str("external_ref", dto.ExternalRef).Msg("failed to import job")
+				results[i] = ImportJobResult{Status: JobImportFailed, Err: err}
+			}
+		}
+
+		if dryRun {
+			return errJobImportDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errJobImportDryRun) {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func importJob(tx *gorm.DB, dto ImportJobDTO) (string, *model.Job, error) {
+	var job model.Job
+	err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).Where("external_ref = ?", dto.ExternalRef).First(&job).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		job = model.Job{
+			Title:       dto.Title,
+			Company:     dto.Company,
+			Description: dto.Description,
+			Locale:      dto.Locale,
+			JobType:     model.JobType(dto.JobType),
+			SalaryMin:   dto.SalaryMin,
+			SalaryMax:   dto.SalaryMax,
+			Location:    dto.Location,
+			ExternalRef: &dto.ExternalRef,
+			Version:     1,
+		}
+		if job.Locale == "" {
+			job.Locale = i18n.DefaultLocale
+		}
+
+		if err := tx.Create(&job).Error; err != nil {
+			return "", nil, err
+		}
+
+		return JobImportCreated, &job, event.Record(tx, event.JobCreated, event.AggregateJob, job.ID, job)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	if job.DeletedAt.Valid {
+		return "", nil, ErrJobClosed
+	}
+
+	update := UpdateJobDTO(dto.CreateJobDTO())
+	if update.Locale == "" {
+		update.Locale = i18n.DefaultLocale
+	}
+
+	columns := changedJobColumns(job, update)
+	if len(columns) == 0 {
+		return JobImportUnchanged, &job, nil
+	}
+	columns["version"] = gorm.Expr("version + 1")
+
+	if err := tx.Model(&model.Job{}).Where("id = ?", job.ID).Updates(columns).Error; err != nil {
+		return "", nil, err
+	}
+	if err := tx.Where("id = ?", job.ID).First(&job).Error; err != nil {
+		return "", nil, err
+	}
+
+	return JobImportUpdated, &job, event.Record(tx, event.JobUpdated, event.AggregateJob, job.ID, job)
+}
+
+// DeleteJob deletes a job
+func (r *JobRepository) DeleteJob(ctx context.Context, ID string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ?", ID).Delete(&model.Job{})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		// Deleting a job twice isn't an event.
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		return event.Record(tx, event.JobDeleted, event.AggregateJob, ID, map[string]string{"id": ID})
+	})
 }
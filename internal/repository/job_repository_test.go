@@ -1,6 +1,18 @@
 package repository
 
-import "testing"
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/rs/zerolog"
+
+	"fliqt/internal/apperror"
+	"fliqt/internal/model"
+	"fliqt/internal/search"
+	"fliqt/internal/util"
+)
 
 func TestCreateJobDTOValidate(t *testing.T) {
 	dto := CreateJobDTO{
@@ -27,3 +39,352 @@ func TestCreateJobDTOValidate(t *testing.T) {
 		t.Errorf("expected ErrJobSalaryRange, got %v", err)
 	}
 }
+
+func TestJobRepositoryListJobsSortedPages(t *testing.T) {
+	db, mock, cleanup := util.SetupMockDB(t)
+	defer cleanup()
+
+	logger := zerolog.New(zerolog.ConsoleWriter{Out: nil})
+
+	repo := NewJobRepository(db, &logger, search.NewMySQLJobSearchIndex())
+	params := JobFilterParams{
+		PaginationParams: model.PaginationParams{
+			PageSize: 1,
+			Sort:     "salary_max,-created_at",
+		},
+	}
+	columns := []string{"id", "title", "company", "job_type", "salary_min", "salary_max", "locale", "created_at", "updated_at"}
+
+	mock.ExpectQuery("SELECT .* FROM `jobs` WHERE `jobs`\\.`deleted_at` IS NULL ORDER BY jobs.salary_max ASC, jobs.created_at DESC, jobs.id DESC LIMIT \\?").
+		WithArgs(2).
+		WillReturnRows(
+			sqlmock.NewRows(columns).
+				AddRow("cqanbg8cvavjpljmh7pg", "Engineer", "fliQt", "full-time", 1000, 2000, "en", "2024-01-02T00:00:00Z", "2024-01-02T00:00:00Z").
+				AddRow("cqanbg8cvavjpljmh7ph", "Designer", "fliQt", "full-time", 1000, 3000, "en", "2024-01-01T00:00:00Z", "2024-01-01T00:00:00Z"),
+		)
+
+	first, err := repo.ListJobs(context.TODO(), params)
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if len(first.Items) != 1 || first.NextToken == "" || first.PrevToken != "" {
+		t.Fatalf("expected one job and a next page, got %+v", first)
+	}
+
+	createdAt := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT .* FROM `jobs` WHERE \\(\\(\\(jobs.salary_max > \\?\\) OR \\(jobs.salary_max = \\? AND jobs.created_at < \\?\\) OR \\(jobs.salary_max = \\? AND jobs.created_at = \\? AND jobs.id < \\?\\)\\)\\) AND `jobs`\\.`deleted_at` IS NULL ORDER BY jobs.salary_max ASC, jobs.created_at DESC, jobs.id DESC LIMIT \\?").
+		WithArgs(2000, 2000, createdAt, 2000, createdAt, "cqanbg8cvavjpljmh7pg", 2).
+		WillReturnRows(
+			sqlmock.NewRows(columns).
+				AddRow("cqanbg8cvavjpljmh7ph", "Designer", "fliQt", "full-time", 1000, 3000, "en", "2024-01-01T00:00:00Z", "2024-01-01T00:00:00Z"),
+		)
+
+	params.NextToken = first.NextToken
+	second, err := repo.ListJobs(context.TODO(), params)
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if len(second.Items) != 1 || second.NextToken != "" || second.PrevToken == "" {
+		t.Errorf("expected the last page with a previous page, got %+v", second)
+	}
+
+	// Cursors are bound to their sort
+	params.Sort = "salary_min"
+	if _, err := repo.ListJobs(context.TODO(), params); err != model.ErrInvalidCursor {
+		t.Errorf("expected ErrInvalidCursor, got %v", err)
+	}
+
+	params.NextToken = first.NextToken[:len(first.NextToken)-1] + "A"
+	params.Sort = "salary_max,-created_at"
+	if _, err := repo.ListJobs(context.TODO(), params); err != model.ErrInvalidCursor {
+		t.Errorf("expected ErrInvalidCursor for a forged cursor, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestJobRepositoryListJobsLocalized(t *testing.T) {
+	db, mock, cleanup := util.SetupMockDB(t)
+	defer cleanup()
+
+	logger := zerolog.New(zerolog.ConsoleWriter{Out: nil})
+
+	repo := NewJobRepository(db, &logger, search.NewMySQLJobSearchIndex())
+	params := JobFilterParams{
+		PaginationParams: model.PaginationParams{PageSize: 10},
+		Keyword:          "工程師",
+		Locales:          []string{"zh-TW", "ja", "en"},
+	}
+
+	mock.ExpectQuery("SELECT jobs.id, .*, GREATEST\\(MATCH\\(jobs.title, jobs.company, jobs.description\\) AGAINST \\(\\?\\), COALESCE\\(\\(SELECT MAX\\(MATCH\\(job_translations.title, job_translations.description\\) AGAINST \\(\\?\\)\\) FROM job_translations WHERE job_translations.job_id = jobs.id AND job_translations.locale IN \\(\\?,\\?,\\?\\) .*\\) AS relevance FROM `jobs` WHERE \\(MATCH\\(jobs.title, jobs.company, jobs.description\\) AGAINST \\(\\?\\) OR jobs.id IN \\(SELECT job_id FROM job_translations WHERE locale IN \\(\\?,\\?,\\?\\) AND deleted_at IS NULL AND MATCH\\(title, description\\) AGAINST \\(\\?\\)\\)\\)").
+		WithArgs("工程師", "工程師", "zh-TW", "ja", "en", "工程師", "zh-TW", "ja", "en", "工程師", 11).
+		WillReturnRows(
+			sqlmock.NewRows([]string{"id", "title", "company", "locale", "relevance"}).
+				AddRow("cqanbg8cvavjpljmh7pg", "Software Engineer", "fliQt", "en", 0.5).
+				AddRow("cqanbg8cvavjpljmh7ph", "ソフトウェアエンジニア", "fliQt", "ja", 0.4).
+				AddRow("cqanbg8cvavjpljmh7pi", "軟體工程師", "fliQt", "zh-TW", 0.3),
+		)
+	mock.ExpectQuery("SELECT \\* FROM `job_translations` WHERE \\(job_id IN \\(\\?,\\?\\) AND locale IN \\(\\?,\\?,\\?\\)\\)").
+		WithArgs("cqanbg8cvavjpljmh7pg", "cqanbg8cvavjpljmh7ph", "zh-TW", "ja", "en").
+		WillReturnRows(
+			sqlmock.NewRows([]string{"id", "job_id", "locale", "title"}).
+				AddRow("1", "cqanbg8cvavjpljmh7pg", "zh-TW", "軟體工程師").
+				AddRow("2", "cqanbg8cvavjpljmh7ph", "en", "Software Engineer"),
+		)
+
+	result, err := repo.ListJobs(context.TODO(), params)
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+
+	// The translation of the preferred locale, the job's own language before a fallback, the job's own language.
+	expected := [][2]string{{"軟體工程師", "zh-TW"}, {"ソフトウェアエンジニア", "ja"}, {"軟體工程師", "zh-TW"}}
+	for i, job := range result.Items {
+		if job.Title != expected[i][0] || job.Locale != expected[i][1] {
+			t.Errorf("expected job %d in %s, got %s in %s", i, expected[i][1], job.Title, job.Locale)
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+// stubJobSearchIndex finds a job which its search names and keeps the requests, indexing isn't implemented.
+type stubJobSearchIndex struct {
+	search.JobSearchIndex
+	search   *search.JobSearch
+	requests []search.JobSearchRequest
+}
+
+func (i *stubJobSearchIndex) Search(ctx context.Context, request search.JobSearchRequest) (*search.JobSearch, error) {
+	i.requests = append(i.requests, request)
+	return i.search, nil
+}
+
+func TestJobRepositoryListJobsSearchIndex(t *testing.T) {
+	db, mock, cleanup := util.SetupMockDB(t)
+	defer cleanup()
+
+	logger := zerolog.Nop()
+
+	searchIndex := &stubJobSearchIndex{search: &search.JobSearch{
+		Condition:     "jobs.id IN ?",
+		Args:          []interface{}{[]interface{}{"cqanbg8cvavjpljmh7pg"}},
+		Relevance:     "CASE jobs.id WHEN ? THEN ? ELSE 0 END",
+		RelevanceArgs: []interface{}{"cqanbg8cvavjpljmh7pg", 1.5},
+		Highlights:    map[string]map[string][]string{"cqanbg8cvavjpljmh7pg": {"title": {"Backend <mark>Engineer</mark>"}}},
+	}}
+	repo := NewJobRepository(db, &logger, searchIndex)
+	params := JobFilterParams{
+		PaginationParams: model.PaginationParams{PageSize: 10, Sort: "-relevance"},
+		Keyword:          "enginer",
+		Location:         "Taipei",
+	}
+
+	mock.ExpectQuery("SELECT jobs.id, .*, CASE jobs.id WHEN \\? THEN \\? ELSE 0 END AS relevance FROM `jobs` WHERE jobs.id IN \\(\\?\\) AND location = \\? .* ORDER BY CASE jobs.id WHEN \\? THEN \\? ELSE 0 END DESC, jobs.id DESC").
+		WithArgs("cqanbg8cvavjpljmh7pg", 1.5, "cqanbg8cvavjpljmh7pg", "Taipei", "cqanbg8cvavjpljmh7pg", 1.5, 11).
+		WillReturnRows(
+			sqlmock.NewRows([]string{"id", "title", "company", "locale", "relevance"}).
+				AddRow("cqanbg8cvavjpljmh7pg", "Backend Engineer", "fliQt", "en", 1.5),
+		)
+
+	result, err := repo.ListJobs(context.TODO(), params)
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if len(result.Items) != 1 || result.Items[0].Highlights["title"][0] != "Backend <mark>Engineer</mark>" {
+		t.Errorf("expected the job with its highlights, got %+v", result.Items)
+	}
+
+	// Ranked results only need the best matches, filtered by the index as well
+	if request := searchIndex.requests[0]; request.All || !request.Highlight || request.Filters.Location != "Taipei" {
+		t.Errorf("expected a ranked search of the best matches in Taipei, got %+v", request)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestJobRepositoryUpdateJobVersionMismatch(t *testing.T) {
+	db, mock, cleanup := util.SetupMockDB(t)
+	defer cleanup()
+
+	logger := zerolog.Nop()
+
+	repo := NewJobRepository(db, &logger, search.NewMySQLJobSearchIndex())
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\* FROM `jobs` WHERE id = \\? AND `jobs`\\.`deleted_at` IS NULL ORDER BY `jobs`\\.`id` LIMIT \\? FOR UPDATE").
+		WithArgs("cqanbg8cvavjpljmh7pg", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "version"}).AddRow("cqanbg8cvavjpljmh7pg", "Engineer", 4))
+	mock.ExpectRollback()
+
+	dto := UpdateJobDTO{Title: "Engineer", Company: "fliQt", JobType: "full-time", SalaryMin: 1000, SalaryMax: 2000}
+	if _, err := repo.UpdateJob(context.Background(), "cqanbg8cvavjpljmh7pg", dto, []int64{3}); err != apperror.ErrPreconditionFailed {
+		t.Errorf("expected ErrPreconditionFailed, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestJobRepositoryPutJobTranslationVersionMismatch(t *testing.T) {
+	db, mock, cleanup := util.SetupMockDB(t)
+	defer cleanup()
+
+	logger := zerolog.Nop()
+
+	repo := NewJobRepository(db, &logger, search.NewMySQLJobSearchIndex())
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\* FROM `jobs` WHERE id = \\? AND `jobs`\\.`deleted_at` IS NULL ORDER BY `jobs`\\.`id` LIMIT \\? FOR UPDATE").
+		WithArgs("cqanbg8cvavjpljmh7pg", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "locale", "version"}).AddRow("cqanbg8cvavjpljmh7pg", "Engineer", "en", 4))
+	mock.ExpectRollback()
+
+	dto := PutJobTranslationDTO{Title: "エンジニア"}
+	if _, _, err := repo.PutJobTranslation(context.Background(), "cqanbg8cvavjpljmh7pg", "ja", dto, []int64{3}); err != apperror.ErrPreconditionFailed {
+		t.Errorf("expected ErrPreconditionFailed, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestJobRepositoryImportJobsDryRun(t *testing.T) {
+	db, mock, cleanup := util.SetupMockDB(t)
+	defer cleanup()
+
+	logger := zerolog.Nop()
+
+	repo := NewJobRepository(db, &logger, search.NewMySQLJobSearchIndex())
+	columns := []string{"id", "title", "company", "description", "locale", "job_type", "salary_min", "salary_max", "external_ref", "version", "deleted_at"}
+
+	mock.ExpectBegin()
+	// REQ-1 is new
+	mock.ExpectExec("SAVEPOINT").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT \\* FROM `jobs` WHERE external_ref = \\? ORDER BY `jobs`\\.`id` LIMIT \\? FOR UPDATE").
+		WithArgs("REQ-1", 1).
+		WillReturnRows(sqlmock.NewRows(columns))
+	mock.ExpectExec("INSERT INTO `jobs`").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO `outbox`").WillReturnResult(sqlmock.NewResult(0, 1))
+	// REQ-2 is unchanged
+	mock.ExpectExec("SAVEPOINT").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT \\* FROM `jobs` WHERE external_ref = \\?").
+		WithArgs("REQ-2", 1).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("cqanbg8cvavjpljmh7pg", "Designer", "fliQt", "", "en", "contract", 1000, 2000, "REQ-2", 2, nil))
+	// REQ-3 is closed
+	mock.ExpectExec("SAVEPOINT").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT \\* FROM `jobs` WHERE external_ref = \\?").
+		WithArgs("REQ-3", 1).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("cqanb5gcvavjneudu13g", "Manager", "fliQt", "", "en", "full-time", 1000, 2000, "REQ-3", 1, time.Now()))
+	mock.ExpectExec("ROLLBACK TO SAVEPOINT").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	results, err := repo.ImportJobs(context.Background(), []ImportJobDTO{
+		{ExternalRef: "REQ-1", Title: "Engineer", Company: "fliQt", JobType: "full-time", SalaryMin: 1000, SalaryMax: 2000},
+		{ExternalRef: "REQ-2", Title: "Designer", Company: "fliQt", JobType: "contract", SalaryMin: 1000, SalaryMax: 2000},
+		{ExternalRef: "REQ-3", Title: "Manager", Company: "fliQt", JobType: "full-time", SalaryMin: 1000, SalaryMax: 3000},
+	}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	statuses := []string{results[0].Status, results[1].Status, results[2].Status}
+	if statuses[0] != JobImportCreated || statuses[1] != JobImportUnchanged || statuses[2] != JobImportFailed {
+		t.Errorf("expected created, unchanged and failed, got %v", statuses)
+	}
+	if results[2].Err != ErrJobClosed {
+		t.Errorf("expected ErrJobClosed, got %v", results[2].Err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestJobRepositoryJobFacets(t *testing.T) {
+	db, mock, cleanup := util.SetupMockDB(t)
+	defer cleanup()
+
+	logger := zerolog.Nop()
+
+	repo := NewJobRepository(db, &logger, search.NewMySQLJobSearchIndex())
+	params := JobFilterParams{JobType: "full-time", Location: "Taipei", SalaryMin: 40000}
+
+	// Each facet is counted without its own filter
+	mock.ExpectQuery("SELECT jobs.job_type AS value, COUNT\\(\\*\\) AS count FROM `jobs` WHERE salary_min >= \\? AND location = \\? AND jobs.job_type <> \\? AND `jobs`.`deleted_at` IS NULL GROUP BY `jobs`.`job_type` ORDER BY count DESC, value ASC").
+		WithArgs(40000, "Taipei", "").
+		WillReturnRows(sqlmock.NewRows([]string{"value", "count"}).AddRow("full-time", 3).AddRow("contract", 1))
+	mock.ExpectQuery("SELECT jobs.company AS value, COUNT\\(\\*\\) AS count FROM `jobs` WHERE salary_min >= \\? AND job_type = \\? AND location = \\? AND jobs.company <> \\? .* LIMIT \\?").
+		WithArgs(40000, "full-time", "Taipei", "", maxFacetValues).
+		WillReturnRows(sqlmock.NewRows([]string{"value", "count"}).AddRow("fliQt", 3))
+	mock.ExpectQuery("SELECT jobs.location AS value, COUNT\\(\\*\\) AS count FROM `jobs` WHERE salary_min >= \\? AND job_type = \\? AND jobs.location <> \\? .* LIMIT \\?").
+		WithArgs(40000, "full-time", "", maxFacetValues).
+		WillReturnRows(sqlmock.NewRows([]string{"value", "count"}).AddRow("Taipei", 3).AddRow("Remote", 2))
+	mock.ExpectQuery("SELECT INTERVAL\\(jobs.salary_min, \\?, \\?\\) AS bucket, COUNT\\(\\*\\) AS count FROM `jobs` WHERE job_type = \\? AND location = \\? AND `jobs`.`deleted_at` IS NULL GROUP BY `bucket`").
+		WithArgs(30000, 50000, "full-time", "Taipei").
+		WillReturnRows(sqlmock.NewRows([]string{"bucket", "count"}).AddRow(0, 1).AddRow(2, 2))
+
+	facets, err := repo.JobFacets(context.Background(), params, []int{50000, 30000})
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+
+	if len(facets.JobTypes) != 2 || facets.JobTypes[0] != (FacetCount{"full-time", 3}) {
+		t.Errorf("unexpected job types %+v", facets.JobTypes)
+	}
+	if len(facets.Locations) != 2 || facets.Locations[1] != (FacetCount{"Remote", 2}) {
+		t.Errorf("unexpected locations %+v", facets.Locations)
+	}
+
+	buckets := facets.SalaryBuckets
+	if len(buckets) != 3 {
+		t.Fatalf("expected 3 salary buckets, got %+v", buckets)
+	}
+	if buckets[0].From != nil || *buckets[0].To != 30000 || buckets[0].Count != 1 {
+		t.Errorf("unexpected first bucket %+v", buckets[0])
+	}
+	if *buckets[1].From != 30000 || *buckets[1].To != 50000 || buckets[1].Count != 0 {
+		t.Errorf("unexpected empty bucket %+v", buckets[1])
+	}
+	if *buckets[2].From != 50000 || buckets[2].To != nil || buckets[2].Count != 2 {
+		t.Errorf("unexpected last bucket %+v", buckets[2])
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestJobRepositoryJobFacetsSearchIndex(t *testing.T) {
+	db, mock, cleanup := util.SetupMockDB(t)
+	defer cleanup()
+
+	logger := zerolog.Nop()
+
+	searchIndex := &stubJobSearchIndex{search: &search.JobSearch{Condition: "1 = 0", Relevance: "0"}}
+	repo := NewJobRepository(db, &logger, searchIndex)
+
+	for _, column := range []string{"job_type", "company", "location"} {
+		mock.ExpectQuery("SELECT jobs." + column + " AS value").WillReturnRows(sqlmock.NewRows([]string{"value", "count"}))
+	}
+
+	if _, err := repo.JobFacets(context.Background(), JobFilterParams{Keyword: "engineer", JobType: "full-time"}, nil); err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+
+	// Facets leave their own filters out, so every match of the keyword is counted
+	if request := searchIndex.requests[0]; !request.All || request.Filters != (search.JobSearchFilters{}) {
+		t.Errorf("expected a search of every match without filters, got %+v", request)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
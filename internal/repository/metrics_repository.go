@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"fliqt/internal/model"
+
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+)
+
+type MetricsRepository struct {
+	db     *gorm.DB
+	logger *zerolog.Logger
+}
+
+func NewMetricsRepository(
+	db *gorm.DB,
+	logger *zerolog.Logger,
+) *MetricsRepository {
+	return &MetricsRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// CountOpenJobs counts jobs which haven't been deleted.
+func (r *MetricsRepository) CountOpenJobs(ctx context.Context) (int64, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&model.Job{}).Count(&count).Error; err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// CountApplicationsByStatus counts applications by their status, purged ones are counted as well.
+func (r *MetricsRepository) CountApplicationsByStatus(ctx context.Context) (map[string]int64, error) {
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	if err := r.db.WithContext(ctx).Model(&model.Application{}).
+		Select("status, COUNT(*) AS count").
+		Group("status").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+
+	return counts, nil
+}
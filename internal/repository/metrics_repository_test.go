@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/rs/zerolog"
+
+	"fliqt/internal/util"
+)
+
+func TestMetricsRepositoryCountApplicationsByStatus(t *testing.T) {
+	db, mock, cleanup := util.SetupMockDB(t)
+	defer cleanup()
+
+	logger := zerolog.New(zerolog.ConsoleWriter{Out: nil})
+
+	repo := NewMetricsRepository(db, &logger)
+
+	mock.ExpectQuery("SELECT status, COUNT\\(\\*\\) AS count FROM `applications` WHERE `applications`\\.`deleted_at` IS NULL GROUP BY `status`").
+		WillReturnRows(
+			sqlmock.NewRows([]string{"status", "count"}).
+				AddRow("pending", 3).
+				AddRow("hired", 1),
+		)
+
+	counts, err := repo.CountApplicationsByStatus(context.TODO())
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+
+	if counts["pending"] != 3 || counts["hired"] != 1 || len(counts) != 2 {
+		t.Errorf("expected counts of pending and hired applications, got %v", counts)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
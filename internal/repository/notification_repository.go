@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"fliqt/internal/apperror"
+	"fliqt/internal/model"
+	"net/http"
+
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrUnknownNotificationType = apperror.New(http.StatusBadRequest, "unknown_notification_type", "unknown notification type")
+
+type NotificationRepository struct {
+	db     *gorm.DB
+	logger *zerolog.Logger
+}
+
+func NewNotificationRepository(
+	db *gorm.DB,
+	logger *zerolog.Logger,
+) *NotificationRepository {
+	return &NotificationRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// NotificationPreferencesDTO tells whether each type of notifications is enabled
+type NotificationPreferencesDTO map[string]bool
+
+func (dto NotificationPreferencesDTO) Validate() error {
+	for notificationType := range dto {
+		if !model.StringList(model.NotificationTypes).Contains(notificationType) {
+			return ErrUnknownNotificationType
+		}
+	}
+
+	return nil
+}
+
+// GetPreferences returns the preferences of a user, types without a preference are enabled
+func (r *NotificationRepository) GetPreferences(ctx context.Context, userID string) (NotificationPreferencesDTO, error) {
+	var preferences []model.NotificationPreference
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Find(&preferences).Error; err != nil {
+		return nil, err
+	}
+
+	dto := NotificationPreferencesDTO{}
+	for _, notificationType := range model.NotificationTypes {
+		dto[notificationType] = true
+	}
+	for _, preference := range preferences {
+		dto[preference.NotificationType] = preference.Enabled
+	}
+
+	return dto, nil
+}
+
+// SetPreferences updates the preferences of a user, types which aren't given are left as they are
+func (r *NotificationRepository) SetPreferences(ctx context.Context, userID string, dto NotificationPreferencesDTO) (NotificationPreferencesDTO, error) {
+	if err := dto.Validate(); err != nil {
+		return nil, err
+	}
+
+	preferences := make([]model.NotificationPreference, 0, len(dto))
+	for notificationType, enabled := range dto {
+		preferences = append(preferences, model.NotificationPreference{
+			UserID:           userID,
+			NotificationType: notificationType,
+			Enabled:          enabled,
+		})
+	}
+
+	if len(preferences) > 0 {
+		if err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+			DoUpdates: clause.AssignmentColumns([]string{"enabled", "updated_at"}),
+		}).Create(&preferences).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return r.GetPreferences(ctx, userID)
+}
+
+// HasSent returns whether a notification has been sent to a user for an event
+func (r *NotificationRepository) HasSent(ctx context.Context, eventID string, userID string, notificationType string) (bool, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&model.NotificationLog{}).
+		Where("event_id = ? AND user_id = ? AND notification_type = ?", eventID, userID, notificationType).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// RecordSent records that a notification has been sent to a user for an event
+func (r *NotificationRepository) RecordSent(ctx context.Context, eventID string, userID string, notificationType string) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&model.NotificationLog{
+		EventID:          eventID,
+		UserID:           userID,
+		NotificationType: notificationType,
+	}).Error
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"fliqt/internal/model"
+	"time"
+
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+)
+
+type PrivacyRepository struct {
+	db     *gorm.DB
+	logger *zerolog.Logger
+}
+
+func NewPrivacyRepository(
+	db *gorm.DB,
+	logger *zerolog.Logger,
+) *PrivacyRepository {
+	return &PrivacyRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// ListApplicationsByUser returns every application of a user along with its job, including purged ones
+func (r *PrivacyRepository) ListApplicationsByUser(ctx context.Context, userID string) ([]model.Application, error) {
+	var applications []model.Application
+	if err := r.db.WithContext(ctx).
+		Preload("Job", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Where("user_id = ?", userID).
+		Order("id ASC").
+		Find(&applications).Error; err != nil {
+		return nil, err
+	}
+
+	return applications, nil
+}
+
+// ListDownloadLogsByObjectKeys returns who downloaded the given files
+func (r *PrivacyRepository) ListDownloadLogsByObjectKeys(ctx context.Context, objectKeys []string) ([]model.DownloadLog, error) {
+	var logs []model.DownloadLog
+	if len(objectKeys) == 0 {
+		return logs, nil
+	}
+
+	if err := r.db.WithContext(ctx).Where("object_key IN ?", objectKeys).Order("id ASC").Find(&logs).Error; err != nil {
+		return nil, err
+	}
+
+	return logs, nil
+}
+
+// PseudonymizeDownloadLogs removes the object keys from download logs, since object keys contain the owner's ID
+func (r *PrivacyRepository) PseudonymizeDownloadLogs(ctx context.Context, objectKeys []string) error {
+	if len(objectKeys) == 0 {
+		return nil
+	}
+
+	return r.db.WithContext(ctx).Model(&model.DownloadLog{}).Where("object_key IN ?", objectKeys).UpdateColumn("object_key", "").Error
+}
+
+// GetPendingErasureRequestByUser returns the pending erasure request of a user
+func (r *PrivacyRepository) GetPendingErasureRequestByUser(ctx context.Context, userID string) (*model.ErasureRequest, error) {
+	var request model.ErasureRequest
+	if err := r.db.WithContext(ctx).Where("user_id = ? AND status = ?", userID, model.ErasureStatusPending).First(&request).Error; err != nil {
+		return nil, err
+	}
+
+	return &request, nil
+}
+
+// CreateErasureRequest creates a pending erasure request
+func (r *PrivacyRepository) CreateErasureRequest(ctx context.Context, userID string) (*model.ErasureRequest, error) {
+	request := model.ErasureRequest{
+		UserID: userID,
+		Status: model.ErasureStatusPending,
+	}
+	if err := r.db.WithContext(ctx).Create(&request).Error; err != nil {
+		return nil, err
+	}
+
+	return &request, nil
+}
+
+// GetErasureRequestByID returns an erasure request by its ID
+func (r *PrivacyRepository) GetErasureRequestByID(ctx context.Context, ID string) (*model.ErasureRequest, error) {
+	var request model.ErasureRequest
+	if err := r.db.WithContext(ctx).Where("id = ?", ID).First(&request).Error; err != nil {
+		return nil, err
+	}
+
+	return &request, nil
+}
+
+type ErasureRequestFilterParams struct {
+	model.PaginationParams
+
+	Status string `form:"status,omitempty"`
+}
+
+// ListErasureRequests returns a list of erasure requests, it can be sorted by created_at
+func (r *PrivacyRepository) ListErasureRequests(ctx context.Context, filterParams ErasureRequestFilterParams) (model.PaginationResponse[model.ErasureRequest], error) {
+	query := r.db.WithContext(ctx).Model(&model.ErasureRequest{})
+
+	if filterParams.Status != "" {
+		query = query.Where("status = ?", filterParams.Status)
+	}
+
+	sort, err := model.ParseSort(filterParams.Sort, map[string]model.SortKey{
+		"created_at": {Column: "created_at", Kind: model.SortKindTime},
+	}, "id")
+	if err != nil {
+		return model.PaginationResponse[model.ErasureRequest]{}, err
+	}
+
+	return model.Paginate(query, filterParams.PaginationParams, sort, func(request model.ErasureRequest, key string) interface{} {
+		if key == "created_at" {
+			return request.CreatedAt
+		}
+		return request.ID
+	})
+}
+
+// ReviewErasureRequest closes a pending erasure request with the given status
+func (r *PrivacyRepository) ReviewErasureRequest(ctx context.Context, ID string, reviewerID string, status string, reason string) (*model.ErasureRequest, error) {
+	now := time.Now()
+	result := r.db.WithContext(ctx).Model(&model.ErasureRequest{}).
+		Where("id = ? AND status = ?", ID, model.ErasureStatusPending).
+		Updates(map[string]interface{}{
+			"status":           status,
+			"reviewer_id":      reviewerID,
+			"reviewed_at":      now,
+			"rejection_reason": reason,
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return r.GetErasureRequestByID(ctx, ID)
+}
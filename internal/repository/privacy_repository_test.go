@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/rs/zerolog"
+
+	"fliqt/internal/model"
+	"fliqt/internal/util"
+)
+
+func TestPrivacyRepositoryReviewErasureRequest(t *testing.T) {
+	db, mock, cleanup := util.SetupMockDB(t)
+	defer cleanup()
+
+	logger := zerolog.New(zerolog.ConsoleWriter{Out: nil})
+
+	repo := NewPrivacyRepository(db, &logger)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `erasure_requests` SET `rejection_reason`=\\?,`reviewed_at`=\\?,`reviewer_id`=\\?,`status`=\\?,`updated_at`=\\? WHERE \\(id = \\? AND status = \\?\\) AND `erasure_requests`\\.`deleted_at` IS NULL").
+		WithArgs("duplicate account", sqlmock.AnyArg(), "hr1", model.ErasureStatusRejected, sqlmock.AnyArg(), "cqanbg8cvavjpljmh7pg", model.ErasureStatusPending).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	mock.ExpectQuery("SELECT \\* FROM `erasure_requests` WHERE id = \\? AND `erasure_requests`\\.`deleted_at` IS NULL ORDER BY `erasure_requests`\\.`id` LIMIT \\?").
+		WithArgs("cqanbg8cvavjpljmh7pg", 1).
+		WillReturnRows(
+			sqlmock.NewRows([]string{"id", "user_id", "status", "reviewer_id", "rejection_reason"}).
+				AddRow("cqanbg8cvavjpljmh7pg", "1", model.ErasureStatusRejected, "hr1", "duplicate account"),
+		)
+
+	request, err := repo.ReviewErasureRequest(context.TODO(), "cqanbg8cvavjpljmh7pg", "hr1", model.ErasureStatusRejected, "duplicate account")
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+
+	if request.Status != model.ErasureStatusRejected || request.ReviewerID == nil || *request.ReviewerID != "hr1" {
+		t.Errorf("Expected a rejected erasure request, got %+v", request)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
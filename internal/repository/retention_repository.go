@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"fliqt/internal/event"
+	"fliqt/internal/model"
+	"time"
+
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+)
+
+type RetentionRepository struct {
+	db     *gorm.DB
+	logger *zerolog.Logger
+}
+
+func NewRetentionRepository(
+	db *gorm.DB,
+	logger *zerolog.Logger,
+) *RetentionRepository {
+	return &RetentionRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// ListExpiredApplications returns applications in the status which haven't been updated since the given time,
+// applications of candidates on legal hold are never returned. It's paginated by IDs in ascending order.
+func (r *RetentionRepository) ListExpiredApplications(ctx context.Context, status string, before time.Time, afterID string, limit int) ([]model.Application, error) {
+	var applications []model.Application
+	query := r.db.WithContext(ctx).Model(&model.Application{}).
+		Joins("JOIN users ON users.id = applications.user_id").
+		Where("applications.status = ?", status).
+		Where("applications.updated_at < ?", before).
+		Where("applications.purged_at IS NULL").
+		Where("users.legal_hold = ?", false).
+		Order("applications.id ASC").
+		Limit(limit)
+
+	if afterID != "" {
+		query = query.Where("applications.id > ?", afterID)
+	}
+
+	if err := query.Find(&applications).Error; err != nil {
+		return nil, err
+	}
+
+	return applications, nil
+}
+
+// IsResumeShared reports whether an application other than applicationID which isn't purged has the resume.
+// Candidates can submit the same resume to several jobs.
+func (r *RetentionRepository) IsResumeShared(ctx context.Context, objectKey string, applicationID string) (bool, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&model.Application{}).
+		Where("resume_object_key = ? AND id <> ? AND purged_at IS NULL", objectKey, applicationID).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// AnonymizeApplication clears the resume of an application, the reasons of its status changes, which may be
+// rendered with the candidate's name, and the webhook deliveries of its events, and records a tombstone. The
+// candidate is anonymized as well once all of their applications are anonymized. Job and status are kept for
+// statistics.
+func (r *RetentionRepository) AnonymizeApplication(ctx context.Context, application model.Application, reason string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		if err := tx.Model(&model.Application{}).Where("id = ? AND purged_at IS NULL", application.ID).Updates(map[string]interface{}{
+			"resume_object_key": "",
+			"purged_at":         now,
+			"version":           gorm.Expr("version + 1"),
+		}).Error; err != nil {
+			return err
+		}
+
+		if err := scrubStatusReasons(tx, application.ID); err != nil {
+			return err
+		}
+
+		if err := scrubWebhookDeliveries(tx, application.ID); err != nil {
+			return err
+		}
+
+		if err := tx.Create(&model.Tombstone{
+			SubjectType: model.TombstoneSubjectApplication,
+			SubjectID:   application.ID,
+			Reason:      reason,
+			PurgedAt:    now,
+		}).Error; err != nil {
+			return err
+		}
+
+		if err := event.Record(tx, event.ApplicationPurged, event.AggregateApplication, application.ID, map[string]string{
+			"application_id": application.ID,
+			"job_id":         application.JobID,
+			"reason":         reason,
+		}); err != nil {
+			return err
+		}
+
+		var remaining int64
+		if err := tx.Model(&model.Application{}).Where("user_id = ? AND purged_at IS NULL", application.UserID).Count(&remaining).Error; err != nil {
+			return err
+		}
+		if remaining > 0 {
+			return nil
+		}
+
+		return anonymizeUser(tx, application.UserID, reason, now)
+	})
+}
+
+// AnonymizeUser anonymizes a user and records a tombstone, it's a no-op when the user is already anonymized.
+func (r *RetentionRepository) AnonymizeUser(ctx context.Context, userID string, reason string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return anonymizeUser(tx, userID, reason, time.Now())
+	})
+}
+
+func anonymizeUser(tx *gorm.DB, userID string, reason string, now time.Time) error {
+	result := tx.Model(&model.User{}).Where("id = ? AND anonymized_at IS NULL", userID).Updates(map[string]interface{}{
+		"totp_secret":   "",
+		"email":         "",
+		"name":          "",
+		"anonymized_at": now,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+
+	return tx.Create(&model.Tombstone{
+		SubjectType: model.TombstoneSubjectUser,
+		SubjectID:   userID,
+		Reason:      reason,
+		PurgedAt:    now,
+	}).Error
+}
+
+// scrubStatusReasons clears the reasons of the status changes of an application, along with the copies in the
+// payloads of its events.
+func scrubStatusReasons(tx *gorm.DB, applicationID string) error {
+	if err := tx.Model(&model.ApplicationStatusHistory{}).Where("application_id = ? AND reason <> ''", applicationID).
+		UpdateColumn("reason", "").Error; err != nil {
+		return err
+	}
+
+	return tx.Model(&model.OutboxEvent{}).
+		Where("aggregate_type = ? AND aggregate_id = ? AND type = ?", event.AggregateApplication, applicationID, event.ApplicationStatusChanged).
+		UpdateColumn("payload", gorm.Expr("JSON_REMOVE(payload, '$.reason')")).Error
+}
+
+// scrubWebhookDeliveries clears the requests and responses of the webhook deliveries of an application's events,
+// they're copies of the event payloads.
+func scrubWebhookDeliveries(tx *gorm.DB, applicationID string) error {
+	return tx.Model(&model.WebhookDelivery{}).
+		Where("aggregate_type = ? AND aggregate_id = ? AND request_body <> ''", event.AggregateApplication, applicationID).
+		UpdateColumns(map[string]interface{}{
+			"request_body":  "",
+			"response_body": "",
+		}).Error
+}
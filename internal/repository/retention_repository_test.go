@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/rs/zerolog"
+
+	"fliqt/internal/event"
+	"fliqt/internal/model"
+	"fliqt/internal/util"
+)
+
+func TestRetentionRepositoryListExpiredApplications(t *testing.T) {
+	db, mock, cleanup := util.SetupMockDB(t)
+	defer cleanup()
+
+	logger := zerolog.New(zerolog.ConsoleWriter{Out: nil})
+
+	repo := NewRetentionRepository(db, &logger)
+	before := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery("SELECT `applications`\\.`id`,.* FROM `applications` JOIN users ON users.id = applications.user_id WHERE applications.status = \\? AND applications.updated_at < \\? AND applications.purged_at IS NULL AND users.legal_hold = \\? AND applications.id > \\? AND `applications`\\.`deleted_at` IS NULL ORDER BY applications.id ASC LIMIT \\?").
+		WithArgs("rejected", before, false, "cqanbg8cvavjpljmh7pg", 100).
+		WillReturnRows(
+			sqlmock.NewRows([]string{"id", "user_id", "status", "resume_object_key"}).
+				AddRow("cqanbg8cvavjpljmh7ph", "1", "rejected", "1/resume"),
+		)
+
+	applications, err := repo.ListExpiredApplications(context.TODO(), "rejected", before, "cqanbg8cvavjpljmh7pg", 100)
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+
+	if len(applications) != 1 || applications[0].ResumeObjectKey != "1/resume" {
+		t.Errorf("Expected one expired application, got %+v", applications)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRetentionRepositoryIsResumeShared(t *testing.T) {
+	db, mock, cleanup := util.SetupMockDB(t)
+	defer cleanup()
+
+	logger := zerolog.New(zerolog.ConsoleWriter{Out: nil})
+
+	repo := NewRetentionRepository(db, &logger)
+
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `applications` WHERE \\(resume_object_key = \\? AND id <> \\? AND purged_at IS NULL\\) AND `applications`\\.`deleted_at` IS NULL").
+		WithArgs("1/resume", "cqanbg8cvavjpljmh7pg").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	shared, err := repo.IsResumeShared(context.TODO(), "1/resume", "cqanbg8cvavjpljmh7pg")
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if !shared {
+		t.Errorf("Expected the resume to be shared")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRetentionRepositoryAnonymizeApplication(t *testing.T) {
+	db, mock, cleanup := util.SetupMockDB(t)
+	defer cleanup()
+
+	logger := zerolog.New(zerolog.ConsoleWriter{Out: nil})
+
+	repo := NewRetentionRepository(db, &logger)
+	application := model.Application{Base: model.Base{ID: "cqanbg8cvavjpljmh7pg"}, JobID: "cqanbg8cvavjpljmh7ph", UserID: "1"}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `applications` SET").WillReturnResult(sqlmock.NewResult(0, 1))
+	// Reasons may be rendered with the candidate's name
+	mock.ExpectExec("UPDATE `application_status_histories` SET `reason`=\\? WHERE \\(application_id = \\? AND reason <> ''\\)").
+		WithArgs("", application.ID).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("UPDATE `outbox` SET `payload`=JSON_REMOVE\\(payload, '\\$.reason'\\) WHERE \\(aggregate_type = \\? AND aggregate_id = \\? AND type = \\?\\)").
+		WithArgs(event.AggregateApplication, application.ID, event.ApplicationStatusChanged).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("UPDATE `webhook_deliveries` SET `request_body`=\\?,`response_body`=\\? WHERE \\(aggregate_type = \\? AND aggregate_id = \\? AND request_body <> ''\\)").
+		WithArgs("", "", event.AggregateApplication, application.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO `tombstones`").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO `outbox`").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `applications`").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectCommit()
+
+	if err := repo.AnonymizeApplication(context.TODO(), application, "erasure"); err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"fliqt/internal/model"
+
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+)
+
+type UserRepository struct {
+	db     *gorm.DB
+	logger *zerolog.Logger
+}
+
+func NewUserRepository(
+	db *gorm.DB,
+	logger *zerolog.Logger,
+) *UserRepository {
+	return &UserRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// GetUserByID returns a user by its ID
+func (r *UserRepository) GetUserByID(ctx context.Context, ID string) (*model.User, error) {
+	var user model.User
+	if err := r.db.WithContext(ctx).Where("id = ?", ID).First(&user).Error; err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// ListUsersByRole returns users of a role, anonymized users aren't returned
+func (r *UserRepository) ListUsersByRole(ctx context.Context, role model.UserRole) ([]model.User, error) {
+	var users []model.User
+	if err := r.db.WithContext(ctx).Where("role = ? AND anonymized_at IS NULL", role).Order("id ASC").Find(&users).Error; err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+type LegalHoldDTO struct {
+	LegalHold bool   `json:"legal_hold"`
+	Reason    string `json:"reason" binding:"required_if=LegalHold true,max=255"`
+}
+
+// SetLegalHold places a candidate on legal hold or releases them
+func (r *UserRepository) SetLegalHold(ctx context.Context, ID string, dto LegalHoldDTO) (*model.User, error) {
+	result := r.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", ID).Updates(map[string]interface{}{
+		"legal_hold":        dto.LegalHold,
+		"legal_hold_reason": dto.Reason,
+	})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return r.GetUserByID(ctx, ID)
+}
@@ -0,0 +1,212 @@
+package repository
+
+import (
+	"context"
+	"fliqt/internal/apperror"
+	"fliqt/internal/model"
+	"time"
+
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WebhookRepository struct {
+	db     *gorm.DB
+	logger *zerolog.Logger
+}
+
+func NewWebhookRepository(
+	db *gorm.DB,
+	logger *zerolog.Logger,
+) *WebhookRepository {
+	return &WebhookRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+type CreateWebhookDTO struct {
+	URL        string   `json:"url" binding:"required,url,max=2048"`
+	EventTypes []string `json:"event_types"`
+	Secret     string   `json:"secret" binding:"omitempty,min=16,max=128"`
+	CreatedBy  string   `json:"-"`
+}
+
+// CreateWebhook creates a new webhook
+func (r *WebhookRepository) CreateWebhook(ctx context.Context, dto CreateWebhookDTO) (*model.Webhook, error) {
+	webhook := model.Webhook{
+		URL:        dto.URL,
+		EventTypes: model.StringList(dto.EventTypes),
+		Secret:     dto.Secret,
+		Enabled:    true,
+		CreatedBy:  dto.CreatedBy,
+		Version:    1,
+	}
+
+	if err := r.db.WithContext(ctx).Create(&webhook).Error; err != nil {
+		return nil, err
+	}
+
+	return &webhook, nil
+}
+
+// GetWebhookByID returns a webhook by its ID
+func (r *WebhookRepository) GetWebhookByID(ctx context.Context, ID string) (*model.Webhook, error) {
+	var webhook model.Webhook
+	if err := r.db.WithContext(ctx).Where("id = ?", ID).First(&webhook).Error; err != nil {
+		return nil, err
+	}
+
+	return &webhook, nil
+}
+
+// ListWebhooks returns every webhook
+func (r *WebhookRepository) ListWebhooks(ctx context.Context) ([]model.Webhook, error) {
+	var webhooks []model.Webhook
+	if err := r.db.WithContext(ctx).Order("id ASC").Find(&webhooks).Error; err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+// ListEnabledWebhooks returns webhooks which receive events
+func (r *WebhookRepository) ListEnabledWebhooks(ctx context.Context) ([]model.Webhook, error) {
+	var webhooks []model.Webhook
+	if err := r.db.WithContext(ctx).Where("enabled = ?", true).Order("id ASC").Find(&webhooks).Error; err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+type UpdateWebhookDTO struct {
+	URL        string   `json:"url" binding:"required,url,max=2048"`
+	EventTypes []string `json:"event_types"`
+	Enabled    bool     `json:"enabled"`
+}
+
+// UpdateWebhook updates a webhook, enabling it again resets its failures. It fails with ErrPreconditionFailed
+// unless the webhook is at one of versions, nil versions update any version.
+func (r *WebhookRepository) UpdateWebhook(ctx context.Context, ID string, dto UpdateWebhookDTO, versions []int64) (*model.Webhook, error) {
+	columns := map[string]interface{}{
+		"url":         dto.URL,
+		"event_types": model.StringList(dto.EventTypes),
+		"enabled":     dto.Enabled,
+		"version":     gorm.Expr("version + 1"),
+	}
+
+	if dto.Enabled {
+		columns["consecutive_failures"] = 0
+		columns["disabled_at"] = nil
+	}
+
+	if err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var webhook model.Webhook
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", ID).First(&webhook).Error; err != nil {
+			return err
+		}
+		if !model.VersionMatches(webhook.Version, versions) {
+			return apperror.ErrPreconditionFailed
+		}
+
+		return tx.Model(&model.Webhook{}).Where("id = ?", ID).Updates(columns).Error
+	}); err != nil {
+		return nil, err
+	}
+
+	return r.GetWebhookByID(ctx, ID)
+}
+
+// DeleteWebhook deletes a webhook
+func (r *WebhookRepository) DeleteWebhook(ctx context.Context, ID string) error {
+	return r.db.WithContext(ctx).Where("id = ?", ID).Delete(&model.Webhook{}).Error
+}
+
+// CountDeliveries returns how many times an event was delivered to a webhook
+func (r *WebhookRepository) CountDeliveries(ctx context.Context, webhookID string, eventID string) (attempts int64, succeeded bool, err error) {
+	var result struct {
+		Attempts  int64
+		Succeeded bool
+	}
+
+	if err := r.db.WithContext(ctx).Model(&model.WebhookDelivery{}).
+		Select("COUNT(*) AS attempts, COALESCE(MAX(succeeded), false) AS succeeded").
+		Where("webhook_id = ? AND event_id = ?", webhookID, eventID).
+		Scan(&result).Error; err != nil {
+		return 0, false, err
+	}
+
+	return result.Attempts, result.Succeeded, nil
+}
+
+// RecordDelivery saves a delivery and updates the failures of its webhook, the webhook is disabled
+// once it fails maxFailures times in a row. Manual deliveries don't count.
+func (r *WebhookRepository) RecordDelivery(ctx context.Context, delivery *model.WebhookDelivery, maxFailures int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(delivery).Error; err != nil {
+			return err
+		}
+
+		if delivery.Manual {
+			return nil
+		}
+
+		if delivery.Succeeded {
+			return tx.Model(&model.Webhook{}).Where("id = ?", delivery.WebhookID).UpdateColumn("consecutive_failures", 0).Error
+		}
+
+		if err := tx.Model(&model.Webhook{}).Where("id = ?", delivery.WebhookID).
+			UpdateColumn("consecutive_failures", gorm.Expr("consecutive_failures + 1")).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&model.Webhook{}).
+			Where("id = ? AND enabled = ? AND consecutive_failures >= ?", delivery.WebhookID, true, maxFailures).
+			UpdateColumns(map[string]interface{}{
+				"enabled":     false,
+				"disabled_at": time.Now(),
+				"version":     gorm.Expr("version + 1"),
+			}).Error
+	})
+}
+
+// GetDelivery returns a delivery of a webhook
+func (r *WebhookRepository) GetDelivery(ctx context.Context, webhookID string, ID string) (*model.WebhookDelivery, error) {
+	var delivery model.WebhookDelivery
+	if err := r.db.WithContext(ctx).Where("webhook_id = ? AND id = ?", webhookID, ID).First(&delivery).Error; err != nil {
+		return nil, err
+	}
+
+	return &delivery, nil
+}
+
+type WebhookDeliveryFilterParams struct {
+	model.PaginationParams
+
+	EventID string `form:"event_id,omitempty"`
+}
+
+// ListDeliveries returns the delivery history of a webhook, latest first unless it's sorted by created_at
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, webhookID string, filterParams WebhookDeliveryFilterParams) (model.PaginationResponse[model.WebhookDelivery], error) {
+	query := r.db.WithContext(ctx).Model(&model.WebhookDelivery{}).Where("webhook_id = ?", webhookID)
+
+	if filterParams.EventID != "" {
+		query = query.Where("event_id = ?", filterParams.EventID)
+	}
+
+	sort, err := model.ParseSort(filterParams.Sort, map[string]model.SortKey{
+		"created_at": {Column: "created_at", Kind: model.SortKindTime},
+	}, "id")
+	if err != nil {
+		return model.PaginationResponse[model.WebhookDelivery]{}, err
+	}
+
+	return model.Paginate(query, filterParams.PaginationParams, sort, func(delivery model.WebhookDelivery, key string) interface{} {
+		if key == "created_at" {
+			return delivery.CreatedAt
+		}
+		return delivery.ID
+	})
+}
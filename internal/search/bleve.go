@@ -0,0 +1,403 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fliqt/config"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/analysis/lang/cjk"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/search/highlight/format/html"
+	"github.com/blevesearch/bleve/v2/search/query"
+
+	"fliqt/internal/i18n"
+	"fliqt/internal/model"
+)
+
+const (
+	bleveKindJob         = "job"
+	bleveKindTranslation = "translation"
+
+	// bleveOpenTimeout is how long opening an index waits for another process to close it
+	bleveOpenTimeout = "5s"
+	// minFuzzyWordLength is the length of the shortest words which match with typos, shorter words would match
+	// too many others.
+	minFuzzyWordLength = 4
+	// exactMatchBoost ranks exact matches above those with typos or synonyms
+	exactMatchBoost = 2
+
+	// bleveMappingVersion is bumped whenever jobIndexMapping or the indexed fields change, indexes of another
+	// version are built again from scratch.
+	bleveMappingVersion    = "2"
+	bleveMappingVersionKey = "mapping_version"
+)
+
+// bleveFieldBoosts are the searched fields of jobs and translations, and how much a match of each counts.
+var bleveFieldBoosts = map[string]float64{"title": 3, "company": 2, "description": 1}
+
+// BleveJobSearchIndex searches jobs with an embedded Bleve index. A job and each of its translations are
+// documents of their own, the ID of a translation is the job ID followed by /locale. Translations have the
+// filtered fields of their job, so they're filtered alike.
+type BleveJobSearchIndex struct {
+	index     bleve.Index
+	maxHits   int
+	fuzziness int
+	synonyms  map[string][]string
+}
+
+// OpenBleveJobSearchIndex opens the index at cfg.SearchIndexPath, it's created when it doesn't exist yet or has
+// another mapping version, so it's empty and has to be rebuilt. The index is kept in memory when the path is
+// empty. An index can only be opened by one process at a time.
+func OpenBleveJobSearchIndex(cfg *config.Config) (*BleveJobSearchIndex, error) {
+	var index bleve.Index
+	var err error
+	if cfg.SearchIndexPath == "" {
+		index, err = newBleveIndex(bleve.NewMemOnly(jobIndexMapping()))
+	} else {
+		index, err = bleve.OpenUsing(cfg.SearchIndexPath, map[string]interface{}{"bolt_timeout": bleveOpenTimeout})
+		if err == nil {
+			err = checkMappingVersion(index, cfg.SearchIndexPath)
+		}
+		if errors.Is(err, bleve.ErrorIndexPathDoesNotExist) {
+			index, err = newBleveIndex(bleve.New(cfg.SearchIndexPath, jobIndexMapping()))
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &BleveJobSearchIndex{
+		index:     index,
+		maxHits:   cfg.SearchMaxHits,
+		fuzziness: cfg.SearchFuzziness,
+		synonyms:  ParseSynonyms(cfg.SearchSynonyms),
+	}, nil
+}
+
+func newBleveIndex(index bleve.Index, err error) (bleve.Index, error) {
+	if err != nil {
+		return nil, err
+	}
+	if err := index.SetInternal([]byte(bleveMappingVersionKey), []byte(bleveMappingVersion)); err != nil {
+		index.Close()
+		return nil, err
+	}
+
+	return index, nil
+}
+
+// checkMappingVersion removes an index of another mapping version, it returns ErrorIndexPathDoesNotExist then
+// so a new one is created.
+func checkMappingVersion(index bleve.Index, path string) error {
+	version, err := index.GetInternal([]byte(bleveMappingVersionKey))
+	if err != nil {
+		index.Close()
+		return err
+	}
+	if string(version) == bleveMappingVersion {
+		return nil
+	}
+
+	if err := index.Close(); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(path); err != nil {
+		return err
+	}
+
+	return bleve.ErrorIndexPathDoesNotExist
+}
+
+// jobIndexMapping analyzes text with the CJK analyzer, which splits Chinese and Japanese into bigrams and
+// other languages into words. Text is stored, so matches can be highlighted.
+func jobIndexMapping() mapping.IndexMapping {
+	text := bleve.NewTextFieldMapping()
+	text.Analyzer = cjk.AnalyzerName
+	text.IncludeTermVectors = true
+
+	keyword := bleve.NewKeywordFieldMapping()
+	keyword.Store = false
+
+	number := bleve.NewNumericFieldMapping()
+	number.Store = false
+
+	document := bleve.NewDocumentStaticMapping()
+	document.AddFieldMappingsAt("kind", keyword)
+	document.AddFieldMappingsAt("locale", keyword)
+	// Filtered fields, company is searched as text as well
+	document.AddFieldMappingsAt("job_type", keyword)
+	document.AddFieldMappingsAt("company_keyword", keyword)
+	document.AddFieldMappingsAt("location", keyword)
+	document.AddFieldMappingsAt("salary_min", number)
+	document.AddFieldMappingsAt("salary_max", number)
+	for field := range bleveFieldBoosts {
+		document.AddFieldMappingsAt(field, text)
+	}
+
+	indexMapping := bleve.NewIndexMapping()
+	indexMapping.DefaultMapping = document
+	indexMapping.DefaultAnalyzer = cjk.AnalyzerName
+
+	return indexMapping
+}
+
+// ParseSynonyms parses groups of synonyms separated by semicolons, words of a group are separated by commas,
+// e.g. "js,javascript;k8s,kubernetes". Words are looked up in lowercase.
+func ParseSynonyms(s string) map[string][]string {
+	synonyms := map[string][]string{}
+	for _, group := range strings.Split(s, ";") {
+		var words []string
+		for _, word := range strings.Split(group, ",") {
+			if word = strings.ToLower(strings.TrimSpace(word)); word != "" {
+				words = append(words, word)
+			}
+		}
+
+		for _, word := range words {
+			for _, synonym := range words {
+				if synonym != word {
+					synonyms[word] = append(synonyms[word], synonym)
+				}
+			}
+		}
+	}
+
+	return synonyms
+}
+
+// Search finds the maxHits best matching jobs, or every one of them when request.All is set. A job scores as its
+// best matching document.
+func (i *BleveJobSearchIndex) Search(ctx context.Context, request JobSearchRequest) (*JobSearch, error) {
+	// Translations in other languages than the requested ones aren't searched
+	otherTranslations := bleve.NewBooleanQuery()
+	otherTranslations.AddMust(termQuery("kind", bleveKindTranslation))
+	for _, locale := range request.Locales {
+		otherTranslations.AddMustNot(termQuery("locale", locale))
+	}
+
+	q := bleve.NewBooleanQuery()
+	q.AddMust(i.keywordQuery(request.Keyword))
+	q.AddMust(filterQueries(request.Filters)...)
+	q.AddMustNot(otherTranslations)
+
+	searchRequest := bleve.NewSearchRequestOptions(q, i.maxHits, 0, false)
+	// Every match is paged through in the same order, the document ID breaks ties of scores. Scores can't be
+	// searched after, so pages are fetched by offset.
+	searchRequest.SortBy([]string{"-_score", "_id"})
+	if request.Highlight {
+		searchRequest.Highlight = bleve.NewHighlightWithStyle(html.Name)
+		for field := range bleveFieldBoosts {
+			searchRequest.Highlight.AddField(field)
+		}
+	}
+
+	search := &JobSearch{
+		Condition: "1 = 0",
+		Relevance: "0",
+		Scores:    map[string]float64{},
+	}
+	if request.Highlight {
+		search.Highlights = map[string]map[string][]string{}
+	}
+	var jobIDs []interface{}
+	for {
+		result, err := i.index.SearchInContext(ctx, searchRequest)
+		if err != nil {
+			return nil, err
+		}
+
+		// Hits are sorted by score, so the first hit of a job is its best one
+		for _, hit := range result.Hits {
+			jobID, _, _ := strings.Cut(hit.ID, "/")
+			if _, ok := search.Scores[jobID]; ok {
+				continue
+			}
+
+			search.Scores[jobID] = hit.Score
+			if request.Highlight {
+				search.Highlights[jobID] = hit.Fragments
+			}
+			jobIDs = append(jobIDs, jobID)
+		}
+
+		if !request.All || len(result.Hits) < i.maxHits {
+			break
+		}
+		searchRequest.From += len(result.Hits)
+	}
+	if len(jobIDs) == 0 {
+		return search, nil
+	}
+
+	search.Condition = "jobs.id IN ?"
+	search.Args = []interface{}{jobIDs}
+	// Every match would make the expression too long, their scores are only sorted by when they're ranked
+	if !request.All {
+		var relevance strings.Builder
+		for _, jobID := range jobIDs {
+			relevance.WriteString(" WHEN ? THEN ?")
+			search.RelevanceArgs = append(search.RelevanceArgs, jobID, search.Scores[jobID.(string)])
+		}
+		search.Relevance = "CASE jobs.id" + relevance.String() + " ELSE 0 END"
+	}
+
+	return search, nil
+}
+
+// filterQueries match the documents of jobs with the values of filters.
+func filterQueries(filters JobSearchFilters) []query.Query {
+	var queries []query.Query
+	if filters.JobType != "" {
+		queries = append(queries, termQuery("job_type", filters.JobType))
+	}
+	if filters.Company != "" {
+		queries = append(queries, termQuery("company_keyword", filters.Company))
+	}
+	if filters.Location != "" {
+		queries = append(queries, termQuery("location", filters.Location))
+	}
+	inclusive := true
+	if filters.SalaryMin != 0 {
+		min := float64(filters.SalaryMin)
+		q := bleve.NewNumericRangeInclusiveQuery(&min, nil, &inclusive, nil)
+		q.SetField("salary_min")
+		queries = append(queries, q)
+	}
+	if filters.SalaryMax != 0 {
+		max := float64(filters.SalaryMax)
+		q := bleve.NewNumericRangeInclusiveQuery(nil, &max, nil, &inclusive)
+		q.SetField("salary_max")
+		queries = append(queries, q)
+	}
+
+	return queries
+}
+
+// keywordQuery matches the keyword and its synonyms exactly, and its words with typos when they're long enough.
+// Words are matched with OR as in the natural language mode of MySQL.
+func (i *BleveJobSearchIndex) keywordQuery(keyword string) query.Query {
+	phrases := []string{keyword}
+	phrases = append(phrases, i.synonyms[strings.ToLower(strings.TrimSpace(keyword))]...)
+	words := strings.Fields(strings.ToLower(keyword))
+	if len(words) > 1 {
+		for _, word := range words {
+			phrases = append(phrases, i.synonyms[word]...)
+		}
+	}
+
+	var alternatives []query.Query
+	for _, phrase := range phrases {
+		for field, boost := range bleveFieldBoosts {
+			exact := bleve.NewMatchQuery(phrase)
+			exact.SetField(field)
+			exact.SetBoost(boost * exactMatchBoost)
+			alternatives = append(alternatives, exact)
+		}
+	}
+
+	for _, word := range words {
+		if i.fuzziness == 0 || !fuzzyMatchable(word) {
+			continue
+		}
+		for field, boost := range bleveFieldBoosts {
+			fuzzy := bleve.NewMatchQuery(word)
+			fuzzy.SetField(field)
+			fuzzy.SetFuzziness(i.fuzziness)
+			fuzzy.SetBoost(boost)
+			alternatives = append(alternatives, fuzzy)
+		}
+	}
+
+	return bleve.NewDisjunctionQuery(alternatives...)
+}
+
+// fuzzyMatchable reports whether a word is long enough to match with typos. Chinese and Japanese are indexed
+// as bigrams, which a typo would change entirely.
+func fuzzyMatchable(word string) bool {
+	if utf8.RuneCountInString(word) < minFuzzyWordLength {
+		return false
+	}
+	for _, r := range word {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func termQuery(field, term string) query.Query {
+	q := bleve.NewTermQuery(term)
+	q.SetField(field)
+	return q
+}
+
+func (i *BleveJobSearchIndex) IndexJobs(ctx context.Context, documents []JobDocument) error {
+	batch := i.index.NewBatch()
+	for _, document := range documents {
+		job := document.Job
+		// Translations which were deleted are replaced by nothing
+		deleteJob(batch, job.ID)
+
+		fields := jobFilterFields(job)
+		fields["kind"] = bleveKindJob
+		fields["locale"] = job.Locale
+		fields["title"] = job.Title
+		fields["company"] = job.Company
+		fields["description"] = job.Description
+		if err := batch.Index(job.ID, fields); err != nil {
+			return err
+		}
+		for _, translation := range document.Translations {
+			fields := jobFilterFields(job)
+			fields["kind"] = bleveKindTranslation
+			fields["locale"] = translation.Locale
+			fields["title"] = translation.Title
+			fields["description"] = translation.Description
+			if err := batch.Index(job.ID+"/"+translation.Locale, fields); err != nil {
+				return err
+			}
+		}
+	}
+
+	return i.index.Batch(batch)
+}
+
+// jobFilterFields are the fields of a job which searches are filtered by.
+func jobFilterFields(job model.Job) map[string]interface{} {
+	return map[string]interface{}{
+		"job_type":        string(job.JobType),
+		"company_keyword": job.Company,
+		"location":        job.Location,
+		"salary_min":      job.SalaryMin,
+		"salary_max":      job.SalaryMax,
+	}
+}
+
+func (i *BleveJobSearchIndex) DeleteJob(ctx context.Context, jobID string) error {
+	batch := i.index.NewBatch()
+	deleteJob(batch, jobID)
+
+	return i.index.Batch(batch)
+}
+
+func deleteJob(batch *bleve.Batch, jobID string) {
+	batch.Delete(jobID)
+	for _, locale := range i18n.SupportedLocales {
+		batch.Delete(jobID + "/" + locale)
+	}
+}
+
+// DocCount returns the number of indexed documents, jobs and translations alike.
+func (i *BleveJobSearchIndex) DocCount() (uint64, error) {
+	return i.index.DocCount()
+}
+
+func (i *BleveJobSearchIndex) Close() error {
+	return i.index.Close()
+}
@@ -0,0 +1,231 @@
+package search
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"fliqt/config"
+	"fliqt/internal/model"
+)
+
+func TestParseSynonyms(t *testing.T) {
+	synonyms := ParseSynonyms(" JS, javascript ;k8s,kubernetes;;lonely")
+
+	expected := map[string][]string{
+		"js":         {"javascript"},
+		"javascript": {"js"},
+		"k8s":        {"kubernetes"},
+		"kubernetes": {"k8s"},
+	}
+	if !reflect.DeepEqual(synonyms, expected) {
+		t.Errorf("Expected %v, got %v", expected, synonyms)
+	}
+}
+
+func TestFuzzyMatchable(t *testing.T) {
+	for word, expected := range map[string]bool{"engineer": true, "java": true, "js": false, "工程師們": false} {
+		if fuzzyMatchable(word) != expected {
+			t.Errorf("Expected %q to be fuzzy matchable: %v", word, expected)
+		}
+	}
+}
+
+func newTestBleveIndex(t *testing.T, maxHits int) *BleveJobSearchIndex {
+	index, err := OpenBleveJobSearchIndex(&config.Config{SearchMaxHits: maxHits, SearchFuzziness: 1, SearchSynonyms: "js,javascript"})
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	t.Cleanup(func() { index.Close() })
+
+	if err := index.IndexJobs(context.TODO(), []JobDocument{
+		{Job: model.Job{Base: model.Base{ID: "backend"}, Title: "Backend Engineer", Company: "fliQt", JobType: model.JobTypeFullTime, Location: "Taipei", SalaryMin: 60000, SalaryMax: 90000, Locale: "en"}},
+		{Job: model.Job{Base: model.Base{ID: "frontend"}, Title: "Frontend Developer", Company: "fliQt", Description: "React and JavaScript", JobType: model.JobTypeContract, Locale: "en"}},
+		{
+			Job:          model.Job{Base: model.Base{ID: "designer"}, Title: "Product Designer", Company: "fliQt", JobType: model.JobTypePartTime, Location: "Tokyo", Locale: "en"},
+			Translations: []model.JobTranslation{{JobID: "designer", Locale: "zh-TW", Title: "產品設計師"}},
+		},
+	}); err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+
+	return index
+}
+
+func searchJobIDs(t *testing.T, index *BleveJobSearchIndex, keyword string, locales ...string) []interface{} {
+	return searchJobIDsWith(t, index, JobSearchRequest{Keyword: keyword, Locales: locales})
+}
+
+func searchJobIDsWith(t *testing.T, index *BleveJobSearchIndex, request JobSearchRequest) []interface{} {
+	search, err := index.Search(context.TODO(), request)
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if len(search.Args) == 0 {
+		return nil
+	}
+
+	return search.Args[0].([]interface{})
+}
+
+func TestBleveJobSearchIndexSearch(t *testing.T) {
+	index := newTestBleveIndex(t, 100)
+
+	for _, tc := range []struct {
+		name     string
+		keyword  string
+		locales  []string
+		expected []interface{}
+	}{
+		{"exact", "engineer", []string{"en"}, []interface{}{"backend"}},
+		{"typo", "enginer", []string{"en"}, []interface{}{"backend"}},
+		{"synonym", "js", []string{"en"}, []interface{}{"frontend"}},
+		{"translation", "設計", []string{"zh-TW", "en"}, []interface{}{"designer"}},
+		{"other translations", "設計", []string{"ja", "en"}, nil},
+		{"no match", "accountant", []string{"en"}, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if IDs := searchJobIDs(t, index, tc.keyword, tc.locales...); !reflect.DeepEqual(IDs, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, IDs)
+			}
+		})
+	}
+}
+
+func TestBleveJobSearchIndexSearchSQL(t *testing.T) {
+	index := newTestBleveIndex(t, 100)
+
+	search, err := index.Search(context.TODO(), JobSearchRequest{Keyword: "engineer", Locales: []string{"en"}, Highlight: true})
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if search.Condition != "jobs.id IN ?" || search.Relevance != "CASE jobs.id WHEN ? THEN ? ELSE 0 END" {
+		t.Errorf("Unexpected SQL %q and %q", search.Condition, search.Relevance)
+	}
+	if len(search.RelevanceArgs) != 2 || search.RelevanceArgs[0] != "backend" {
+		t.Errorf("Expected the score of the job, got %v", search.RelevanceArgs)
+	}
+	if highlights := search.Highlights["backend"]["title"]; len(highlights) != 1 || highlights[0] != "Backend <mark>Engineer</mark>" {
+		t.Errorf("Expected the title to be highlighted, got %v", search.Highlights)
+	}
+
+	search, err = index.Search(context.TODO(), JobSearchRequest{Keyword: "accountant", Locales: []string{"en"}})
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if search.Condition != "1 = 0" {
+		t.Errorf("Expected no job to match, got %q", search.Condition)
+	}
+}
+
+func TestBleveJobSearchIndexSearchFilters(t *testing.T) {
+	index := newTestBleveIndex(t, 100)
+
+	for _, tc := range []struct {
+		name     string
+		filters  JobSearchFilters
+		expected []interface{}
+	}{
+		{"job type", JobSearchFilters{JobType: "full-time"}, []interface{}{"backend"}},
+		{"location", JobSearchFilters{Location: "Taipei"}, []interface{}{"backend"}},
+		{"salary", JobSearchFilters{SalaryMin: 50000, SalaryMax: 90000}, []interface{}{"backend"}},
+		{"salary out of range", JobSearchFilters{SalaryMin: 70000}, nil},
+		{"company", JobSearchFilters{Company: "Other"}, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			IDs := searchJobIDsWith(t, index, JobSearchRequest{Keyword: "engineer", Locales: []string{"en"}, Filters: tc.filters})
+			if !reflect.DeepEqual(IDs, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, IDs)
+			}
+		})
+	}
+
+	// Translations are filtered as their job
+	IDs := searchJobIDsWith(t, index, JobSearchRequest{Keyword: "設計", Locales: []string{"zh-TW"}, Filters: JobSearchFilters{Location: "Tokyo"}})
+	if !reflect.DeepEqual(IDs, []interface{}{"designer"}) {
+		t.Errorf("Expected the translated job, got %v", IDs)
+	}
+}
+
+func TestBleveJobSearchIndexSearchAll(t *testing.T) {
+	index := newTestBleveIndex(t, 1)
+
+	if IDs := searchJobIDs(t, index, "fliqt", "en"); len(IDs) != 1 {
+		t.Errorf("Expected the best match, got %v", IDs)
+	}
+
+	// Every match is paged through, however many hits a page has
+	search, err := index.Search(context.TODO(), JobSearchRequest{Keyword: "fliqt", Locales: []string{"zh-TW", "en"}, All: true})
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if IDs := search.Args[0].([]interface{}); len(IDs) != 3 || len(search.Scores) != 3 {
+		t.Errorf("Expected every job, got %v", IDs)
+	}
+	if search.Relevance != "0" {
+		t.Errorf("Expected the scores to be left out of SQL, got %q", search.Relevance)
+	}
+}
+
+func TestBleveJobSearchIndexUpdates(t *testing.T) {
+	index := newTestBleveIndex(t, 100)
+
+	// The translation is deleted
+	if err := index.IndexJobs(context.TODO(), []JobDocument{
+		{Job: model.Job{Base: model.Base{ID: "designer"}, Title: "Product Designer", Company: "fliQt", Locale: "en"}},
+	}); err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if IDs := searchJobIDs(t, index, "設計", "zh-TW"); IDs != nil {
+		t.Errorf("Expected the translation to be removed, got %v", IDs)
+	}
+
+	if err := index.DeleteJob(context.TODO(), "backend"); err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if IDs := searchJobIDs(t, index, "engineer", "en"); IDs != nil {
+		t.Errorf("Expected the job to be removed, got %v", IDs)
+	}
+}
+
+func TestOpenBleveJobSearchIndex(t *testing.T) {
+	cfg := &config.Config{SearchIndexPath: filepath.Join(t.TempDir(), "search", "jobs.bleve"), SearchMaxHits: 100}
+
+	index, err := OpenBleveJobSearchIndex(cfg)
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if err := index.IndexJobs(context.TODO(), []JobDocument{{Job: model.Job{Base: model.Base{ID: "backend"}, Title: "Backend Engineer"}}}); err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if err := index.Close(); err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+
+	// The index is opened again rather than created
+	index, err = OpenBleveJobSearchIndex(cfg)
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	defer func() { index.Close() }()
+
+	if count, err := index.DocCount(); err != nil || count != 1 {
+		t.Errorf("Expected the indexed job, got %d %v", count, err)
+	}
+
+	// An index of another mapping version is created again, so it's rebuilt
+	if err := index.index.SetInternal([]byte(bleveMappingVersionKey), []byte("1")); err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if err := index.Close(); err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	index, err = OpenBleveJobSearchIndex(cfg)
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if count, err := index.DocCount(); err != nil || count != 0 {
+		t.Errorf("Expected an empty index, got %d %v", count, err)
+	}
+}
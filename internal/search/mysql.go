@@ -0,0 +1,37 @@
+package search
+
+import "context"
+
+// MySQLJobSearchIndex searches jobs with the full-text indexes of MySQL in natural language mode. MySQL keeps
+// them up to date along with the tables, so there's nothing to index. Every match is found, filters are left to
+// the rest of the query.
+type MySQLJobSearchIndex struct{}
+
+func NewMySQLJobSearchIndex() *MySQLJobSearchIndex {
+	return &MySQLJobSearchIndex{}
+}
+
+func (i *MySQLJobSearchIndex) Search(ctx context.Context, request JobSearchRequest) (*JobSearch, error) {
+	keyword, locales := request.Keyword, request.Locales
+	return &JobSearch{
+		Condition: "MATCH(jobs.title, jobs.company, jobs.description) AGAINST (?) OR jobs.id IN (" +
+			"SELECT job_id FROM job_translations WHERE locale IN ? AND deleted_at IS NULL AND MATCH(title, description) AGAINST (?))",
+		Args: []interface{}{keyword, locales, keyword},
+		Relevance: "GREATEST(MATCH(jobs.title, jobs.company, jobs.description) AGAINST (?), COALESCE((" +
+			"SELECT MAX(MATCH(job_translations.title, job_translations.description) AGAINST (?)) FROM job_translations " +
+			"WHERE job_translations.job_id = jobs.id AND job_translations.locale IN ? AND job_translations.deleted_at IS NULL), 0))",
+		RelevanceArgs: []interface{}{keyword, keyword, locales},
+	}, nil
+}
+
+func (i *MySQLJobSearchIndex) IndexJobs(ctx context.Context, documents []JobDocument) error {
+	return nil
+}
+
+func (i *MySQLJobSearchIndex) DeleteJob(ctx context.Context, jobID string) error {
+	return nil
+}
+
+func (i *MySQLJobSearchIndex) Close() error {
+	return nil
+}
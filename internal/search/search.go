@@ -0,0 +1,69 @@
+// Package search finds jobs by keyword, either with the full-text indexes of MySQL or with an embedded Bleve
+// index which is kept in sync from domain events.
+package search
+
+import (
+	"context"
+
+	"fliqt/internal/model"
+)
+
+const (
+	BackendMySQL = "mysql"
+	BackendBleve = "bleve"
+)
+
+// JobSearchRequest is a search of jobs by keyword in the requested locales.
+type JobSearchRequest struct {
+	Keyword string
+	Locales []string
+	// Filters are applied by backends which limit the number of matches, so the limit isn't spent on jobs which
+	// are filtered out anyway
+	Filters JobSearchFilters
+	// All finds every match rather than the best ones, for results which aren't ranked by relevance or aren't
+	// paginated
+	All bool
+	// Highlight asks for snippets of the fields which matched
+	Highlight bool
+}
+
+// JobSearchFilters narrow a search down to jobs with these values, zero values don't filter.
+type JobSearchFilters struct {
+	JobType   string
+	Company   string
+	Location  string
+	SalaryMin int
+	SalaryMax int
+}
+
+// JobSearch narrows a query of jobs down to the ones which match a keyword, and ranks them.
+type JobSearch struct {
+	// Condition with Args is the SQL condition of the matching jobs
+	Condition string
+	Args      []interface{}
+	// Relevance with RelevanceArgs is the SQL expression of the score of a job
+	Relevance     string
+	RelevanceArgs []interface{}
+	// Scores are the scores of the matching jobs by ID, for backends whose Relevance doesn't tell them
+	Scores map[string]float64
+	// Highlights are snippets of the fields which matched by job ID, matches are wrapped in <mark> and the rest
+	// is escaped as HTML. Backends which can't highlight leave it nil.
+	Highlights map[string]map[string][]string
+}
+
+// JobDocument is a job along with its translations, as they're indexed.
+type JobDocument struct {
+	Job          model.Job
+	Translations []model.JobTranslation
+}
+
+// JobSearchIndex finds jobs by keyword in their own language and in their translations of the requested
+// locales.
+type JobSearchIndex interface {
+	Search(ctx context.Context, request JobSearchRequest) (*JobSearch, error)
+	// IndexJobs adds jobs or replaces them along with their translations
+	IndexJobs(ctx context.Context, documents []JobDocument) error
+	// DeleteJob removes a job along with its translations
+	DeleteJob(ctx context.Context, jobID string) error
+	Close() error
+}
@@ -1,18 +1,19 @@
 package service
 
 import (
-	"errors"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/pquerna/otp/totp"
 	"gorm.io/gorm"
 
+	"fliqt/internal/apperror"
 	"fliqt/internal/model"
 )
 
 var (
-	ErrUnauthorized = errors.New("unauthorized")
-	ErrFailedTOTP   = errors.New("failed to verify TOTP")
+	ErrUnauthorized = apperror.New(http.StatusUnauthorized, "unauthorized", "unauthorized")
+	ErrFailedTOTP   = apperror.New(http.StatusUnauthorized, "totp_failed", "failed to verify TOTP")
 )
 
 type AuthServiceInterface interface {
@@ -58,6 +59,7 @@ func (s *AuthService) CurrentUser(ctx *gin.Context) (*model.User, error) {
 
 func (s *AuthService) VerifyTOTP(ctx *gin.Context, secret string, passcode string) error {
 	if !totp.Validate(passcode, secret) {
+		totpFailureCounter.Add(ctx, 1)
 		return ErrFailedTOTP
 	}
 
@@ -0,0 +1,704 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fliqt/config"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+
+	"fliqt/internal/apperror"
+	"fliqt/internal/jobs"
+	"fliqt/internal/model"
+	"fliqt/internal/repository"
+	"fliqt/internal/util"
+	"fliqt/internal/xlsx"
+)
+
+// TaskBulkApplications is the background task which runs a bulk operation on applications
+const TaskBulkApplications = "applications.bulk"
+
+// TaskExpireExports is the recurring task which deletes exports older than ExportRetention
+const TaskExpireExports = "exports.expire"
+
+// expireExportsBatchSize is the number of expired exports loaded at once.
+const expireExportsBatchSize = 100
+
+// bulkFailuresLimit is the number of failed items returned along with an operation.
+const bulkFailuresLimit = 100
+
+var (
+	ErrBulkActionForbidden   = apperror.New(http.StatusForbidden, "bulk_action_forbidden", "only HR can act on applications in bulk")
+	ErrInvalidAssignee       = apperror.New(http.StatusBadRequest, "invalid_assignee", "the assignee must be an interviewer")
+	ErrInvalidReasonTemplate = apperror.New(http.StatusBadRequest, "invalid_reason_template", "the reason template is invalid")
+	ErrTooManyApplications   = apperror.New(http.StatusBadRequest, "too_many_applications", "the filter selects too many applications")
+	ErrApplicationPurged     = apperror.New(http.StatusConflict, "application_purged", "the application has been purged")
+)
+
+// BulkApplicationsPayload is the payload of TaskBulkApplications
+type BulkApplicationsPayload struct {
+	OperationID string `json:"operation_id"`
+}
+
+// BulkOperationReport is a bulk operation along with the items it failed on, and the link to its export.
+type BulkOperationReport struct {
+	*model.BulkOperation
+	Failures []model.ApplicationAuditLog `json:"failures"`
+	// DownloadURL is a short-lived link to the export for its creator, it works without signing in
+	DownloadURL       string     `json:"download_url,omitempty"`
+	DownloadExpiresAt *time.Time `json:"download_expires_at,omitempty"`
+}
+
+// BulkReasonData is the data of reason templates.
+type BulkReasonData struct {
+	CandidateName string
+	JobTitle      string
+	Company       string
+}
+
+type BulkApplicationServiceInterface interface {
+	// Start checks a bulk action of the user and enqueues it, it runs on the worker.
+	Start(ctx context.Context, actor *model.User, dto repository.BulkApplicationDTO) (*model.BulkOperation, error)
+	// Get returns the progress of an operation, only its creator and HR can see it. Links to exports are only
+	// handed to their creators.
+	Get(ctx context.Context, actor *model.User, ID string) (*BulkOperationReport, error)
+	// Run applies an operation to the applications it hasn't processed yet, it's safe to run again.
+	Run(ctx context.Context, ID string) error
+	// Fail marks an operation which can't be run anymore as failed.
+	Fail(ctx context.Context, ID string, err error) error
+	// OpenExport opens the file of a completed export through a signed link. The caller must close the body.
+	OpenExport(ctx context.Context, ID string, expires int64, signature string) (*model.BulkOperation, *s3.GetObjectOutput, error)
+}
+
+type BulkApplicationService struct {
+	cfg               *config.Config
+	logger            *zerolog.Logger
+	userRepo          *repository.UserRepository
+	applicationRepo   *repository.ApplicationRepository
+	bulkOperationRepo *repository.BulkOperationRepository
+	s3Service         S3ServiceInterface
+	queue             *jobs.Queue
+}
+
+func NewBulkApplicationService(
+	cfg *config.Config,
+	logger *zerolog.Logger,
+	userRepo *repository.UserRepository,
+	applicationRepo *repository.ApplicationRepository,
+	bulkOperationRepo *repository.BulkOperationRepository,
+	s3Service S3ServiceInterface,
+	queue *jobs.Queue,
+) *BulkApplicationService {
+	return &BulkApplicationService{
+		cfg,
+		logger,
+		userRepo,
+		applicationRepo,
+		bulkOperationRepo,
+		s3Service,
+		queue,
+	}
+}
+
+func (s *BulkApplicationService) Start(ctx context.Context, actor *model.User, dto repository.BulkApplicationDTO) (*model.BulkOperation, error) {
+	if err := dto.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Bulk actions don't grant more than the routes of single applications, which are HR's
+	if actor.Role != model.RoleHR {
+		return nil, ErrBulkActionForbidden
+	}
+
+	if dto.Action == model.BulkActionAssign {
+		assignee, err := s.userRepo.GetUserByID(ctx, dto.AssigneeID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidAssignee
+		}
+		if err != nil {
+			return nil, err
+		}
+		if assignee.Role != model.RoleInteviewer {
+			return nil, ErrInvalidAssignee
+		}
+	}
+
+	// Check the template once here, so a broken one isn't found out on every application.
+	if _, err := renderBulkReason(dto.ReasonTemplate, BulkReasonData{CandidateName: "Jane Doe", JobTitle: "Engineer", Company: "fliQt"}); err != nil {
+		return nil, err
+	}
+
+	operation := &model.BulkOperation{
+		Action:         dto.Action,
+		ApplicationIDs: uniqueIDs(dto.ApplicationIDs),
+		CreatedBy:      actor.ID,
+	}
+
+	// Application IDs are kept in their own column, so params stay small enough to be copied into audit logs.
+	dto.ApplicationIDs = nil
+	params, err := json.Marshal(dto)
+	if err != nil {
+		return nil, err
+	}
+	operation.Params = params
+
+	if err := s.bulkOperationRepo.CreateBulkOperation(ctx, operation); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.queue.Enqueue(ctx, TaskBulkApplications, BulkApplicationsPayload{operation.ID}, jobs.Timeout(s.cfg.BulkOperationTimeout)); err != nil {
+		return nil, err
+	}
+
+	return operation, nil
+}
+
+func (s *BulkApplicationService) Get(ctx context.Context, actor *model.User, ID string) (*BulkOperationReport, error) {
+	operation, err := s.bulkOperationRepo.GetBulkOperationByID(ctx, ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Operations of others don't exist as far as an interviewer knows
+	if actor.Role != model.RoleHR && operation.CreatedBy != actor.ID {
+		return nil, apperror.ErrNotFound
+	}
+
+	failures, err := s.bulkOperationRepo.ListBulkOperationFailures(ctx, ID, bulkFailuresLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &BulkOperationReport{
+		BulkOperation: operation,
+		Failures:      failures,
+	}
+
+	// Only the creator of an export gets links to it, they verified TOTP when they started it.
+	if s.exportAvailable(operation) && operation.CreatedBy == actor.ID {
+		// Links are handed to people without accounts, so they're signed rather than pre-signed URLs of S3
+		// which would need the headers of the data key.
+		// Links don't outlive the export
+		expiresAt := time.Now().Add(s.cfg.ExportLinkTTL).Truncate(time.Second)
+		if retainedUntil := operation.FinishedAt.Add(s.cfg.ExportRetention).Truncate(time.Second); retainedUntil.Before(expiresAt) {
+			expiresAt = retainedUntil
+		}
+		report.DownloadURL = fmt.Sprintf("%s/api/exports/%s?%s", s.cfg.PublicURL, operation.ID, url.Values{
+			"expires":   {strconv.FormatInt(expiresAt.Unix(), 10)},
+			"signature": {ExportLinkSignature(s.cfg.ExportLinkSecret, operation.ID, expiresAt.Unix())},
+		}.Encode())
+		report.DownloadExpiresAt = &expiresAt
+	}
+
+	return report, nil
+}
+
+func (s *BulkApplicationService) OpenExport(ctx context.Context, ID string, expires int64, signature string) (*model.BulkOperation, *s3.GetObjectOutput, error) {
+	if err := VerifyExportLink(s.cfg.ExportLinkSecret, ID, expires, signature, time.Now()); err != nil {
+		return nil, nil, err
+	}
+
+	operation, err := s.bulkOperationRepo.GetBulkOperationByID(ctx, ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !s.exportAvailable(operation) {
+		return nil, nil, apperror.ErrNotFound
+	}
+
+	object, err := s.s3Service.GetObject(ctx, s.cfg.S3Bucket, operation.ResultObjectKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return operation, object, nil
+}
+
+// exportAvailable reports whether the file of an operation can be downloaded. Expired exports can't, even before
+// ExpireExports has deleted them.
+func (s *BulkApplicationService) exportAvailable(operation *model.BulkOperation) bool {
+	return operation.Status == model.BulkOperationCompleted && operation.ResultObjectKey != "" &&
+		operation.FinishedAt != nil && time.Since(*operation.FinishedAt) < s.cfg.ExportRetention
+}
+
+// ExpireExports deletes the files of exports completed more than ExportRetention ago, it returns the number of
+// them. An export whose file can't be deleted is tried again next time.
+func (s *BulkApplicationService) ExpireExports(ctx context.Context) (int, error) {
+	expired := 0
+	for {
+		operations, err := s.bulkOperationRepo.ListExpiredExports(ctx, time.Now().Add(-s.cfg.ExportRetention), expireExportsBatchSize)
+		if err != nil {
+			return expired, err
+		}
+
+		for i := range operations {
+			operation := &operations[i]
+			if err := s.s3Service.DeleteObject(ctx, s.cfg.S3Bucket, operation.ResultObjectKey); err != nil {
+				return expired, err
+			}
+			if err := s.bulkOperationRepo.ClearExportResult(ctx, operation); err != nil {
+				return expired, err
+			}
+			expired++
+		}
+
+		if len(operations) < expireExportsBatchSize {
+			return expired, nil
+		}
+	}
+}
+
+func (s *BulkApplicationService) Run(ctx context.Context, ID string) error {
+	operation, err := s.bulkOperationRepo.GetBulkOperationByID(ctx, ID)
+	if err != nil {
+		return err
+	}
+
+	if operation.Finished() {
+		return nil
+	}
+
+	var dto repository.BulkApplicationDTO
+	if err := json.Unmarshal(operation.Params, &dto); err != nil {
+		return err
+	}
+
+	if operation.Status == model.BulkOperationPending {
+		applicationIDs, err := s.selectApplications(ctx, operation, dto)
+		if appErr, ok := apperror.As(err); ok {
+			return s.bulkOperationRepo.FinishBulkOperation(ctx, operation, model.BulkOperationFailed, appErr.Code, "")
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := s.bulkOperationRepo.StartBulkOperation(ctx, operation, applicationIDs); err != nil {
+			return err
+		}
+	}
+
+	actor, err := s.userRepo.GetUserByID(ctx, operation.CreatedBy)
+	if err != nil {
+		return err
+	}
+
+	if operation.Action == model.BulkActionExport {
+		return s.export(ctx, operation, actor, dto.Format)
+	}
+
+	processed, err := s.bulkOperationRepo.ListProcessedApplicationIDs(ctx, operation.ID)
+	if err != nil {
+		return err
+	}
+	skipped := map[string]bool{}
+	for _, ID := range processed {
+		skipped[ID] = true
+	}
+
+	for _, applicationID := range operation.ApplicationIDs {
+		if skipped[applicationID] {
+			continue
+		}
+
+		if err := s.bulkOperationRepo.ProcessBulkItem(ctx, operation, applicationID, func(tx *gorm.DB, application *model.Application) error {
+			return applyBulkAction(tx, actor, application, dto)
+		}); err != nil {
+			return err
+		}
+	}
+
+	return s.bulkOperationRepo.FinishBulkOperation(ctx, operation, model.BulkOperationCompleted, "", "")
+}
+
+// selectApplications returns the IDs of the applications an operation applies to.
+func (s *BulkApplicationService) selectApplications(ctx context.Context, operation *model.BulkOperation, dto repository.BulkApplicationDTO) ([]string, error) {
+	if dto.Filter == nil {
+		return operation.ApplicationIDs, nil
+	}
+
+	// One more than the limit tells whether the filter selects too many.
+	applicationIDs, err := s.applicationRepo.ListApplicationIDs(ctx, *dto.Filter, s.cfg.BulkOperationMaxApplications+1)
+	if err != nil {
+		return nil, err
+	}
+	if len(applicationIDs) > s.cfg.BulkOperationMaxApplications {
+		return nil, ErrTooManyApplications
+	}
+
+	return applicationIDs, nil
+}
+
+// export writes the applications to a file owned by the actor. The file is written again from scratch
+// when the operation is retried, only items which haven't been recorded yet are recorded.
+func (s *BulkApplicationService) export(ctx context.Context, operation *model.BulkOperation, actor *model.User, format string) error {
+	if format == "" {
+		format = model.ExportFormatCSV
+	}
+
+	results := make(map[string]error, len(operation.ApplicationIDs))
+	applications := make([]*model.Application, 0, len(operation.ApplicationIDs))
+	for _, applicationID := range operation.ApplicationIDs {
+		application, err := s.bulkOperationRepo.GetBulkItem(ctx, applicationID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			results[applicationID] = apperror.ErrNotFound
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := authorizeBulkItem(actor, application); err != nil {
+			results[applicationID] = err
+			continue
+		}
+
+		applications = append(applications, application)
+		results[applicationID] = nil
+	}
+
+	histories, err := s.listStatusHistories(ctx, applications)
+	if err != nil {
+		return err
+	}
+
+	// Resumes can make the file large, so it's written to disk rather than memory.
+	file, err := os.CreateTemp("", "export-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	if err := s.writeExport(ctx, file, format, applications, histories); err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	objectKey := fmt.Sprintf("exports/%s.%s", operation.ID, format)
+	if err := s.s3Service.PutObject(ctx, s.cfg.S3Bucket, actor.ID, objectKey, exportContentTypes[format], file); err != nil {
+		return err
+	}
+
+	processed, err := s.bulkOperationRepo.ListProcessedApplicationIDs(ctx, operation.ID)
+	if err != nil {
+		return err
+	}
+	skipped := map[string]bool{}
+	for _, ID := range processed {
+		skipped[ID] = true
+	}
+
+	for _, applicationID := range operation.ApplicationIDs {
+		if skipped[applicationID] {
+			continue
+		}
+
+		if err := s.bulkOperationRepo.RecordBulkItem(ctx, operation, applicationID, results[applicationID]); err != nil {
+			return err
+		}
+	}
+
+	return s.bulkOperationRepo.FinishBulkOperation(ctx, operation, model.BulkOperationCompleted, "", objectKey)
+}
+
+// listStatusHistories returns the status changes of the applications by their IDs.
+func (s *BulkApplicationService) listStatusHistories(ctx context.Context, applications []*model.Application) (map[string][]model.ApplicationStatusHistory, error) {
+	histories := map[string][]model.ApplicationStatusHistory{}
+	for start := 0; start < len(applications); start += exportHistoryBatchSize {
+		end := min(start+exportHistoryBatchSize, len(applications))
+
+		applicationIDs := make([]string, 0, end-start)
+		for _, application := range applications[start:end] {
+			applicationIDs = append(applicationIDs, application.ID)
+		}
+
+		batch, err := s.applicationRepo.ListApplicationStatusHistories(ctx, applicationIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, history := range batch {
+			histories[history.ApplicationID] = append(histories[history.ApplicationID], history)
+		}
+	}
+
+	return histories, nil
+}
+
+// writeExport writes the applications in the format. Spreadsheets have the status history in its own sheet,
+// CSV files in a column, and ZIP archives bundle a spreadsheet with the resumes.
+func (s *BulkApplicationService) writeExport(ctx context.Context, w io.Writer, format string, applications []*model.Application, histories map[string][]model.ApplicationStatusHistory) error {
+	switch format {
+	case model.ExportFormatCSV:
+		// Names and emails are written by candidates, so cells aren't evaluated as formulas when the file is
+		// opened in a spreadsheet
+		writer := csv.NewWriter(w)
+		for _, row := range exportApplicationRows(applications, histories) {
+			if err := writer.Write(util.EscapeCSVRow(row)); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	case model.ExportFormatXLSX:
+		return writeExportWorkbook(w, applications, histories)
+	case model.ExportFormatZIP:
+		archive := zip.NewWriter(w)
+
+		entry, err := archive.Create("applications.xlsx")
+		if err != nil {
+			return err
+		}
+		if err := writeExportWorkbook(entry, applications, histories); err != nil {
+			return err
+		}
+
+		for _, application := range applications {
+			if application.ResumeObjectKey == "" {
+				continue
+			}
+
+			if err := copyObjectToArchive(ctx, s.s3Service, s.cfg.S3Bucket, archive, "resumes/"+resumeFileName(application), application.ResumeObjectKey); err != nil {
+				return err
+			}
+		}
+
+		return archive.Close()
+	default:
+		return fmt.Errorf("unknown export format: %s", format)
+	}
+}
+
+func writeExportWorkbook(w io.Writer, applications []*model.Application, histories map[string][]model.ApplicationStatusHistory) error {
+	workbook := xlsx.NewWriter(w)
+
+	if err := workbook.AddSheet("Applications", exportApplicationRows(applications, nil)); err != nil {
+		return err
+	}
+
+	rows := [][]string{{"application_id", "candidate_name", "from_status", "to_status", "changed_by", "reason", "changed_at"}}
+	for _, application := range applications {
+		for _, history := range histories[application.ID] {
+			rows = append(rows, []string{
+				application.ID,
+				application.User.Name,
+				history.FromStatus,
+				history.ToStatus,
+				history.ChangedBy,
+				history.Reason,
+				history.CreatedAt.UTC().Format(time.RFC3339),
+			})
+		}
+	}
+	if err := workbook.AddSheet("Status history", rows); err != nil {
+		return err
+	}
+
+	return workbook.Close()
+}
+
+func (s *BulkApplicationService) Fail(ctx context.Context, ID string, err error) error {
+	operation, getErr := s.bulkOperationRepo.GetBulkOperationByID(ctx, ID)
+	if getErr != nil {
+		return getErr
+	}
+
+	if operation.Finished() {
+		return nil
+	}
+
+	code := apperror.ErrInternal.Code
+	if appErr, ok := apperror.As(err); ok {
+		code = appErr.Code
+	}
+
+	return s.bulkOperationRepo.FinishBulkOperation(ctx, operation, model.BulkOperationFailed, code, "")
+}
+
+// authorizeBulkItem checks whether the actor can act on the application, only HR can. Operations which were
+// started by others before are refused item by item.
+func authorizeBulkItem(actor *model.User, application *model.Application) error {
+	if actor.Role != model.RoleHR {
+		return ErrBulkActionForbidden
+	}
+
+	return nil
+}
+
+// applyBulkAction applies the action of an operation to an application locked by tx.
+func applyBulkAction(tx *gorm.DB, actor *model.User, application *model.Application, dto repository.BulkApplicationDTO) error {
+	if err := authorizeBulkItem(actor, application); err != nil {
+		return err
+	}
+
+	if application.PurgedAt != nil {
+		return ErrApplicationPurged
+	}
+
+	switch dto.Action {
+	case model.BulkActionStatus, model.BulkActionReject:
+		status := dto.Status
+		if dto.Action == model.BulkActionReject {
+			status = model.ApplicationStatusRejected
+		}
+
+		reason, err := renderBulkReason(dto.ReasonTemplate, BulkReasonData{
+			CandidateName: application.User.Name,
+			JobTitle:      application.Job.Title,
+			Company:       application.Job.Company,
+		})
+		if err != nil {
+			return err
+		}
+
+		return repository.ChangeApplicationStatus(tx, application, repository.UpdateApplicationStatusDTO{
+			Status:    status,
+			Reason:    reason,
+			ChangedBy: actor.ID,
+		})
+	case model.BulkActionAssign:
+		return repository.AssignApplication(tx, application, dto.AssigneeID)
+	case model.BulkActionTag:
+		return repository.TagApplication(tx, application, dto.AddTags, dto.RemoveTags)
+	default:
+		return fmt.Errorf("unknown bulk action: %s", dto.Action)
+	}
+}
+
+// renderBulkReason renders a reason template for an application, an empty template renders no reason.
+func renderBulkReason(text string, data BulkReasonData) (string, error) {
+	if text == "" {
+		return "", nil
+	}
+
+	tmpl, err := template.New("reason").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", ErrInvalidReasonTemplate.Wrap(err)
+	}
+
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", ErrInvalidReasonTemplate.Wrap(err)
+	}
+
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// exportHistoryBatchSize is the number of applications whose status histories are loaded at once.
+const exportHistoryBatchSize = 500
+
+var exportContentTypes = map[string]string{
+	model.ExportFormatCSV:  "text/csv",
+	model.ExportFormatXLSX: xlsx.ContentType,
+	model.ExportFormatZIP:  "application/zip",
+}
+
+// exportApplicationRows returns a header and a row of every application, the status history is summarized
+// in the last column unless histories is nil.
+func exportApplicationRows(applications []*model.Application, histories map[string][]model.ApplicationStatusHistory) [][]string {
+	header := []string{
+		"application_id", "job_id", "job_title", "company", "candidate_id", "candidate_name", "candidate_email",
+		"status", "assignee_id", "tags", "resume_file", "created_at", "updated_at",
+	}
+	if histories != nil {
+		header = append(header, "status_history")
+	}
+
+	rows := [][]string{header}
+	for _, application := range applications {
+		assigneeID := ""
+		if application.AssigneeID != nil {
+			assigneeID = *application.AssigneeID
+		}
+
+		resumeFile := ""
+		if application.ResumeObjectKey != "" {
+			resumeFile = resumeFileName(application)
+		}
+
+		row := []string{
+			application.ID,
+			application.JobID,
+			application.Job.Title,
+			application.Job.Company,
+			application.UserID,
+			application.User.Name,
+			application.User.Email,
+			application.Status,
+			assigneeID,
+			strings.Join(application.Tags, ";"),
+			resumeFile,
+			application.CreatedAt.UTC().Format(time.RFC3339),
+			application.UpdatedAt.UTC().Format(time.RFC3339),
+		}
+
+		if histories != nil {
+			changes := make([]string, 0, len(histories[application.ID]))
+			for _, history := range histories[application.ID] {
+				changes = append(changes, fmt.Sprintf("%s %s>%s", history.CreatedAt.UTC().Format(time.RFC3339), history.FromStatus, history.ToStatus))
+			}
+			row = append(row, strings.Join(changes, "; "))
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+// resumeFileName is a readable file name of the resume of an application, e.g. "Jane Doe - cqanbg8cvavjpljmh7pg.pdf".
+// The ID keeps the names of candidates with the same name apart.
+func resumeFileName(application *model.Application) string {
+	words := []string{}
+	for _, word := range strings.FieldsFunc(application.User.Name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_' && r != '.' && r != '\''
+	}) {
+		// Dots are kept within names, but never make up path segments like ".."
+		if word = strings.Trim(word, "."); word != "" {
+			words = append(words, word)
+		}
+	}
+
+	name := strings.Join(words, " ")
+	if name == "" {
+		name = "candidate"
+	}
+
+	return fmt.Sprintf("%s - %s%s", name, application.ID, path.Ext(application.ResumeObjectKey))
+}
+
+// uniqueIDs returns IDs without duplicates in their original order.
+func uniqueIDs(IDs []string) model.StringList {
+	if len(IDs) == 0 {
+		return nil
+	}
+
+	seen := map[string]bool{}
+	unique := make(model.StringList, 0, len(IDs))
+	for _, ID := range IDs {
+		if !seen[ID] {
+			seen[ID] = true
+			unique = append(unique, ID)
+		}
+	}
+
+	return unique
+}
@@ -0,0 +1,292 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/rs/zerolog"
+
+	"fliqt/config"
+	"fliqt/internal/model"
+	"fliqt/internal/repository"
+	"fliqt/internal/util"
+)
+
+func TestRenderBulkReason(t *testing.T) {
+	data := BulkReasonData{CandidateName: "Alice", JobTitle: "Backend Engineer", Company: "fliQt"}
+
+	for _, tc := range []struct {
+		name     string
+		template string
+		expected string
+		err      error
+	}{
+		{"empty", "", "", nil},
+		{"fields", "Dear {{.CandidateName}}, {{.Company}} has filled the {{.JobTitle}} position.", "Dear Alice, fliQt has filled the Backend Engineer position.", nil},
+		{"unknown field", "Dear {{.Name}}", "", ErrInvalidReasonTemplate},
+		{"syntax", "Dear {{.CandidateName", "", ErrInvalidReasonTemplate},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			reason, err := renderBulkReason(tc.template, data)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("Expected error %v, got %v", tc.err, err)
+			}
+			if reason != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, reason)
+			}
+		})
+	}
+}
+
+func TestAuthorizeBulkItem(t *testing.T) {
+	hr := &model.User{Base: model.Base{ID: "hr"}, Role: model.RoleHR}
+	interviewer := &model.User{Base: model.Base{ID: "interviewer"}, Role: model.RoleInteviewer}
+	other := "other"
+
+	for _, tc := range []struct {
+		name        string
+		actor       *model.User
+		application *model.Application
+		err         error
+	}{
+		{"hr", hr, &model.Application{}, nil},
+		{"assigned", interviewer, &model.Application{AssigneeID: &interviewer.ID}, ErrBulkActionForbidden},
+		{"assigned to another", interviewer, &model.Application{AssigneeID: &other}, ErrBulkActionForbidden},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := authorizeBulkItem(tc.actor, tc.application); !errors.Is(err, tc.err) {
+				t.Errorf("Expected error %v, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestUniqueIDs(t *testing.T) {
+	IDs := uniqueIDs([]string{"b", "a", "b", "c", "a"})
+	if len(IDs) != 3 || IDs[0] != "b" || IDs[1] != "a" || IDs[2] != "c" {
+		t.Errorf("Expected [b a c], got %v", IDs)
+	}
+
+	if uniqueIDs(nil) != nil {
+		t.Errorf("Expected nil")
+	}
+}
+
+func TestExportLink(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	expires := now.Add(time.Minute).Unix()
+	signature := ExportLinkSignature("secret", "cqanbg8cvavjpljmh7pg", expires)
+
+	if err := VerifyExportLink("secret", "cqanbg8cvavjpljmh7pg", expires, signature, now); err != nil {
+		t.Errorf("Expected the link to be valid, got %v", err)
+	}
+
+	if err := VerifyExportLink("secret", "cqanbg8cvavjpljmh7ph", expires, signature, now); !errors.Is(err, ErrInvalidExportLink) {
+		t.Errorf("Expected another operation to be invalid, got %v", err)
+	}
+
+	if err := VerifyExportLink("secret", "cqanbg8cvavjpljmh7pg", expires+3600, signature, now); !errors.Is(err, ErrInvalidExportLink) {
+		t.Errorf("Expected an extended expiry to be invalid, got %v", err)
+	}
+
+	if err := VerifyExportLink("secret", "cqanbg8cvavjpljmh7pg", expires, signature, now.Add(2*time.Minute)); !errors.Is(err, ErrExportLinkExpired) {
+		t.Errorf("Expected the link to expire, got %v", err)
+	}
+}
+
+func TestResumeFileName(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		expected string
+	}{
+		{"Jane Doe", "Jane Doe - cqanbg8cvavjpljmh7pg.pdf"},
+		{"王小明", "王小明 - cqanbg8cvavjpljmh7pg.pdf"},
+		{"../../etc/passwd", "etc passwd - cqanbg8cvavjpljmh7pg.pdf"},
+		{"", "candidate - cqanbg8cvavjpljmh7pg.pdf"},
+	} {
+		application := &model.Application{
+			Base:            model.Base{ID: "cqanbg8cvavjpljmh7pg"},
+			User:            model.User{Name: tc.name},
+			ResumeObjectKey: "1/resume.pdf",
+		}
+		if name := resumeFileName(application); name != tc.expected {
+			t.Errorf("Expected %q, got %q", tc.expected, name)
+		}
+	}
+}
+
+// fakeObjectStore serves objects from memory, other methods of S3ServiceInterface aren't implemented.
+type fakeObjectStore struct {
+	S3ServiceInterface
+	objects map[string]string
+}
+
+func (f *fakeObjectStore) DeleteObject(ctx context.Context, bucket, objectKey string) error {
+	delete(f.objects, objectKey)
+	return nil
+}
+
+func (f *fakeObjectStore) ListObjectKeys(ctx context.Context, bucket, prefix string) ([]string, error) {
+	objectKeys := []string{}
+	for objectKey := range f.objects {
+		if strings.HasPrefix(objectKey, prefix) {
+			objectKeys = append(objectKeys, objectKey)
+		}
+	}
+	sort.Strings(objectKeys)
+	return objectKeys, nil
+}
+
+func (f *fakeObjectStore) GetObject(ctx context.Context, bucket, objectKey string) (*s3.GetObjectOutput, error) {
+	return &s3.GetObjectOutput{Body: io.NopCloser(strings.NewReader(f.objects[objectKey]))}, nil
+}
+
+func TestBulkApplicationServiceWriteExport(t *testing.T) {
+	changedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	applications := []*model.Application{
+		{
+			Base:            model.Base{ID: "cqanbg8cvavjpljmh7pg", CreatedAt: changedAt, UpdatedAt: changedAt},
+			JobID:           "job",
+			Job:             model.Job{Title: "Backend Engineer", Company: "fliQt"},
+			UserID:          "candidate",
+			User:            model.User{Name: "Jane Doe", Email: "=HYPERLINK(\"https://example.com\")"},
+			Status:          model.ApplicationStatusAccepted,
+			ResumeObjectKey: "candidate/resume.pdf",
+			Tags:            model.StringList{"urgent", "remote"},
+		},
+	}
+	histories := map[string][]model.ApplicationStatusHistory{
+		"cqanbg8cvavjpljmh7pg": {
+			{Base: model.Base{CreatedAt: changedAt}, ApplicationID: "cqanbg8cvavjpljmh7pg", FromStatus: "pending", ToStatus: "accepted"},
+		},
+	}
+
+	logger := zerolog.New(zerolog.ConsoleWriter{Out: nil})
+	s := NewBulkApplicationService(&config.Config{S3Bucket: "bucket"}, &logger, nil, nil, nil, &fakeObjectStore{
+		objects: map[string]string{"candidate/resume.pdf": "%PDF-1.4"},
+	}, nil)
+
+	t.Run("CSV", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := s.writeExport(context.TODO(), &buf, model.ExportFormatCSV, applications, histories); err != nil {
+			t.Fatalf("Error: %s", err)
+		}
+
+		records, err := csv.NewReader(&buf).ReadAll()
+		if err != nil {
+			t.Fatalf("Error: %s", err)
+		}
+		if len(records) != 2 {
+			t.Fatalf("Expected a header and a row, got %v", records)
+		}
+
+		row := records[1]
+		if row[5] != "Jane Doe" || row[9] != "urgent;remote" || row[10] != "Jane Doe - cqanbg8cvavjpljmh7pg.pdf" {
+			t.Errorf("Unexpected row %v", row)
+		}
+		if row[6] != `'=HYPERLINK("https://example.com")` {
+			t.Errorf("Expected the formula to be escaped, got %q", row[6])
+		}
+		if history := row[len(row)-1]; history != "2024-01-02T03:04:05Z pending>accepted" {
+			t.Errorf("Expected the status history, got %q", history)
+		}
+	})
+
+	t.Run("ZIP", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := s.writeExport(context.TODO(), &buf, model.ExportFormatZIP, applications, histories); err != nil {
+			t.Fatalf("Error: %s", err)
+		}
+
+		archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		if err != nil {
+			t.Fatalf("Error: %s", err)
+		}
+
+		names := []string{}
+		for _, file := range archive.File {
+			names = append(names, file.Name)
+		}
+		if len(names) != 2 || names[0] != "applications.xlsx" || names[1] != "resumes/Jane Doe - cqanbg8cvavjpljmh7pg.pdf" {
+			t.Errorf("Unexpected entries %v", names)
+		}
+	})
+}
+
+func TestBulkApplicationServiceGetExportLink(t *testing.T) {
+	db, mock, cleanup := util.SetupMockDB(t)
+	defer cleanup()
+
+	logger := zerolog.New(zerolog.ConsoleWriter{Out: nil})
+	s := NewBulkApplicationService(
+		&config.Config{PublicURL: "https://fliqt.example", ExportLinkSecret: "secret", ExportLinkTTL: time.Hour, ExportRetention: 24 * time.Hour},
+		&logger, nil, nil, repository.NewBulkOperationRepository(db, &logger), nil, nil,
+	)
+
+	creator := &model.User{Base: model.Base{ID: "hr"}, Role: model.RoleHR}
+	for _, tc := range []struct {
+		name       string
+		actor      *model.User
+		finishedAt time.Time
+		expected   bool
+	}{
+		{"creator", creator, time.Now().Add(-time.Hour), true},
+		{"other HR", &model.User{Base: model.Base{ID: "other"}, Role: model.RoleHR}, time.Now().Add(-time.Hour), false},
+		{"expired", creator, time.Now().Add(-25 * time.Hour), false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mock.ExpectQuery("SELECT \\* FROM `bulk_operations`").
+				WillReturnRows(sqlmock.NewRows([]string{"id", "action", "status", "result_object_key", "created_by", "finished_at"}).
+					AddRow("cqanbg8cvavjpljmh7pg", model.BulkActionExport, model.BulkOperationCompleted, "exports/cqanbg8cvavjpljmh7pg.csv", "hr", tc.finishedAt))
+			mock.ExpectQuery("SELECT \\* FROM `application_audit_logs`").
+				WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+			report, err := s.Get(context.TODO(), tc.actor, "cqanbg8cvavjpljmh7pg")
+			if err != nil {
+				t.Fatalf("Error: %s", err)
+			}
+			if (report.DownloadURL != "") != tc.expected {
+				t.Errorf("Expected a link: %v, got %q", tc.expected, report.DownloadURL)
+			}
+		})
+	}
+}
+
+func TestBulkApplicationServiceExpireExports(t *testing.T) {
+	db, mock, cleanup := util.SetupMockDB(t)
+	defer cleanup()
+
+	logger := zerolog.New(zerolog.ConsoleWriter{Out: nil})
+	store := &fakeObjectStore{objects: map[string]string{"exports/cqanbg8cvavjpljmh7pg.zip": "PK"}}
+	s := NewBulkApplicationService(&config.Config{ExportRetention: 24 * time.Hour}, &logger, nil, nil, repository.NewBulkOperationRepository(db, &logger), store, nil)
+
+	mock.ExpectQuery("SELECT \\* FROM `bulk_operations` WHERE \\(action = \\? AND result_object_key <> '' AND finished_at < \\?\\)").
+		WithArgs(model.BulkActionExport, sqlmock.AnyArg(), expireExportsBatchSize).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "result_object_key"}).AddRow("cqanbg8cvavjpljmh7pg", "exports/cqanbg8cvavjpljmh7pg.zip"))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `bulk_operations` SET `result_object_key`=").
+		WithArgs("", sqlmock.AnyArg(), "cqanbg8cvavjpljmh7pg").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	expired, err := s.ExpireExports(context.TODO())
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if expired != 1 || len(store.objects) != 0 {
+		t.Errorf("Expected the export to be deleted, got %d %v", expired, store.objects)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"fliqt/config"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"fliqt/internal/model"
+	"fliqt/internal/repository"
+)
+
+var applicationStatuses = []string{
+	model.ApplicationStatusAccepted,
+	model.ApplicationStatusPending,
+	model.ApplicationStatusRejected,
+	model.ApplicationStatusWithdrawn,
+	model.ApplicationStatusHired,
+}
+
+// BusinessMetricsService exposes business KPIs as gauges. Counting them is too expensive to do on every
+// collection, so they're refreshed periodically and collections observe the last values.
+type BusinessMetricsService struct {
+	cfg         *config.Config
+	logger      *zerolog.Logger
+	metricsRepo *repository.MetricsRepository
+
+	mu                   sync.RWMutex
+	openJobs             int64
+	applicationsByStatus map[string]int64
+}
+
+func NewBusinessMetricsService(
+	cfg *config.Config,
+	logger *zerolog.Logger,
+	metricsRepo *repository.MetricsRepository,
+) (*BusinessMetricsService, error) {
+	s := &BusinessMetricsService{
+		cfg:                  cfg,
+		logger:               logger,
+		metricsRepo:          metricsRepo,
+		applicationsByStatus: map[string]int64{},
+	}
+
+	openJobs, err := meter.Int64ObservableGauge("fliqt.jobs.open",
+		metric.WithDescription("Jobs which haven't been deleted"))
+	if err != nil {
+		return nil, err
+	}
+	applications, err := meter.Int64ObservableGauge("fliqt.applications",
+		metric.WithDescription("Applications by status"))
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := meter.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+
+		o.ObserveInt64(openJobs, s.openJobs)
+		// Every status is observed, so a status without applications drops to 0 instead of disappearing.
+		for _, status := range applicationStatuses {
+			o.ObserveInt64(applications, s.applicationsByStatus[status], metric.WithAttributes(
+				attribute.String("status", status),
+			))
+		}
+		return nil
+	}, openJobs, applications); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Refresh counts the KPIs again.
+func (s *BusinessMetricsService) Refresh(ctx context.Context) error {
+	openJobs, err := s.metricsRepo.CountOpenJobs(ctx)
+	if err != nil {
+		return err
+	}
+
+	applicationsByStatus, err := s.metricsRepo.CountApplicationsByStatus(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.openJobs = openJobs
+	s.applicationsByStatus = applicationsByStatus
+
+	return nil
+}
+
+// Run refreshes the KPIs every METRICS_REFRESH_INTERVAL until ctx is done.
+func (s *BusinessMetricsService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.MetricsRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Refresh(ctx); err != nil {
+			s.logger.Error().Err(err).Msg("failed to refresh business metrics")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+
+	"gorm.io/gorm"
+
+	"fliqt/internal/model"
+	"fliqt/internal/repository"
+)
+
+const (
+	sseCustomerAlgorithm = "AES256"
+	rewrapBatchSize      = 100
+)
+
+// SSECustomerKey is the plaintext data key of an object, S3 encrypts the object with it (SSE-C).
+type SSECustomerKey struct {
+	Key []byte
+}
+
+func (k *SSECustomerKey) Algorithm() string {
+	return sseCustomerAlgorithm
+}
+
+func (k *SSECustomerKey) KeyBase64() string {
+	return base64.StdEncoding.EncodeToString(k.Key)
+}
+
+func (k *SSECustomerKey) KeyMD5Base64() string {
+	sum := md5.Sum(k.Key)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// Headers returns the headers clients have to send along with a presigned request of an encrypted object.
+func (k *SSECustomerKey) Headers() map[string]string {
+	return map[string]string{
+		"x-amz-server-side-encryption-customer-algorithm": k.Algorithm(),
+		"x-amz-server-side-encryption-customer-key":       k.KeyBase64(),
+		"x-amz-server-side-encryption-customer-key-MD5":   k.KeyMD5Base64(),
+	}
+}
+
+type EncryptionServiceInterface interface {
+	// GenerateDataKey creates the data key of a new object, the key is wrapped by the tenant's current key and stored.
+	GenerateDataKey(ctx context.Context, tenantID string, objectKey string) (*SSECustomerKey, error)
+	// GetDataKey returns the data key of an object, or nil when the object isn't encrypted.
+	GetDataKey(ctx context.Context, objectKey string) (*SSECustomerKey, error)
+	// DeleteDataKey deletes the data key of an object, so the object can't be decrypted anymore.
+	DeleteDataKey(ctx context.Context, objectKey string) error
+	// RewrapDataKeys re-wraps the data keys of a tenant which aren't wrapped by its current key.
+	RewrapDataKeys(ctx context.Context, tenantID string) (int, error)
+}
+
+type EncryptionService struct {
+	kms  KMS
+	repo *repository.EncryptedObjectRepository
+}
+
+func NewEncryptionService(
+	kms KMS,
+	repo *repository.EncryptedObjectRepository,
+) *EncryptionService {
+	return &EncryptionService{
+		kms,
+		repo,
+	}
+}
+
+func (s *EncryptionService) GenerateDataKey(ctx context.Context, tenantID string, objectKey string) (*SSECustomerKey, error) {
+	dataKey := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, err
+	}
+
+	keyID, err := s.kms.CurrentKeyID(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	wrappedKey, err := s.kms.WrapKey(ctx, keyID, dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateEncryptedObject(ctx, &model.EncryptedObject{
+		ObjectKey:  objectKey,
+		TenantID:   tenantID,
+		KeyID:      keyID,
+		WrappedKey: wrappedKey,
+		Algorithm:  sseCustomerAlgorithm,
+	}); err != nil {
+		return nil, err
+	}
+
+	return &SSECustomerKey{Key: dataKey}, nil
+}
+
+func (s *EncryptionService) GetDataKey(ctx context.Context, objectKey string) (*SSECustomerKey, error) {
+	object, err := s.repo.GetEncryptedObjectByObjectKey(ctx, objectKey)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	dataKey, err := s.kms.UnwrapKey(ctx, object.KeyID, object.WrappedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SSECustomerKey{Key: dataKey}, nil
+}
+
+func (s *EncryptionService) DeleteDataKey(ctx context.Context, objectKey string) error {
+	return s.repo.DeleteEncryptedObject(ctx, objectKey)
+}
+
+func (s *EncryptionService) RewrapDataKeys(ctx context.Context, tenantID string) (int, error) {
+	keyID, err := s.kms.CurrentKeyID(ctx, tenantID)
+	if err != nil {
+		return 0, err
+	}
+
+	rewrapped := 0
+	for {
+		objects, err := s.repo.ListStaleEncryptedObjects(ctx, tenantID, keyID, rewrapBatchSize)
+		if err != nil {
+			return rewrapped, err
+		}
+
+		if len(objects) == 0 {
+			return rewrapped, nil
+		}
+
+		for _, object := range objects {
+			dataKey, err := s.kms.UnwrapKey(ctx, object.KeyID, object.WrappedKey)
+			if err != nil {
+				return rewrapped, err
+			}
+
+			wrappedKey, err := s.kms.WrapKey(ctx, keyID, dataKey)
+			if err != nil {
+				return rewrapped, err
+			}
+
+			if err := s.repo.UpdateWrappedKey(ctx, object.ID, keyID, wrappedKey); err != nil {
+				return rewrapped, err
+			}
+
+			rewrapped++
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fliqt/internal/apperror"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrInvalidExportLink = apperror.New(http.StatusForbidden, "invalid_export_link", "invalid export link")
+	ErrExportLinkExpired = apperror.New(http.StatusGone, "export_link_expired", "the export link has expired")
+)
+
+// ExportLinkSignature signs the operation and the expiry, so export links work without signing in.
+func ExportLinkSignature(secret string, operationID string, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(operationID))
+	mac.Write([]byte{0})
+	mac.Write([]byte(strconv.FormatInt(expires, 10)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyExportLink checks the signature of an export link and whether it has expired at now.
+func VerifyExportLink(secret string, operationID string, expires int64, signature string, now time.Time) error {
+	if !hmac.Equal([]byte(signature), []byte(ExportLinkSignature(secret, operationID, expires))) {
+		return ErrInvalidExportLink
+	}
+
+	if now.Unix() > expires {
+		return ErrExportLinkExpired
+	}
+
+	return nil
+}
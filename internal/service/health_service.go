@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"fliqt/config"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/go-redis/redis/v8"
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+)
+
+const (
+	HealthStatusOK          = "ok"
+	HealthStatusUnavailable = "unavailable"
+)
+
+// HealthCheck is the status of a dependency. Errors are logged rather than reported, since the probe is public.
+type HealthCheck struct {
+	Status    string `json:"status"`
+	LatencyMs int64  `json:"latency_ms"`
+}
+
+// ReadinessReport is ok only when every dependency is ok.
+type ReadinessReport struct {
+	Status string                 `json:"status"`
+	Checks map[string]HealthCheck `json:"checks"`
+}
+
+type HealthServiceInterface interface {
+	// Ready checks MySQL, Redis and the S3 bucket, each check gives up after the readiness timeout.
+	Ready(ctx context.Context) *ReadinessReport
+}
+
+type HealthService struct {
+	cfg    *config.Config
+	logger *zerolog.Logger
+	checks map[string]func(ctx context.Context) error
+}
+
+func NewHealthService(
+	cfg *config.Config,
+	logger *zerolog.Logger,
+	db *gorm.DB,
+	redisClient *redis.Client,
+	s3Client *s3.Client,
+) *HealthService {
+	return &HealthService{
+		cfg,
+		logger,
+		map[string]func(ctx context.Context) error{
+			"mysql": func(ctx context.Context) error {
+				sqlDB, err := db.DB()
+				if err != nil {
+					return err
+				}
+				return sqlDB.PingContext(ctx)
+			},
+			"redis": func(ctx context.Context) error {
+				return redisClient.Ping(ctx).Err()
+			},
+			"s3": func(ctx context.Context) error {
+				_, err := s3Client.HeadBucket(ctx, &s3.HeadBucketInput{
+					Bucket: aws.String(cfg.S3Bucket),
+				})
+				return err
+			},
+		},
+	}
+}
+
+func (s *HealthService) Ready(ctx context.Context) *ReadinessReport {
+	report := &ReadinessReport{
+		Status: HealthStatusOK,
+		Checks: make(map[string]HealthCheck, len(s.checks)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range s.checks {
+		wg.Add(1)
+		go func(name string, check func(ctx context.Context) error) {
+			defer wg.Done()
+
+			result := s.run(ctx, name, check)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[name] = result
+			if result.Status != HealthStatusOK {
+				report.Status = HealthStatusUnavailable
+			}
+		}(name, check)
+	}
+	wg.Wait()
+
+	return report
+}
+
+func (s *HealthService) run(ctx context.Context, name string, check func(ctx context.Context) error) HealthCheck {
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.ReadinessTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check(ctx)
+	result := HealthCheck{
+		Status:    HealthStatusOK,
+		LatencyMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		result.Status = HealthStatusUnavailable
+		s.logger.Warn().Err(err).Str("check", name).Msg("dependency unavailable")
+	}
+
+	return result
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"fliqt/config"
+)
+
+func TestHealthServiceReady(t *testing.T) {
+	cfg := &config.Config{ReadinessTimeout: 50 * time.Millisecond}
+	logger := zerolog.Nop()
+	s := &HealthService{
+		cfg,
+		&logger,
+		map[string]func(ctx context.Context) error{
+			"mysql": func(ctx context.Context) error { return nil },
+			"redis": func(ctx context.Context) error { return errors.New("connection refused") },
+			"s3": func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+		},
+	}
+
+	report := s.Ready(context.Background())
+	if report.Status != HealthStatusUnavailable {
+		t.Errorf("expected %s, got %s", HealthStatusUnavailable, report.Status)
+	}
+	if report.Checks["mysql"].Status != HealthStatusOK {
+		t.Errorf("expected mysql to be ok, got %+v", report.Checks["mysql"])
+	}
+	if report.Checks["redis"].Status != HealthStatusUnavailable {
+		t.Errorf("expected redis to be unavailable, got %+v", report.Checks["redis"])
+	}
+	if report.Checks["s3"].Status != HealthStatusUnavailable {
+		t.Errorf("expected s3 to time out, got %+v", report.Checks["s3"])
+	}
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fliqt/config"
+	"fmt"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/rs/zerolog"
+
+	"fliqt/internal/event"
+	"fliqt/internal/repository"
+)
+
+// jobFacetsGenerationKey is bumped on every job write, cached facets of earlier generations are never read
+// again and expire on their own.
+const jobFacetsGenerationKey = "job_facets:generation"
+
+type JobFacetServiceInterface interface {
+	// GetJobFacets returns the facets of the jobs which match filterParams.
+	GetJobFacets(ctx context.Context, filterParams repository.JobFilterParams) (*repository.JobFacets, error)
+}
+
+// JobFacetService counts jobs by the values of search filters, counts are cached in Redis until a job changes.
+type JobFacetService struct {
+	cfg         *config.Config
+	logger      *zerolog.Logger
+	redisClient *redis.Client
+	jobRepo     *repository.JobRepository
+}
+
+func NewJobFacetService(
+	cfg *config.Config,
+	logger *zerolog.Logger,
+	redisClient *redis.Client,
+	jobRepo *repository.JobRepository,
+) *JobFacetService {
+	return &JobFacetService{
+		cfg:         cfg,
+		logger:      logger,
+		redisClient: redisClient,
+		jobRepo:     jobRepo,
+	}
+}
+
+// GetJobFacets returns cached facets unless a job changed since they were counted. Searches work without the
+// cache, so Redis failures are only logged.
+func (s *JobFacetService) GetJobFacets(ctx context.Context, filterParams repository.JobFilterParams) (*repository.JobFacets, error) {
+	generation, err := s.redisClient.Get(ctx, jobFacetsGenerationKey).Int64()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		s.logger.Warn().Err(err).Msg("failed to get the generation of job facets")
+		return s.jobRepo.JobFacets(ctx, filterParams, s.cfg.JobFacetSalaryBuckets)
+	}
+	key := jobFacetsCacheKey(generation, filterParams)
+
+	if cached, err := s.redisClient.Get(ctx, key).Bytes(); err == nil {
+		var facets repository.JobFacets
+		if err := json.Unmarshal(cached, &facets); err == nil {
+			return &facets, nil
+		}
+	} else if !errors.Is(err, redis.Nil) {
+		s.logger.Warn().Err(err).Msg("failed to get cached job facets")
+	}
+
+	facets, err := s.jobRepo.JobFacets(ctx, filterParams, s.cfg.JobFacetSalaryBuckets)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(facets)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.redisClient.Set(ctx, key, data, s.cfg.JobFacetsCacheTTL).Err(); err != nil {
+		s.logger.Warn().Err(err).Msg("failed to cache job facets")
+	}
+
+	return facets, nil
+}
+
+// Handle invalidates cached facets when a job is written, translations included since keywords are searched
+// in them.
+func (s *JobFacetService) Handle(ctx context.Context, e event.Event) error {
+	switch e.Type {
+	case event.JobCreated, event.JobUpdated, event.JobDeleted:
+		return s.redisClient.Incr(ctx, jobFacetsGenerationKey).Err()
+	}
+
+	return nil
+}
+
+// jobFacetsCacheKey identifies the facets of filterParams, pagination doesn't change them.
+func jobFacetsCacheKey(generation int64, filterParams repository.JobFilterParams) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%q\x00%d\x00%d\x00%q\x00%q\x00%q\x00%s",
+		filterParams.Keyword,
+		filterParams.SalaryMin,
+		filterParams.SalaryMax,
+		filterParams.JobType,
+		filterParams.Company,
+		filterParams.Location,
+		strings.Join(filterParams.Locales, ","),
+	)
+
+	return fmt.Sprintf("job_facets:%d:%s", generation, hex.EncodeToString(hash.Sum(nil)))
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/rs/zerolog"
+
+	"fliqt/config"
+	"fliqt/internal/event"
+	"fliqt/internal/model"
+	"fliqt/internal/repository"
+	"fliqt/internal/search"
+	"fliqt/internal/util"
+)
+
+func TestJobFacetsCacheKey(t *testing.T) {
+	params := repository.JobFilterParams{Keyword: "engineer", Locales: []string{"en"}}
+	key := jobFacetsCacheKey(1, params)
+
+	paged := params
+	paged.PaginationParams = model.PaginationParams{PageSize: 50, NextToken: "token", Sort: "-created_at"}
+	if jobFacetsCacheKey(1, paged) != key {
+		t.Errorf("expected pagination not to change the key")
+	}
+
+	for _, other := range []repository.JobFilterParams{
+		{Keyword: "designer", Locales: []string{"en"}},
+		{Keyword: "engineer", Locales: []string{"ja", "en"}},
+		{Keyword: "engineer", Location: "Taipei", Locales: []string{"en"}},
+	} {
+		if jobFacetsCacheKey(1, other) == key {
+			t.Errorf("expected %+v to have another key", other)
+		}
+	}
+
+	if jobFacetsCacheKey(2, params) == key {
+		t.Errorf("expected another generation to have another key")
+	}
+}
+
+func TestJobFacetServiceInvalidation(t *testing.T) {
+	db, mock, cleanup := util.SetupMockDB(t)
+	defer cleanup()
+
+	logger := zerolog.New(zerolog.ConsoleWriter{Out: nil})
+	mr := miniredis.RunT(t)
+	s := NewJobFacetService(&config.Config{}, &logger, redis.NewClient(&redis.Options{Addr: mr.Addr()}), repository.NewJobRepository(db, &logger, search.NewMySQLJobSearchIndex()))
+
+	expectFacets := func(count int) {
+		for _, column := range []string{"job_type", "company", "location"} {
+			mock.ExpectQuery("SELECT jobs." + column + " AS value").
+				WillReturnRows(sqlmock.NewRows([]string{"value", "count"}).AddRow("value", count))
+		}
+	}
+	params := repository.JobFilterParams{Keyword: "engineer"}
+
+	expectFacets(1)
+	if _, err := s.GetJobFacets(context.TODO(), params); err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+
+	// Cached facets are returned without querying
+	facets, err := s.GetJobFacets(context.TODO(), params)
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if facets.JobTypes[0].Count != 1 {
+		t.Errorf("expected the cached facets, got %+v", facets.JobTypes)
+	}
+
+	if err := s.Handle(context.TODO(), event.Event{Type: event.JobUpdated}); err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+
+	expectFacets(2)
+	facets, err = s.GetJobFacets(context.TODO(), params)
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if facets.JobTypes[0].Count != 2 {
+		t.Errorf("expected the facets to be counted again, got %+v", facets.JobTypes)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
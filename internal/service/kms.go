@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fliqt/config"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+var (
+	ErrUnknownKMSProvider = errors.New("unknown KMS provider")
+	ErrKeyNotFound        = errors.New("key-encryption key not found")
+)
+
+// KMS manages key-encryption keys (KEK), which wrap the data keys of encrypted objects.
+// Every tenant has its own keys, and a key is never deleted after it's rotated, so data keys
+// wrapped by an old key can still be unwrapped until they're re-wrapped.
+type KMS interface {
+	// CurrentKeyID returns the key which wraps new data keys of the tenant, the key is created when the tenant has none.
+	CurrentKeyID(ctx context.Context, tenantID string) (string, error)
+	// RotateKey creates a new key for the tenant and makes it the current key.
+	RotateKey(ctx context.Context, tenantID string) (string, error)
+	WrapKey(ctx context.Context, keyID string, dataKey []byte) ([]byte, error)
+	UnwrapKey(ctx context.Context, keyID string, wrappedKey []byte) ([]byte, error)
+}
+
+func NewKMS(cfg *config.Config) (KMS, error) {
+	switch cfg.KMSProvider {
+	case "local":
+		return NewLocalKMS(cfg.KMSLocalKeyFile)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKMSProvider, cfg.KMSProvider)
+	}
+}
+
+type localKMSTenant struct {
+	CurrentKeyID string            `json:"current_key_id"`
+	Keys         map[string][]byte `json:"keys"`
+}
+
+// LocalKMS keeps keys in a JSON file, it's only meant for development.
+type LocalKMS struct {
+	mu      sync.Mutex
+	path    string
+	tenants map[string]*localKMSTenant
+}
+
+func NewLocalKMS(path string) (*LocalKMS, error) {
+	kms := &LocalKMS{
+		path:    path,
+		tenants: map[string]*localKMSTenant{},
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return kms, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &kms.tenants); err != nil {
+		return nil, err
+	}
+
+	return kms, nil
+}
+
+func (k *LocalKMS) save() error {
+	data, err := json.MarshalIndent(k.tenants, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(k.path), 0o700); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first, so a crash can't leave a truncated key file behind.
+	tmpPath := k.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, k.path)
+}
+
+func (k *LocalKMS) rotateKey(tenantID string) (string, error) {
+	tenant, ok := k.tenants[tenantID]
+	if !ok {
+		tenant = &localKMSTenant{Keys: map[string][]byte{}}
+		k.tenants[tenantID] = tenant
+	}
+
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return "", err
+	}
+
+	keyID := fmt.Sprintf("%s/v%d", tenantID, len(tenant.Keys)+1)
+	tenant.Keys[keyID] = key
+	tenant.CurrentKeyID = keyID
+
+	if err := k.save(); err != nil {
+		return "", err
+	}
+
+	return keyID, nil
+}
+
+func (k *LocalKMS) key(keyID string) ([]byte, error) {
+	for _, tenant := range k.tenants {
+		if key, ok := tenant.Keys[keyID]; ok {
+			return key, nil
+		}
+	}
+
+	return nil, ErrKeyNotFound
+}
+
+func (k *LocalKMS) CurrentKeyID(ctx context.Context, tenantID string) (string, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if tenant, ok := k.tenants[tenantID]; ok {
+		return tenant.CurrentKeyID, nil
+	}
+
+	return k.rotateKey(tenantID)
+}
+
+func (k *LocalKMS) RotateKey(ctx context.Context, tenantID string) (string, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return k.rotateKey(tenantID)
+}
+
+func (k *LocalKMS) WrapKey(ctx context.Context, keyID string, dataKey []byte) ([]byte, error) {
+	k.mu.Lock()
+	key, err := k.key(keyID)
+	k.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	// The key ID is authenticated, so a wrapped key can't be unwrapped with another tenant's key.
+	return gcm.Seal(nonce, nonce, dataKey, []byte(keyID)), nil
+}
+
+func (k *LocalKMS) UnwrapKey(ctx context.Context, keyID string, wrappedKey []byte) ([]byte, error) {
+	k.mu.Lock()
+	key, err := k.key(keyID)
+	k.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(wrappedKey) < gcm.NonceSize() {
+		return nil, ErrKeyNotFound
+	}
+
+	nonce, ciphertext := wrappedKey[:gcm.NonceSize()], wrappedKey[gcm.NonceSize():]
+
+	return gcm.Open(nil, nonce, ciphertext, []byte(keyID))
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalKMS(t *testing.T) {
+	ctx := context.TODO()
+	path := filepath.Join(t.TempDir(), "keys.json")
+
+	kms, err := NewLocalKMS(path)
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+
+	keyID, err := kms.CurrentKeyID(ctx, "tenant")
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+
+	dataKey := bytes.Repeat([]byte{1}, 32)
+	wrappedKey, err := kms.WrapKey(ctx, keyID, dataKey)
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+
+	rotatedKeyID, err := kms.RotateKey(ctx, "tenant")
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if rotatedKeyID == keyID {
+		t.Errorf("expected a new key after rotation, got %s", rotatedKeyID)
+	}
+
+	// Keys are loaded from the file, and the old key still unwraps data keys wrapped before the rotation.
+	kms, err = NewLocalKMS(path)
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+
+	currentKeyID, err := kms.CurrentKeyID(ctx, "tenant")
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if currentKeyID != rotatedKeyID {
+		t.Errorf("expected current key to be %s, got %s", rotatedKeyID, currentKeyID)
+	}
+
+	unwrappedKey, err := kms.UnwrapKey(ctx, keyID, wrappedKey)
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if !bytes.Equal(unwrappedKey, dataKey) {
+		t.Errorf("expected unwrapped key to equal the data key")
+	}
+
+	if _, err := kms.UnwrapKey(ctx, rotatedKeyID, wrappedKey); err == nil {
+		t.Errorf("expected an error when unwrapping with another key")
+	}
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"fliqt/internal/util"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	presignOperationUpload     = "upload"
+	presignOperationDownload   = "download"
+	presignOperationUploadPart = "upload_part"
+)
+
+var (
+	meter = otel.Meter(util.GetFileNameFromCaller())
+
+	presignCounter = util.Must(meter.Int64Counter(
+		"fliqt.s3.presigns",
+		metric.WithDescription("Presigned S3 URLs by operation and outcome"),
+	))
+	totpFailureCounter = util.Must(meter.Int64Counter(
+		"fliqt.auth.totp_failures",
+		metric.WithDescription("Failed TOTP verifications"),
+	))
+)
+
+// recordPresign counts a presign, cached ones are presigned URLs which are handed out again.
+func recordPresign(ctx context.Context, operation string, outcome string) {
+	presignCounter.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("operation", operation),
+		attribute.String("outcome", outcome),
+	))
+}
+
+func presignOutcome(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "success"
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fliqt/config"
+	"fmt"
+	"net/url"
+
+	"github.com/rs/zerolog"
+
+	"fliqt/internal/event"
+	"fliqt/internal/model"
+	"fliqt/internal/notification"
+	"fliqt/internal/repository"
+)
+
+var ErrNotificationFailed = errors.New("failed to send notifications")
+
+// NotificationData is the data of notification templates.
+type NotificationData struct {
+	RecipientName  string
+	ApplicationID  string
+	CandidateID    string
+	JobTitle       string
+	Company        string
+	Status         string
+	Reason         string
+	UnsubscribeURL string
+}
+
+type NotificationServiceInterface interface {
+	// Handle sends the notifications of an event, it fails when any of them fails so the event is retried,
+	// notifications which have been sent are skipped.
+	Handle(ctx context.Context, e event.Event) error
+	// CheckUnsubscribe checks the signed token of an unsubscribe link without changing anything.
+	CheckUnsubscribe(ctx context.Context, userID string, notificationType string, token string) error
+	// Unsubscribe disables a type of notifications for a user with a signed token from a notification.
+	Unsubscribe(ctx context.Context, userID string, notificationType string, token string) error
+}
+
+type NotificationService struct {
+	cfg              *config.Config
+	logger           *zerolog.Logger
+	userRepo         *repository.UserRepository
+	jobRepo          *repository.JobRepository
+	notificationRepo *repository.NotificationRepository
+	renderer         *notification.Renderer
+	provider         notification.Provider
+}
+
+func NewNotificationService(
+	cfg *config.Config,
+	logger *zerolog.Logger,
+	userRepo *repository.UserRepository,
+	jobRepo *repository.JobRepository,
+	notificationRepo *repository.NotificationRepository,
+	renderer *notification.Renderer,
+	provider notification.Provider,
+) *NotificationService {
+	return &NotificationService{
+		cfg,
+		logger,
+		userRepo,
+		jobRepo,
+		notificationRepo,
+		renderer,
+		provider,
+	}
+}
+
+type notificationRecipient struct {
+	userID           string
+	notificationType string
+}
+
+func (s *NotificationService) Handle(ctx context.Context, e event.Event) error {
+	var recipients []notificationRecipient
+	var data NotificationData
+	var jobID string
+
+	switch e.Type {
+	case event.ApplicationSubmitted:
+		var application model.Application
+		if err := json.Unmarshal(e.Payload, &application); err != nil {
+			return err
+		}
+
+		jobID = application.JobID
+		data.ApplicationID = application.ID
+		data.CandidateID = application.UserID
+		data.Status = application.Status
+		recipients = append(recipients, notificationRecipient{application.UserID, model.NotificationApplicationSubmitted})
+
+		hrs, err := s.userRepo.ListUsersByRole(ctx, model.RoleHR)
+		if err != nil {
+			return err
+		}
+		for _, hr := range hrs {
+			recipients = append(recipients, notificationRecipient{hr.ID, model.NotificationApplicationReceived})
+		}
+	case event.ApplicationStatusChanged:
+		var payload event.ApplicationStatusChangedPayload
+		if err := json.Unmarshal(e.Payload, &payload); err != nil {
+			return err
+		}
+
+		jobID = payload.JobID
+		data.ApplicationID = payload.ApplicationID
+		data.CandidateID = payload.UserID
+		data.Status = payload.ToStatus
+		data.Reason = payload.Reason
+		recipients = append(recipients, notificationRecipient{payload.UserID, model.NotificationApplicationStatusChanged})
+	default:
+		return nil
+	}
+
+	job, err := s.jobRepo.GetJobByID(ctx, jobID)
+	if err != nil {
+		return err
+	}
+	data.JobTitle = job.Title
+	data.Company = job.Company
+
+	failed := 0
+	for _, recipient := range recipients {
+		if err := s.notify(ctx, e.ID, recipient, data); err != nil {
+			s.logger.Error().Err(err).Str("event_id", e.ID).Str("user_id", recipient.userID).Str("type", recipient.notificationType).Msg("failed to send notification")
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%w: %d of %d", ErrNotificationFailed, failed, len(recipients))
+	}
+
+	return nil
+}
+
+func (s *NotificationService) notify(ctx context.Context, eventID string, recipient notificationRecipient, data NotificationData) error {
+	user, err := s.userRepo.GetUserByID(ctx, recipient.userID)
+	if err != nil {
+		return err
+	}
+
+	if user.Email == "" || user.AnonymizedAt != nil {
+		return nil
+	}
+
+	preferences, err := s.notificationRepo.GetPreferences(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if !preferences[recipient.notificationType] {
+		return nil
+	}
+
+	sent, err := s.notificationRepo.HasSent(ctx, eventID, user.ID, recipient.notificationType)
+	if err != nil || sent {
+		return err
+	}
+
+	data.RecipientName = user.Name
+	data.UnsubscribeURL = s.unsubscribeURL(user.ID, recipient.notificationType)
+
+	subject, html, text, err := s.renderer.Render(user.Locale, recipient.notificationType, data)
+	if err != nil {
+		return err
+	}
+
+	if err := s.provider.Send(ctx, notification.Message{
+		To:      user.Email,
+		Subject: subject,
+		HTML:    html,
+		Text:    text,
+		Headers: map[string]string{
+			// One-click unsubscribe of mail clients (RFC 8058)
+			"List-Unsubscribe":      fmt.Sprintf("<%s>", data.UnsubscribeURL),
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		},
+	}); err != nil {
+		return err
+	}
+
+	return s.notificationRepo.RecordSent(ctx, eventID, user.ID, recipient.notificationType)
+}
+
+func (s *NotificationService) unsubscribeURL(userID string, notificationType string) string {
+	query := url.Values{}
+	query.Set("user_id", userID)
+	query.Set("type", notificationType)
+	query.Set("token", notification.UnsubscribeToken(s.cfg.UnsubscribeSecret, userID, notificationType))
+
+	return fmt.Sprintf("%s/api/notifications/unsubscribe?%s", s.cfg.PublicURL, query.Encode())
+}
+
+func (s *NotificationService) CheckUnsubscribe(ctx context.Context, userID string, notificationType string, token string) error {
+	return notification.VerifyUnsubscribeToken(s.cfg.UnsubscribeSecret, userID, notificationType, token)
+}
+
+func (s *NotificationService) Unsubscribe(ctx context.Context, userID string, notificationType string, token string) error {
+	if err := s.CheckUnsubscribe(ctx, userID, notificationType, token); err != nil {
+		return err
+	}
+
+	_, err := s.notificationRepo.SetPreferences(ctx, userID, repository.NotificationPreferencesDTO{notificationType: false})
+	return err
+}
@@ -0,0 +1,324 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fliqt/config"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+
+	"fliqt/internal/apperror"
+	"fliqt/internal/model"
+	"fliqt/internal/repository"
+)
+
+const erasureReason = "erasure_request"
+
+var (
+	ErrLegalHold              = apperror.New(http.StatusConflict, "legal_hold", "user is on legal hold")
+	ErrErasureRequestReviewed = apperror.New(http.StatusConflict, "erasure_request_reviewed", "erasure request has already been reviewed")
+)
+
+type PrivacyServiceInterface interface {
+	// Export writes a ZIP archive of everything held about the user, as JSON along with the original files.
+	Export(ctx context.Context, user *model.User, w io.Writer) error
+	// RequestErasure starts the erasure of the user's data, it's carried out once HR approves it.
+	RequestErasure(ctx context.Context, user *model.User) (*model.ErasureRequest, error)
+	// ApproveErasure deletes the files of the user and pseudonymizes their rows, statistics are kept.
+	ApproveErasure(ctx context.Context, reviewer *model.User, requestID string) (*model.ErasureRequest, error)
+	// RejectErasure closes the erasure request without erasing anything.
+	RejectErasure(ctx context.Context, reviewer *model.User, requestID string, reason string) (*model.ErasureRequest, error)
+}
+
+type PrivacyService struct {
+	cfg             *config.Config
+	logger          *zerolog.Logger
+	userRepo        *repository.UserRepository
+	applicationRepo *repository.ApplicationRepository
+	privacyRepo     *repository.PrivacyRepository
+	retentionRepo   *repository.RetentionRepository
+	s3Service       S3ServiceInterface
+}
+
+func NewPrivacyService(
+	cfg *config.Config,
+	logger *zerolog.Logger,
+	userRepo *repository.UserRepository,
+	applicationRepo *repository.ApplicationRepository,
+	privacyRepo *repository.PrivacyRepository,
+	retentionRepo *repository.RetentionRepository,
+	s3Service S3ServiceInterface,
+) *PrivacyService {
+	return &PrivacyService{
+		cfg,
+		logger,
+		userRepo,
+		applicationRepo,
+		privacyRepo,
+		retentionRepo,
+		s3Service,
+	}
+}
+
+func (s *PrivacyService) Export(ctx context.Context, user *model.User, w io.Writer) error {
+	applications, err := s.privacyRepo.ListApplicationsByUser(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	applicationIDs := make([]string, 0, len(applications))
+	for _, application := range applications {
+		applicationIDs = append(applicationIDs, application.ID)
+	}
+
+	statusHistories, err := s.applicationRepo.ListApplicationStatusHistories(ctx, applicationIDs)
+	if err != nil {
+		return err
+	}
+
+	// Portfolios, videos, design samples and resumes which weren't submitted are kept under the user's prefix
+	objectKeys, err := s.s3Service.ListObjectKeys(ctx, s.cfg.S3Bucket, userObjectPrefix(user.ID))
+	if err != nil {
+		return err
+	}
+
+	downloadLogs, err := s.privacyRepo.ListDownloadLogsByObjectKeys(ctx, uniqueIDs(append(resumeObjectKeys(applications), objectKeys...)))
+	if err != nil {
+		return err
+	}
+
+	archive := zip.NewWriter(w)
+
+	for name, data := range map[string]interface{}{
+		"profile.json":          exportProfile(user),
+		"applications.json":     applications,
+		"status_history.json":   statusHistories,
+		"resume_downloads.json": exportDownloads(downloadLogs),
+	} {
+		if err := writeJSONEntry(archive, name, data); err != nil {
+			return err
+		}
+	}
+
+	exported := map[string]bool{}
+	for _, application := range applications {
+		if application.ResumeObjectKey == "" {
+			continue
+		}
+
+		name := fmt.Sprintf("resumes/%s/%s", application.ID, path.Base(application.ResumeObjectKey))
+		if err := s.writeObjectEntry(ctx, archive, name, application.ResumeObjectKey); err != nil {
+			return err
+		}
+		exported[application.ResumeObjectKey] = true
+	}
+
+	for _, objectKey := range objectKeys {
+		if exported[objectKey] {
+			continue
+		}
+
+		if err := s.writeObjectEntry(ctx, archive, "files/"+path.Base(objectKey), objectKey); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+// exportedProfile is the profile of a candidate as they export it, internal fields such as legal holds are left out.
+type exportedProfile struct {
+	ID           string         `json:"id"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	Role         model.UserRole `json:"Role"`
+	Email        string         `json:"Email"`
+	Name         string         `json:"Name"`
+	Locale       string         `json:"Locale"`
+	AnonymizedAt *time.Time     `json:",omitempty"`
+}
+
+func exportProfile(user *model.User) exportedProfile {
+	return exportedProfile{
+		ID:           user.ID,
+		CreatedAt:    user.CreatedAt,
+		UpdatedAt:    user.UpdatedAt,
+		Role:         user.Role,
+		Email:        user.Email,
+		Name:         user.Name,
+		Locale:       user.Locale,
+		AnonymizedAt: user.AnonymizedAt,
+	}
+}
+
+// exportedDownload is a download of a candidate's resume, who downloaded it from where is left out.
+type exportedDownload struct {
+	ObjectKey    string    `json:"object_key"`
+	DownloadedAt time.Time `json:"downloaded_at"`
+	Watermarked  bool      `json:"watermarked"`
+}
+
+func exportDownloads(downloadLogs []model.DownloadLog) []exportedDownload {
+	downloads := make([]exportedDownload, 0, len(downloadLogs))
+	for _, downloadLog := range downloadLogs {
+		downloads = append(downloads, exportedDownload{
+			ObjectKey:    downloadLog.ObjectKey,
+			DownloadedAt: downloadLog.CreatedAt,
+			Watermarked:  downloadLog.Watermarked,
+		})
+	}
+
+	return downloads
+}
+
+func writeJSONEntry(archive *zip.Writer, name string, data interface{}) error {
+	entry, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(entry)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(data)
+}
+
+func (s *PrivacyService) writeObjectEntry(ctx context.Context, archive *zip.Writer, name string, objectKey string) error {
+	return copyObjectToArchive(ctx, s.s3Service, s.cfg.S3Bucket, archive, name, objectKey)
+}
+
+// copyObjectToArchive adds an object of S3 to a ZIP archive, decrypted.
+func copyObjectToArchive(ctx context.Context, s3Service S3ServiceInterface, bucket string, archive *zip.Writer, name string, objectKey string) error {
+	object, err := s3Service.GetObject(ctx, bucket, objectKey)
+	if err != nil {
+		return err
+	}
+	defer object.Body.Close()
+
+	entry, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(entry, object.Body)
+	return err
+}
+
+func (s *PrivacyService) RequestErasure(ctx context.Context, user *model.User) (*model.ErasureRequest, error) {
+	// Requesting again doesn't open another request while one is still waiting for review.
+	request, err := s.privacyRepo.GetPendingErasureRequestByUser(ctx, user.ID)
+	if err == nil {
+		return request, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	return s.privacyRepo.CreateErasureRequest(ctx, user.ID)
+}
+
+func (s *PrivacyService) getPendingErasureRequest(ctx context.Context, requestID string) (*model.ErasureRequest, error) {
+	request, err := s.privacyRepo.GetErasureRequestByID(ctx, requestID)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.Status != model.ErasureStatusPending {
+		return nil, ErrErasureRequestReviewed
+	}
+
+	return request, nil
+}
+
+func (s *PrivacyService) ApproveErasure(ctx context.Context, reviewer *model.User, requestID string) (*model.ErasureRequest, error) {
+	request, err := s.getPendingErasureRequest(ctx, requestID)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, request.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.LegalHold {
+		return nil, ErrLegalHold
+	}
+
+	applications, err := s.privacyRepo.ListApplicationsByUser(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	objectKeys, err := s.s3Service.ListObjectKeys(ctx, s.cfg.S3Bucket, userObjectPrefix(user.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	// Object keys are prefixed with the user ID, so they're removed from download logs as well.
+	if err := s.privacyRepo.PseudonymizeDownloadLogs(ctx, uniqueIDs(append(resumeObjectKeys(applications), objectKeys...))); err != nil {
+		return nil, err
+	}
+
+	for _, application := range applications {
+		if application.PurgedAt != nil {
+			continue
+		}
+
+		// Applications are anonymized one by one, so a resume is deleted along with the last which has it
+		if err := deleteResume(ctx, s.s3Service, s.retentionRepo, s.cfg.S3Bucket, application); err != nil {
+			return nil, err
+		}
+
+		if err := s.retentionRepo.AnonymizeApplication(ctx, application, erasureReason); err != nil {
+			return nil, err
+		}
+	}
+
+	// Every other upload of the user is deleted along with its data key, whether it was submitted or not
+	for _, objectKey := range objectKeys {
+		if err := s.s3Service.DeleteObject(ctx, s.cfg.S3Bucket, objectKey); err != nil {
+			return nil, err
+		}
+	}
+
+	// Candidates without any application aren't anonymized along with their applications.
+	if err := s.retentionRepo.AnonymizeUser(ctx, user.ID, erasureReason); err != nil {
+		return nil, err
+	}
+
+	return s.privacyRepo.ReviewErasureRequest(ctx, request.ID, reviewer.ID, model.ErasureStatusCompleted, "")
+}
+
+func (s *PrivacyService) RejectErasure(ctx context.Context, reviewer *model.User, requestID string, reason string) (*model.ErasureRequest, error) {
+	request, err := s.getPendingErasureRequest(ctx, requestID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.privacyRepo.ReviewErasureRequest(ctx, request.ID, reviewer.ID, model.ErasureStatusRejected, reason)
+}
+
+// userObjectPrefix is the prefix of the keys of the objects a user uploads.
+func userObjectPrefix(userID string) string {
+	return userID + "/"
+}
+
+func resumeObjectKeys(applications []model.Application) []string {
+	objectKeys := []string{}
+	for _, application := range applications {
+		if application.ResumeObjectKey != "" {
+			objectKeys = append(objectKeys, application.ResumeObjectKey)
+		}
+	}
+
+	return objectKeys
+}
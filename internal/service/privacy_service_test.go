@@ -0,0 +1,89 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/rs/zerolog"
+
+	"fliqt/config"
+	"fliqt/internal/model"
+	"fliqt/internal/repository"
+	"fliqt/internal/util"
+)
+
+func TestPrivacyServiceExportProjections(t *testing.T) {
+	user := &model.User{Base: model.Base{ID: "cqanbg8cvavjpljmh7pg"}, Role: model.RoleCandidate, Name: "Alice", LegalHold: true, LegalHoldReason: "litigation"}
+	profile, err := json.Marshal(exportProfile(user))
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if strings.Contains(string(profile), "LegalHold") || !strings.Contains(string(profile), `"Name":"Alice"`) {
+		t.Errorf("expected the profile without legal holds, got %s", profile)
+	}
+
+	downloads, err := json.Marshal(exportDownloads([]model.DownloadLog{{
+		ObjectKey:   "cqanbg8cvavjpljmh7pg/resume.pdf",
+		UserID:      "cqan84gcvavjif3csp4g",
+		Watermarked: true,
+		ClientIP:    "203.0.113.1",
+		UserAgent:   "curl/8.0",
+	}}))
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	for _, leaked := range []string{"cqan84gcvavjif3csp4g", "203.0.113.1", "curl/8.0"} {
+		if strings.Contains(string(downloads), leaked) {
+			t.Errorf("expected downloads without %s, got %s", leaked, downloads)
+		}
+	}
+	if !strings.Contains(string(downloads), `"watermarked":true`) {
+		t.Errorf("expected downloads to tell they're watermarked, got %s", downloads)
+	}
+}
+
+func TestPrivacyServiceExportUploads(t *testing.T) {
+	db, mock, cleanup := util.SetupMockDB(t)
+	defer cleanup()
+
+	logger := zerolog.Nop()
+	user := &model.User{Base: model.Base{ID: "cqanbg8cvavjpljmh7pg"}, Role: model.RoleCandidate}
+	objects := &fakeObjectStore{objects: map[string]string{
+		"cqanbg8cvavjpljmh7pg/portfolio": "portfolio",
+		"cqanbg8cvavjpljmh7ph/resume":    "someone else's resume",
+	}}
+	s := NewPrivacyService(&config.Config{S3Bucket: "bucket"}, &logger, nil, repository.NewApplicationRepository(db, &logger), repository.NewPrivacyRepository(db, &logger), nil, objects)
+
+	mock.ExpectQuery("SELECT \\* FROM `applications` WHERE user_id = \\?").
+		WithArgs(user.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT \\* FROM `download_logs` WHERE object_key IN \\(\\?\\)").
+		WithArgs("cqanbg8cvavjpljmh7pg/portfolio").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	var buf bytes.Buffer
+	if err := s.Export(context.TODO(), user, &buf); err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	names := map[string]bool{}
+	for _, file := range archive.File {
+		names[file.Name] = true
+	}
+	if !names["files/portfolio"] || names["files/resume"] {
+		t.Errorf("Expected the user's portfolio only, got %v", names)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
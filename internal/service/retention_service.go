@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"fliqt/config"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"fliqt/internal/model"
+	"fliqt/internal/repository"
+)
+
+const retentionBatchSize = 100
+
+// TaskPurgeRetention is the background task which purges expired applications
+const TaskPurgeRetention = "retention.purge"
+
+// RetentionCandidate is an application whose retention period has expired.
+type RetentionCandidate struct {
+	ApplicationID   string    `json:"application_id"`
+	UserID          string    `json:"user_id"`
+	Status          string    `json:"status"`
+	ResumeObjectKey string    `json:"resume_object_key"`
+	UpdatedAt       time.Time `json:"updated_at"`
+	ExpiredAt       time.Time `json:"expired_at"`
+}
+
+type RetentionReport struct {
+	DryRun bool                 `json:"dry_run"`
+	Items  []RetentionCandidate `json:"items"`
+	Purged int                  `json:"purged"`
+	Failed int                  `json:"failed"`
+}
+
+type RetentionServiceInterface interface {
+	// Purge deletes resumes and anonymizes applications whose retention period has expired,
+	// nothing is changed on a dry run.
+	Purge(ctx context.Context, dryRun bool) (*RetentionReport, error)
+}
+
+type RetentionService struct {
+	cfg           *config.Config
+	logger        *zerolog.Logger
+	retentionRepo *repository.RetentionRepository
+	s3Service     S3ServiceInterface
+}
+
+func NewRetentionService(
+	cfg *config.Config,
+	logger *zerolog.Logger,
+	retentionRepo *repository.RetentionRepository,
+	s3Service S3ServiceInterface,
+) *RetentionService {
+	return &RetentionService{
+		cfg,
+		logger,
+		retentionRepo,
+		s3Service,
+	}
+}
+
+// periods returns the retention period of each application outcome, outcomes without a period are kept forever.
+func (s *RetentionService) periods() map[string]time.Duration {
+	periods := map[string]time.Duration{}
+	for status, days := range map[string]int{
+		model.ApplicationStatusRejected:  s.cfg.RetentionRejectedDays,
+		model.ApplicationStatusWithdrawn: s.cfg.RetentionWithdrawnDays,
+		model.ApplicationStatusHired:     s.cfg.RetentionHiredDays,
+	} {
+		if days > 0 {
+			periods[status] = time.Duration(days) * 24 * time.Hour
+		}
+	}
+
+	return periods
+}
+
+func (s *RetentionService) Purge(ctx context.Context, dryRun bool) (*RetentionReport, error) {
+	report := &RetentionReport{
+		DryRun: dryRun,
+		Items:  []RetentionCandidate{},
+	}
+
+	for status, period := range s.periods() {
+		before := time.Now().Add(-period)
+		afterID := ""
+
+		for {
+			applications, err := s.retentionRepo.ListExpiredApplications(ctx, status, before, afterID, retentionBatchSize)
+			if err != nil {
+				return report, err
+			}
+
+			if len(applications) == 0 {
+				break
+			}
+
+			for _, application := range applications {
+				report.Items = append(report.Items, RetentionCandidate{
+					ApplicationID:   application.ID,
+					UserID:          application.UserID,
+					Status:          application.Status,
+					ResumeObjectKey: application.ResumeObjectKey,
+					UpdatedAt:       application.UpdatedAt,
+					ExpiredAt:       application.UpdatedAt.Add(period),
+				})
+
+				if dryRun {
+					continue
+				}
+
+				if err := s.purgeApplication(ctx, application); err != nil {
+					// Keep purging the others, the failed one will be retried on the next run.
+					s.logger.Error().Err(err).Str("application_id", application.ID).Msg("failed to purge application")
+					report.Failed++
+					continue
+				}
+
+				report.Purged++
+			}
+
+			afterID = applications[len(applications)-1].ID
+		}
+	}
+
+	return report, nil
+}
+
+func (s *RetentionService) purgeApplication(ctx context.Context, application model.Application) error {
+	if err := deleteResume(ctx, s.s3Service, s.retentionRepo, s.cfg.S3Bucket, application); err != nil {
+		return err
+	}
+
+	return s.retentionRepo.AnonymizeApplication(ctx, application, fmt.Sprintf("retention_%s", application.Status))
+}
+
+// deleteResume deletes the resume of an application along with its data key, unless another application which
+// isn't purged has it too. It's deleted along with the last of them.
+func deleteResume(ctx context.Context, s3Service S3ServiceInterface, retentionRepo *repository.RetentionRepository, bucket string, application model.Application) error {
+	if application.ResumeObjectKey == "" {
+		return nil
+	}
+
+	shared, err := retentionRepo.IsResumeShared(ctx, application.ResumeObjectKey, application.ID)
+	if err != nil || shared {
+		return err
+	}
+
+	return s3Service.DeleteObject(ctx, bucket, application.ResumeObjectKey)
+}
@@ -2,89 +2,630 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"fliqt/config"
+	"fliqt/internal/apperror"
 	"fmt"
+	"io"
+	"net/http"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/go-redis/redis/v8"
 )
 
 const (
 	presignedUrlExpiration = 5 * time.Minute
 	presignedUrlCacheTTL   = 30 * time.Second
+
+	// Parts of a large file take a while to upload, so their URLs live longer.
+	presignedPartUrlExpiration = time.Hour
+	// S3 rejects multipart uploads with more parts than this.
+	maxMultipartParts = 10000
+)
+
+// TaskAbortStaleMultipartUploads is the background task which aborts incomplete multipart uploads
+const TaskAbortStaleMultipartUploads = "s3.abort_stale_multipart_uploads"
+
+var (
+	ErrMultipartUploadNotFound = apperror.New(http.StatusNotFound, "multipart_upload_not_found", "multipart upload not found")
+	ErrInvalidPartNumber       = apperror.New(http.StatusBadRequest, "invalid_part_number", "invalid part number")
 )
 
 type S3ServiceInterface interface {
-	PresignUpload(ctx context.Context, bucket, userID string, objectKey string, contentType string, fileSize int64) (string, error)
-	GetPresignDownloadURL(ctx context.Context, bucket, objectKey string) (string, error)
+	PresignUpload(ctx context.Context, bucket, userID string, objectKey string, contentType string, fileSize int64) (*PresignedUpload, error)
+	GetPresignDownloadURL(ctx context.Context, bucket, objectKey string) (*PresignedDownload, error)
+	GetObject(ctx context.Context, bucket, objectKey string) (*s3.GetObjectOutput, error)
+	PutObject(ctx context.Context, bucket, userID string, objectKey string, contentType string, body io.ReadSeeker) error
+	DeleteObject(ctx context.Context, bucket, objectKey string) error
+	// ListObjectKeys returns the keys of every object with the prefix
+	ListObjectKeys(ctx context.Context, bucket, prefix string) ([]string, error)
+
+	CreateMultipartUpload(ctx context.Context, bucket, userID string, objectKey string, contentType string, fileSize int64) (*MultipartUpload, error)
+	PresignUploadParts(ctx context.Context, bucket, userID string, uploadID string, partNumbers []int32) ([]PresignedPart, []UploadedPart, error)
+	CompleteMultipartUpload(ctx context.Context, bucket, userID string, uploadID string, parts []UploadedPart) (*MultipartUpload, error)
+	AbortMultipartUpload(ctx context.Context, bucket, userID string, uploadID string) error
+	AbortStaleMultipartUploads(ctx context.Context, bucket string, maxAge time.Duration) (int, error)
 }
 
 type S3Service struct {
 	cfg             *config.Config
 	redisClient     *redis.Client
+	s3Client        *s3.Client
 	s3PresignClient *s3.PresignClient
+	// encryptionService is nil when encryption is disabled
+	encryptionService EncryptionServiceInterface
 }
 
 func NewS3Service(
 	cfg *config.Config,
 	redisClient *redis.Client,
+	s3Client *s3.Client,
 	s3PresignClient *s3.PresignClient,
+	encryptionService EncryptionServiceInterface,
 ) *S3Service {
 	return &S3Service{
 		cfg,
 		redisClient,
+		s3Client,
 		s3PresignClient,
+		encryptionService,
+	}
+}
+
+type PresignedUpload struct {
+	ObjectKey   string            `json:"object_key"`
+	URL         string            `json:"url"`
+	ContentType string            `json:"content_type"`
+	FileSize    int64             `json:"file_size"`
+	Headers     map[string]string `json:"headers,omitempty"`
+}
+
+type PresignedDownload struct {
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// MultipartUpload is the upload session we keep in Redis, it lets clients resume an upload
+// and makes sure every part is presigned with the size declared when the upload was initiated.
+type MultipartUpload struct {
+	UploadID    string `json:"upload_id"`
+	ObjectKey   string `json:"object_key"`
+	UserID      string `json:"user_id"`
+	ContentType string `json:"content_type"`
+	FileSize    int64  `json:"file_size"`
+	PartSize    int64  `json:"part_size"`
+	PartCount   int32  `json:"part_count"`
+}
+
+type PresignedPart struct {
+	PartNumber int32             `json:"part_number"`
+	URL        string            `json:"url"`
+	Size       int64             `json:"size"`
+	Headers    map[string]string `json:"headers,omitempty"`
+}
+
+type UploadedPart struct {
+	PartNumber int32  `json:"part_number" binding:"required"`
+	ETag       string `json:"etag" binding:"required"`
+	Size       int64  `json:"size,omitempty"`
+}
+
+func (u *MultipartUpload) partSize(partNumber int32) int64 {
+	if partNumber == u.PartCount {
+		return u.FileSize - int64(u.PartCount-1)*u.PartSize
+	}
+
+	return u.PartSize
+}
+
+// generateDataKey creates the data key of a new object, it returns nil when encryption is disabled.
+func (s *S3Service) generateDataKey(ctx context.Context, userID string, objectKey string) (*SSECustomerKey, error) {
+	if s.encryptionService == nil {
+		return nil, nil
+	}
+
+	// Every candidate is a tenant, so their resumes are wrapped by their own keys.
+	return s.encryptionService.GenerateDataKey(ctx, userID, objectKey)
+}
+
+// applySSEC returns the SSE-C algorithm, key and key MD5 of requests of an object, they're nil when the object
+// isn't encrypted.
+func applySSEC(sseKey *SSECustomerKey) (*string, *string, *string) {
+	if sseKey == nil {
+		return nil, nil, nil
+	}
+
+	return aws.String(sseKey.Algorithm()), aws.String(sseKey.KeyBase64()), aws.String(sseKey.KeyMD5Base64())
+}
+
+// getDataKey returns the data key of an object, it returns nil when the object isn't encrypted.
+func (s *S3Service) getDataKey(ctx context.Context, objectKey string) (*SSECustomerKey, error) {
+	if s.encryptionService == nil {
+		return nil, nil
 	}
+
+	return s.encryptionService.GetDataKey(ctx, objectKey)
 }
 
-func (s *S3Service) PresignUpload(ctx context.Context, bucket string, userID string, objectKey string, contentType string, fileSize int64) (string, error) {
+// PresignUpload presigns a single PUT upload. When encryption is enabled, clients have to send the
+// returned headers along with the upload, so S3 encrypts the object with its data key.
+func (s *S3Service) PresignUpload(ctx context.Context, bucket string, userID string, objectKey string, contentType string, fileSize int64) (*PresignedUpload, error) {
 	cacheKey := fmt.Sprintf("upload_tmp:%s/%s", bucket, userID)
-	previousPresignedURL, err := s.redisClient.Get(ctx, cacheKey).Result()
-	if err == nil {
-		return previousPresignedURL, nil
+	if data, err := s.redisClient.Get(ctx, cacheKey).Bytes(); err == nil {
+		var previous PresignedUpload
+		if err := json.Unmarshal(data, &previous); err == nil && previous.ContentType == contentType && previous.FileSize == fileSize {
+			recordPresign(ctx, presignOperationUpload, "cached")
+			return &previous, nil
+		}
+	}
+
+	sseKey, err := s.generateDataKey(ctx, userID, objectKey)
+	if err != nil {
+		return nil, err
 	}
 
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(objectKey),
+		// Ensure the loading file is the same size and same type of presinged file.
+		ContentType:   aws.String(contentType),
+		ContentLength: aws.Int64(fileSize),
+	}
+	input.SSECustomerAlgorithm, input.SSECustomerKey, input.SSECustomerKeyMD5 = applySSEC(sseKey)
+
 	// Get the presigned URL from S3
 	s3Req, err := s.s3PresignClient.PresignPutObject(ctx,
-		&s3.PutObjectInput{
-			Bucket: aws.String(bucket),
-			Key:    aws.String(objectKey),
-			// Ensure the loading file is the same size and same type of presinged file.
-			ContentType:   aws.String(contentType),
-			ContentLength: aws.Int64(fileSize),
-		},
+		input,
 		func(po *s3.PresignOptions) {
 			po.Expires = presignedUrlExpiration
 		},
 	)
+	recordPresign(ctx, presignOperationUpload, presignOutcome(err))
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	upload := &PresignedUpload{
+		ObjectKey:   objectKey,
+		URL:         s3Req.URL,
+		ContentType: contentType,
+		FileSize:    fileSize,
+	}
+	if sseKey != nil {
+		upload.Headers = sseKey.Headers()
+	}
+
+	data, err := json.Marshal(upload)
+	if err != nil {
+		return upload, err
 	}
 
 	// Cache the presigned URL, ensure the presigned URL can't be generated too frequently.
-	if err := s.redisClient.Set(ctx, cacheKey, s3Req.URL, presignedUrlCacheTTL).Err(); err != nil {
-		return s3Req.URL, err
+	if err := s.redisClient.Set(ctx, cacheKey, data, presignedUrlCacheTTL).Err(); err != nil {
+		return upload, err
 	}
 
-	return s3Req.URL, nil
+	return upload, nil
 }
 
-func (s *S3Service) GetPresignDownloadURL(ctx context.Context, bucket string, objectKey string) (string, error) {
+// GetPresignDownloadURL presigns a download. Encrypted objects can only be downloaded with the
+// returned headers, use GetObject to stream them through the API instead.
+func (s *S3Service) GetPresignDownloadURL(ctx context.Context, bucket string, objectKey string) (*PresignedDownload, error) {
+	sseKey, err := s.getDataKey(ctx, objectKey)
+	if err != nil {
+		return nil, err
+	}
+
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(objectKey),
+	}
+	input.SSECustomerAlgorithm, input.SSECustomerKey, input.SSECustomerKeyMD5 = applySSEC(sseKey)
+
 	// Get the presigned URL from S3
 	s3Req, err := s.s3PresignClient.PresignGetObject(ctx,
-		&s3.GetObjectInput{
-			Bucket: aws.String(bucket),
-			Key:    aws.String(objectKey),
-		},
+		input,
 		func(po *s3.PresignOptions) {
 			po.Expires = presignedUrlExpiration
 		},
 	)
+	recordPresign(ctx, presignOperationDownload, presignOutcome(err))
+	if err != nil {
+		return nil, err
+	}
+
+	download := &PresignedDownload{
+		URL: s3Req.URL,
+	}
+	if sseKey != nil {
+		download.Headers = sseKey.Headers()
+	}
+
+	return download, nil
+}
+
+// GetObject reads an object from S3, encrypted objects are decrypted with their data keys.
+// The caller must close the body of the output.
+func (s *S3Service) GetObject(ctx context.Context, bucket string, objectKey string) (*s3.GetObjectOutput, error) {
+	sseKey, err := s.getDataKey(ctx, objectKey)
+	if err != nil {
+		return nil, err
+	}
+
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(objectKey),
+	}
+	input.SSECustomerAlgorithm, input.SSECustomerKey, input.SSECustomerKeyMD5 = applySSEC(sseKey)
+
+	return s.s3Client.GetObject(ctx, input)
+}
+
+// PutObject uploads an object generated by the server, it's encrypted with a data key of the user who owns it.
+func (s *S3Service) PutObject(ctx context.Context, bucket string, userID string, objectKey string, contentType string, body io.ReadSeeker) error {
+	sseKey, err := s.generateDataKey(ctx, userID, objectKey)
+	if err != nil {
+		return err
+	}
+
+	input := &s3.PutObjectInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(objectKey),
+		ContentType: aws.String(contentType),
+		Body:        body,
+	}
+	input.SSECustomerAlgorithm, input.SSECustomerKey, input.SSECustomerKeyMD5 = applySSEC(sseKey)
+
+	_, err = s.s3Client.PutObject(ctx, input)
+	return err
+}
+
+// DeleteObject deletes an object from S3 along with its data key.
+func (s *S3Service) DeleteObject(ctx context.Context, bucket string, objectKey string) error {
+	if _, err := s.s3Client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(objectKey),
+	}); err != nil {
+		return err
+	}
+
+	if s.encryptionService == nil {
+		return nil
+	}
+
+	return s.encryptionService.DeleteDataKey(ctx, objectKey)
+}
+
+func (s *S3Service) ListObjectKeys(ctx context.Context, bucket string, prefix string) ([]string, error) {
+	objectKeys := []string{}
+	paginator := s3.NewListObjectsV2Paginator(s.s3Client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, object := range output.Contents {
+			objectKeys = append(objectKeys, aws.ToString(object.Key))
+		}
+	}
+
+	return objectKeys, nil
+}
+
+func multipartUploadCacheKey(bucket, uploadID string) string {
+	return fmt.Sprintf("multipart_upload:%s/%s", bucket, uploadID)
+}
+
+func (s *S3Service) getMultipartUpload(ctx context.Context, bucket, userID, uploadID string) (*MultipartUpload, error) {
+	data, err := s.redisClient.Get(ctx, multipartUploadCacheKey(bucket, uploadID)).Bytes()
+	if err == redis.Nil {
+		return nil, ErrMultipartUploadNotFound
+	}
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	var upload MultipartUpload
+	if err := json.Unmarshal(data, &upload); err != nil {
+		return nil, err
 	}
 
-	return s3Req.URL, nil
+	// Don't leak the existence of other users' uploads.
+	if upload.UserID != userID {
+		return nil, ErrMultipartUploadNotFound
+	}
+
+	return &upload, nil
+}
+
+// CreateMultipartUpload initiates a multipart upload and remembers its session until it's completed,
+// aborted, or cleaned up by AbortStaleMultipartUploads.
+func (s *S3Service) CreateMultipartUpload(ctx context.Context, bucket string, userID string, objectKey string, contentType string, fileSize int64) (*MultipartUpload, error) {
+	partSize := s.cfg.S3MultipartPartSize
+	// Grow the part size when the file would need more parts than S3 allows.
+	if minPartSize := (fileSize + maxMultipartParts - 1) / maxMultipartParts; partSize < minPartSize {
+		partSize = minPartSize
+	}
+
+	sseKey, err := s.generateDataKey(ctx, userID, objectKey)
+	if err != nil {
+		return nil, err
+	}
+
+	input := &s3.CreateMultipartUploadInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(objectKey),
+		ContentType: aws.String(contentType),
+	}
+	input.SSECustomerAlgorithm, input.SSECustomerKey, input.SSECustomerKeyMD5 = applySSEC(sseKey)
+
+	output, err := s.s3Client.CreateMultipartUpload(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	upload := &MultipartUpload{
+		UploadID:    aws.ToString(output.UploadId),
+		ObjectKey:   objectKey,
+		UserID:      userID,
+		ContentType: contentType,
+		FileSize:    fileSize,
+		PartSize:    partSize,
+		PartCount:   int32((fileSize + partSize - 1) / partSize),
+	}
+
+	data, err := json.Marshal(upload)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.redisClient.Set(ctx, multipartUploadCacheKey(bucket, upload.UploadID), data, s.cfg.S3MultipartMaxAge).Err(); err != nil {
+		return nil, err
+	}
+
+	return upload, nil
+}
+
+// PresignUploadParts presigns the given parts, or every part which hasn't been uploaded yet when
+// no part number is given. Already uploaded parts are returned as well, so clients can resume.
+func (s *S3Service) PresignUploadParts(ctx context.Context, bucket string, userID string, uploadID string, partNumbers []int32) ([]PresignedPart, []UploadedPart, error) {
+	upload, err := s.getMultipartUpload(ctx, bucket, userID, uploadID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	uploaded, err := s.listUploadedParts(ctx, bucket, upload)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(partNumbers) == 0 {
+		done := make(map[int32]bool, len(uploaded))
+		for _, part := range uploaded {
+			done[part.PartNumber] = true
+		}
+
+		for partNumber := int32(1); partNumber <= upload.PartCount; partNumber++ {
+			if !done[partNumber] {
+				partNumbers = append(partNumbers, partNumber)
+			}
+		}
+	}
+
+	sseKey, err := s.getDataKey(ctx, upload.ObjectKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	parts := make([]PresignedPart, 0, len(partNumbers))
+	for _, partNumber := range partNumbers {
+		if partNumber < 1 || partNumber > upload.PartCount {
+			return nil, nil, ErrInvalidPartNumber
+		}
+
+		size := upload.partSize(partNumber)
+		input := &s3.UploadPartInput{
+			Bucket:     aws.String(bucket),
+			Key:        aws.String(upload.ObjectKey),
+			UploadId:   aws.String(upload.UploadID),
+			PartNumber: aws.Int32(partNumber),
+			// Ensure every part has exactly the size we expect, so the whole file can't exceed the declared size.
+			ContentLength: aws.Int64(size),
+		}
+		input.SSECustomerAlgorithm, input.SSECustomerKey, input.SSECustomerKeyMD5 = applySSEC(sseKey)
+
+		s3Req, err := s.s3PresignClient.PresignUploadPart(ctx,
+			input,
+			func(po *s3.PresignOptions) {
+				po.Expires = presignedPartUrlExpiration
+			},
+		)
+		recordPresign(ctx, presignOperationUploadPart, presignOutcome(err))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		part := PresignedPart{
+			PartNumber: partNumber,
+			URL:        s3Req.URL,
+			Size:       size,
+		}
+		if sseKey != nil {
+			part.Headers = sseKey.Headers()
+		}
+
+		parts = append(parts, part)
+	}
+
+	return parts, uploaded, nil
+}
+
+func (s *S3Service) listUploadedParts(ctx context.Context, bucket string, upload *MultipartUpload) ([]UploadedPart, error) {
+	var parts []UploadedPart
+
+	paginator := s3.NewListPartsPaginator(s.s3Client, &s3.ListPartsInput{
+		Bucket:   aws.String(bucket),
+		Key:      aws.String(upload.ObjectKey),
+		UploadId: aws.String(upload.UploadID),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, part := range page.Parts {
+			parts = append(parts, UploadedPart{
+				PartNumber: aws.ToInt32(part.PartNumber),
+				ETag:       aws.ToString(part.ETag),
+				Size:       aws.ToInt64(part.Size),
+			})
+		}
+	}
+
+	return parts, nil
+}
+
+// CompleteMultipartUpload assembles the uploaded parts into the final object.
+func (s *S3Service) CompleteMultipartUpload(ctx context.Context, bucket string, userID string, uploadID string, parts []UploadedPart) (*MultipartUpload, error) {
+	upload, err := s.getMultipartUpload(ctx, bucket, userID, uploadID)
+	if err != nil {
+		return nil, err
+	}
+
+	completedParts, err := completedMultipartParts(parts, upload.PartCount)
+	if err != nil {
+		return nil, err
+	}
+
+	sseKey, err := s.getDataKey(ctx, upload.ObjectKey)
+	if err != nil {
+		return nil, err
+	}
+
+	input := &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(bucket),
+		Key:             aws.String(upload.ObjectKey),
+		UploadId:        aws.String(upload.UploadID),
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: completedParts},
+	}
+	input.SSECustomerAlgorithm, input.SSECustomerKey, input.SSECustomerKeyMD5 = applySSEC(sseKey)
+
+	if _, err := s.s3Client.CompleteMultipartUpload(ctx, input); err != nil {
+		return nil, err
+	}
+
+	if err := s.redisClient.Del(ctx, multipartUploadCacheKey(bucket, uploadID)).Err(); err != nil {
+		return upload, err
+	}
+
+	return upload, nil
+}
+
+// completedMultipartParts returns every part of an upload of partCount parts exactly once, in the ascending order
+// S3 requires whatever order the client sent them in.
+func completedMultipartParts(parts []UploadedPart, partCount int32) ([]types.CompletedPart, error) {
+	if len(parts) != int(partCount) {
+		return nil, ErrInvalidPartNumber
+	}
+
+	completedParts := make([]types.CompletedPart, partCount)
+	for _, part := range parts {
+		if part.PartNumber < 1 || part.PartNumber > partCount || completedParts[part.PartNumber-1].PartNumber != nil {
+			return nil, ErrInvalidPartNumber
+		}
+
+		completedParts[part.PartNumber-1] = types.CompletedPart{
+			PartNumber: aws.Int32(part.PartNumber),
+			ETag:       aws.String(part.ETag),
+		}
+	}
+
+	return completedParts, nil
+}
+
+// deleteDataKey deletes the data key of an object which was never completed, if it has one.
+func (s *S3Service) deleteDataKey(ctx context.Context, objectKey string) error {
+	if s.encryptionService == nil {
+		return nil
+	}
+
+	return s.encryptionService.DeleteDataKey(ctx, objectKey)
+}
+
+// AbortMultipartUpload aborts a multipart upload and frees the storage of its uploaded parts.
+func (s *S3Service) AbortMultipartUpload(ctx context.Context, bucket string, userID string, uploadID string) error {
+	upload, err := s.getMultipartUpload(ctx, bucket, userID, uploadID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.s3Client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(bucket),
+		Key:      aws.String(upload.ObjectKey),
+		UploadId: aws.String(upload.UploadID),
+	}); err != nil {
+		return err
+	}
+
+	if err := s.deleteDataKey(ctx, upload.ObjectKey); err != nil {
+		return err
+	}
+
+	return s.redisClient.Del(ctx, multipartUploadCacheKey(bucket, uploadID)).Err()
+}
+
+// AbortStaleMultipartUploads aborts every multipart upload initiated before maxAge,
+// uploaded parts of incomplete uploads are billed until they're aborted.
+func (s *S3Service) AbortStaleMultipartUploads(ctx context.Context, bucket string, maxAge time.Duration) (int, error) {
+	deadline := time.Now().Add(-maxAge)
+	aborted := 0
+
+	input := &s3.ListMultipartUploadsInput{
+		Bucket: aws.String(bucket),
+	}
+	for {
+		output, err := s.s3Client.ListMultipartUploads(ctx, input)
+		if err != nil {
+			return aborted, err
+		}
+
+		for _, upload := range output.Uploads {
+			if upload.Initiated == nil || upload.Initiated.After(deadline) {
+				continue
+			}
+
+			if _, err := s.s3Client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+				Bucket:   aws.String(bucket),
+				Key:      upload.Key,
+				UploadId: upload.UploadId,
+			}); err != nil {
+				return aborted, err
+			}
+
+			if err := s.deleteDataKey(ctx, aws.ToString(upload.Key)); err != nil {
+				return aborted, err
+			}
+
+			if err := s.redisClient.Del(ctx, multipartUploadCacheKey(bucket, aws.ToString(upload.UploadId))).Err(); err != nil {
+				return aborted, err
+			}
+
+			aborted++
+		}
+
+		if !aws.ToBool(output.IsTruncated) {
+			return aborted, nil
+		}
+
+		input.KeyMarker = output.NextKeyMarker
+		input.UploadIdMarker = output.NextUploadIdMarker
+	}
 }
@@ -0,0 +1,28 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestCompletedMultipartParts(t *testing.T) {
+	parts, err := completedMultipartParts([]UploadedPart{{PartNumber: 2, ETag: "b"}, {PartNumber: 1, ETag: "a"}}, 2)
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if aws.ToInt32(parts[0].PartNumber) != 1 || aws.ToString(parts[0].ETag) != "a" || aws.ToInt32(parts[1].PartNumber) != 2 {
+		t.Errorf("Expected parts in ascending order, got %+v", parts)
+	}
+
+	for name, parts := range map[string][]UploadedPart{
+		"duplicate":    {{PartNumber: 1}, {PartNumber: 1}},
+		"out of range": {{PartNumber: 1}, {PartNumber: 3}},
+		"missing":      {{PartNumber: 1}},
+	} {
+		if _, err := completedMultipartParts(parts, 2); !errors.Is(err, ErrInvalidPartNumber) {
+			t.Errorf("%s: expected ErrInvalidPartNumber, got %v", name, err)
+		}
+	}
+}
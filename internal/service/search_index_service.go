@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+
+	"fliqt/internal/event"
+	"fliqt/internal/repository"
+	"fliqt/internal/search"
+)
+
+// SearchIndexGroup is the consumer group which keeps the search index of an API replica in sync, replicas are
+// told apart by hostname.
+func SearchIndexGroup(hostname string) string {
+	return "search_index:" + hostname
+}
+
+// SearchIndexService keeps the job search index in sync with the jobs.
+type SearchIndexService struct {
+	logger  *zerolog.Logger
+	jobRepo *repository.JobRepository
+	index   search.JobSearchIndex
+}
+
+func NewSearchIndexService(
+	logger *zerolog.Logger,
+	jobRepo *repository.JobRepository,
+	index search.JobSearchIndex,
+) *SearchIndexService {
+	return &SearchIndexService{
+		logger:  logger,
+		jobRepo: jobRepo,
+		index:   index,
+	}
+}
+
+// Handle indexes a job as it is when one of its events is handled, so redelivered and reordered events leave
+// the index up to date. Jobs which don't exist anymore are removed.
+func (s *SearchIndexService) Handle(ctx context.Context, e event.Event) error {
+	if e.AggregateType != event.AggregateJob {
+		return nil
+	}
+
+	document, err := s.jobRepo.GetJobDocument(ctx, e.AggregateID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return s.index.DeleteJob(ctx, e.AggregateID)
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.index.IndexJobs(ctx, []search.JobDocument{*document})
+}
+
+// Rebuild indexes every job, it returns the number of them.
+func (s *SearchIndexService) Rebuild(ctx context.Context) (int, error) {
+	count := 0
+	err := s.jobRepo.ListJobDocuments(ctx, func(documents []search.JobDocument) error {
+		if err := s.index.IndexJobs(ctx, documents); err != nil {
+			return err
+		}
+
+		count += len(documents)
+		s.logger.Debug().Int("count", count).Msg("indexed jobs")
+		return nil
+	})
+
+	return count, err
+}
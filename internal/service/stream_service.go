@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fliqt/config"
+	"net/http"
+
+	"github.com/rs/zerolog"
+
+	"fliqt/internal/apperror"
+	"fliqt/internal/event"
+	"fliqt/internal/model"
+)
+
+var ErrInvalidLastEventID = apperror.New(http.StatusBadRequest, "invalid_last_event_id", "invalid Last-Event-ID")
+
+type StreamServiceInterface interface {
+	// Subscribe returns the events the user can see, starting after lastEventID when it's given.
+	// The channel is closed when ctx is done or the subscriber falls too far behind.
+	Subscribe(ctx context.Context, user *model.User, lastEventID string) (<-chan event.Event, error)
+}
+
+type StreamService struct {
+	cfg    *config.Config
+	logger *zerolog.Logger
+	hub    *event.Hub
+}
+
+func NewStreamService(
+	cfg *config.Config,
+	logger *zerolog.Logger,
+	hub *event.Hub,
+) *StreamService {
+	return &StreamService{
+		cfg,
+		logger,
+		hub,
+	}
+}
+
+func (s *StreamService) Subscribe(ctx context.Context, user *model.User, lastEventID string) (<-chan event.Event, error) {
+	if lastEventID != "" && !event.ValidStreamID(lastEventID) {
+		return nil, ErrInvalidLastEventID
+	}
+
+	// Subscribe before replaying, so nothing published in between is missed.
+	live, unsubscribe := s.hub.Subscribe()
+
+	var replay []event.Event
+	if lastEventID != "" {
+		var err error
+		replay, err = s.hub.Replay(ctx, lastEventID, s.cfg.StreamReplayLimit)
+		if err != nil {
+			unsubscribe()
+			return nil, err
+		}
+	}
+
+	out := make(chan event.Event)
+	go func() {
+		defer close(out)
+		defer unsubscribe()
+
+		last := lastEventID
+		send := func(e event.Event) bool {
+			// Events replayed already are published live as well.
+			if last != "" && event.CompareStreamIDs(e.StreamID, last) <= 0 {
+				return true
+			}
+			last = e.StreamID
+
+			if !canReceive(user, e) {
+				return true
+			}
+
+			select {
+			case out <- e:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		for _, e := range replay {
+			if !send(e) {
+				return
+			}
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case e, ok := <-live:
+				if !ok || !send(e) {
+					return
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// applicationParties returns the candidate of the application an event is about, and the interviewer it's
+// assigned to.
+func applicationParties(e event.Event) (string, string) {
+	var payload struct {
+		UserID          string  `json:"user_id"`
+		AssigneeID      string  `json:"assignee_id"`
+		ModelUserID     string  `json:"UserID"`
+		ModelAssigneeID *string `json:"AssigneeID"`
+	}
+	if err := json.Unmarshal(e.Payload, &payload); err != nil {
+		return "", ""
+	}
+
+	// Submitted applications are the model itself
+	if payload.ModelUserID != "" {
+		assigneeID := ""
+		if payload.ModelAssigneeID != nil {
+			assigneeID = *payload.ModelAssigneeID
+		}
+		return payload.ModelUserID, assigneeID
+	}
+
+	return payload.UserID, payload.AssigneeID
+}
+
+// canReceive decides whether a user can see an event. Everyone sees changes of jobs, while HR sees every change
+// of applications, interviewers the ones assigned to them and candidates their own.
+func canReceive(user *model.User, e event.Event) bool {
+	switch e.Type {
+	case event.JobCreated, event.JobUpdated, event.JobDeleted:
+		return true
+	case event.ApplicationSubmitted, event.ApplicationStatusChanged:
+		ownerID, assigneeID := applicationParties(e)
+		switch user.Role {
+		case model.RoleHR:
+			return true
+		case model.RoleInteviewer:
+			return assigneeID == user.ID
+		default:
+			return ownerID == user.ID
+		}
+	default:
+		return user.Role == model.RoleHR
+	}
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+
+	"fliqt/internal/event"
+	"fliqt/internal/model"
+)
+
+func TestStreamServiceCanReceive(t *testing.T) {
+	hr := &model.User{Base: model.Base{ID: "cqan84gcvavjif3csp4g"}, Role: model.RoleHR}
+	candidate := &model.User{Base: model.Base{ID: "cqanbg8cvavjpljmh7pg"}, Role: model.RoleCandidate}
+	otherCandidate := &model.User{Base: model.Base{ID: "cqanbg8cvavjpljmh7ph"}, Role: model.RoleCandidate}
+	interviewer := &model.User{Base: model.Base{ID: "cqanb5gcvavjneudu13g"}, Role: model.RoleInteviewer}
+	otherInterviewer := &model.User{Base: model.Base{ID: "cqanb5gcvavjneudu13h"}, Role: model.RoleInteviewer}
+
+	submitted, _ := json.Marshal(model.Application{UserID: candidate.ID})
+	assigned, _ := json.Marshal(model.Application{UserID: candidate.ID, AssigneeID: &interviewer.ID})
+	statusChanged, _ := json.Marshal(event.ApplicationStatusChangedPayload{UserID: candidate.ID, AssigneeID: interviewer.ID, ToStatus: model.ApplicationStatusHired})
+
+	for _, tc := range []struct {
+		user     *model.User
+		e        event.Event
+		expected bool
+	}{
+		{hr, event.Event{Type: event.ApplicationSubmitted, Payload: submitted}, true},
+		{candidate, event.Event{Type: event.ApplicationSubmitted, Payload: submitted}, true},
+		{otherCandidate, event.Event{Type: event.ApplicationSubmitted, Payload: submitted}, false},
+		{candidate, event.Event{Type: event.ApplicationStatusChanged, Payload: statusChanged}, true},
+		{otherCandidate, event.Event{Type: event.ApplicationStatusChanged, Payload: statusChanged}, false},
+		{interviewer, event.Event{Type: event.ApplicationSubmitted, Payload: submitted}, false},
+		{interviewer, event.Event{Type: event.ApplicationSubmitted, Payload: assigned}, true},
+		{interviewer, event.Event{Type: event.ApplicationStatusChanged, Payload: statusChanged}, true},
+		{otherInterviewer, event.Event{Type: event.ApplicationStatusChanged, Payload: statusChanged}, false},
+		{otherCandidate, event.Event{Type: event.JobDeleted, Payload: json.RawMessage(`{}`)}, true},
+		{hr, event.Event{Type: event.ApplicationPurged, Payload: json.RawMessage(`{}`)}, true},
+		{candidate, event.Event{Type: event.ApplicationPurged, Payload: json.RawMessage(`{}`)}, false},
+	} {
+		if actual := canReceive(tc.user, tc.e); actual != tc.expected {
+			t.Errorf("expected %s to receive %s: %v, got %v", tc.user.Role, tc.e.Type, tc.expected, actual)
+		}
+	}
+}
+
+func TestCompareStreamIDs(t *testing.T) {
+	if event.CompareStreamIDs("1700000000000-1", "1700000000000-0") != 1 {
+		t.Error("expected a later sequence to be greater")
+	}
+	if event.CompareStreamIDs("999-9", "1000-0") != -1 {
+		t.Error("expected stream IDs to be compared numerically")
+	}
+	if event.CompareStreamIDs("1000-0", "1000-0") != 0 {
+		t.Error("expected equal stream IDs")
+	}
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	pdfmodel "github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+
+	"fliqt/config"
+	"fliqt/internal/apperror"
+)
+
+const (
+	// A visible, diagonal stamp over every page.
+	visibleWatermarkStyle = "fontname:Helvetica, points:18, rotation:45, opacity:0.2, fillcolor:#808080, scalefactor:0.9 rel"
+	// An invisible stamp, it's not rendered but stays in the text layer of every page.
+	invisibleWatermarkStyle = "fontname:Helvetica, points:4, rotation:0, opacity:0, position:bl, scalefactor:0.5 rel"
+
+	documentConversionTimeout = time.Minute
+)
+
+var (
+	ErrUnsupportedDocument = apperror.New(http.StatusUnprocessableEntity, "unsupported_document", "document type is not supported")
+	ErrConversionFailed    = errors.New("failed to convert document to PDF")
+)
+
+// Watermark identifies who downloaded a document and when.
+type Watermark struct {
+	DownloadID string
+	ViewerID   string
+	ViewerRole string
+	Timestamp  time.Time
+}
+
+func (w Watermark) String() string {
+	return fmt.Sprintf("CONFIDENTIAL - %s (%s) - %s - %s", w.ViewerID, w.ViewerRole, w.Timestamp.UTC().Format(time.RFC3339), w.DownloadID)
+}
+
+type WatermarkServiceInterface interface {
+	// Render converts the document to PDF and stamps the watermark on every page of it.
+	Render(ctx context.Context, document io.Reader, watermark Watermark) ([]byte, error)
+}
+
+type WatermarkService struct {
+	converterCommand string
+}
+
+func NewWatermarkService(cfg *config.Config) *WatermarkService {
+	// Don't let pdfcpu create its config directory, the API may run on a read-only filesystem.
+	pdfmodel.ConfigPath = "disable"
+
+	return &WatermarkService{
+		converterCommand: cfg.DocumentConverterCommand,
+	}
+}
+
+func (s *WatermarkService) Render(ctx context.Context, document io.Reader, watermark Watermark) ([]byte, error) {
+	data, err := io.ReadAll(document)
+	if err != nil {
+		return nil, err
+	}
+
+	pdf, err := s.toPDF(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+
+	visible, err := api.TextWatermark(watermark.String(), visibleWatermarkStyle, true, false, types.POINTS)
+	if err != nil {
+		return nil, err
+	}
+
+	invisible, err := api.TextWatermark(fmt.Sprintf("download-id:%s viewer:%s", watermark.DownloadID, watermark.ViewerID), invisibleWatermarkStyle, true, false, types.POINTS)
+	if err != nil {
+		return nil, err
+	}
+
+	pageCount, err := api.PageCount(bytes.NewReader(pdf), pdfmodel.NewDefaultConfiguration())
+	if err != nil {
+		return nil, err
+	}
+
+	watermarks := make(map[int][]*pdfmodel.Watermark, pageCount)
+	for page := 1; page <= pageCount; page++ {
+		watermarks[page] = []*pdfmodel.Watermark{visible, invisible}
+	}
+
+	var stamped bytes.Buffer
+	if err := api.AddWatermarksSliceMap(bytes.NewReader(pdf), &stamped, watermarks, pdfmodel.NewDefaultConfiguration()); err != nil {
+		return nil, err
+	}
+
+	// Also keep the identity in the document properties, they survive even when pages are extracted as images.
+	var result bytes.Buffer
+	if err := api.AddProperties(bytes.NewReader(stamped.Bytes()), &result, map[string]string{
+		"DownloadID": watermark.DownloadID,
+		"Viewer":     watermark.ViewerID,
+		"ViewedAt":   watermark.Timestamp.UTC().Format(time.RFC3339),
+	}, pdfmodel.NewDefaultConfiguration()); err != nil {
+		return nil, err
+	}
+
+	return result.Bytes(), nil
+}
+
+// toPDF converts images and Word documents to PDF, the type is detected from the content rather than
+// trusting the content type declared on upload.
+func (s *WatermarkService) toPDF(ctx context.Context, data []byte) ([]byte, error) {
+	mime := mimetype.Detect(data)
+
+	switch {
+	case mime.Is("application/pdf"):
+		return data, nil
+	case mime.Is("image/jpeg"), mime.Is("image/png"):
+		var pdf bytes.Buffer
+		if err := api.ImportImages(nil, &pdf, []io.Reader{bytes.NewReader(data)}, nil, pdfmodel.NewDefaultConfiguration()); err != nil {
+			return nil, err
+		}
+		return pdf.Bytes(), nil
+	case mime.Is("application/vnd.openxmlformats-officedocument.wordprocessingml.document"):
+		return s.convert(ctx, data, ".docx")
+	case mime.Is("application/msword"), mime.Is("application/x-ole-storage"):
+		return s.convert(ctx, data, ".doc")
+	default:
+		return nil, ErrUnsupportedDocument
+	}
+}
+
+// convert runs the converter command (LibreOffice by default) to convert a document to PDF.
+func (s *WatermarkService) convert(ctx context.Context, data []byte, ext string) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "fliqt-convert-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	input := filepath.Join(dir, "document"+ext)
+	if err := os.WriteFile(input, data, 0o600); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, documentConversionTimeout)
+	defer cancel()
+
+	args := strings.Fields(s.converterCommand)
+	if len(args) == 0 {
+		return nil, ErrConversionFailed
+	}
+	args = append(args, "--headless", "--convert-to", "pdf", "--outdir", dir, input)
+
+	if output, err := exec.CommandContext(ctx, args[0], args[1:]...).CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("%w: %v: %s", ErrConversionFailed, err, output)
+	}
+
+	pdf, err := os.ReadFile(filepath.Join(dir, "document.pdf"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrConversionFailed, err)
+	}
+
+	return pdf, nil
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+
+	"fliqt/config"
+)
+
+func TestWatermarkServiceRender(t *testing.T) {
+	s := NewWatermarkService(&config.Config{})
+
+	var img bytes.Buffer
+	if err := png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 200, 300))); err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+
+	pdf, err := s.Render(context.TODO(), &img, Watermark{
+		DownloadID: "cqanbg8cvavjpljmh7pg",
+		ViewerID:   "cqan84gcvavjif3csp4g",
+		ViewerRole: "hr",
+		Timestamp:  time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+
+	properties, err := api.Properties(bytes.NewReader(pdf), nil)
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if properties["DownloadID"] != "cqanbg8cvavjpljmh7pg" {
+		t.Errorf("expected download ID in properties, got %v", properties)
+	}
+
+	hasWatermarks, err := api.HasWatermarks(bytes.NewReader(pdf), nil)
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if !hasWatermarks {
+		t.Errorf("expected the document to be watermarked")
+	}
+
+	if _, err := s.Render(context.TODO(), bytes.NewReader([]byte("plain text")), Watermark{}); err != ErrUnsupportedDocument {
+		t.Errorf("expected ErrUnsupportedDocument, got %v", err)
+	}
+}
@@ -0,0 +1,286 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fliqt/config"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/xid"
+	"github.com/rs/zerolog"
+
+	"fliqt/internal/apperror"
+	"fliqt/internal/event"
+	"fliqt/internal/model"
+	"fliqt/internal/repository"
+)
+
+const (
+	WebhookSignatureHeader = "X-FLIQT-Signature"
+	WebhookEventHeader     = "X-FLIQT-Event"
+	WebhookEventIDHeader   = "X-FLIQT-Event-ID"
+	WebhookDeliveryHeader  = "X-FLIQT-Delivery"
+
+	webhookUserAgent       = "fliqt-webhooks/1.0"
+	webhookResponseMaxSize = 4 * 1024
+)
+
+var (
+	ErrInvalidSignature        = errors.New("invalid webhook signature")
+	ErrWebhookDelivery         = errors.New("failed to deliver webhook")
+	ErrWebhookDeliveryScrubbed = apperror.New(http.StatusGone, "webhook_delivery_scrubbed", "the request of the delivery has been scrubbed")
+)
+
+// CreatedWebhook is returned only once on creation, it's the only time the secret is shown.
+type CreatedWebhook struct {
+	model.Webhook
+
+	Secret string `json:"secret"`
+}
+
+type WebhookServiceInterface interface {
+	// CreateWebhook creates a webhook, a secret is generated when it isn't given.
+	CreateWebhook(ctx context.Context, dto repository.CreateWebhookDTO) (*CreatedWebhook, error)
+	// Dispatch delivers an event to every enabled webhook subscribing to it, it fails when any delivery fails
+	// so the event is retried, webhooks which have received it already are skipped.
+	Dispatch(ctx context.Context, e event.Event) error
+	// Redeliver sends the request of a past delivery again.
+	Redeliver(ctx context.Context, webhookID string, deliveryID string) (*model.WebhookDelivery, error)
+}
+
+type WebhookService struct {
+	cfg         *config.Config
+	logger      *zerolog.Logger
+	webhookRepo *repository.WebhookRepository
+	httpClient  *http.Client
+}
+
+func NewWebhookService(
+	cfg *config.Config,
+	logger *zerolog.Logger,
+	webhookRepo *repository.WebhookRepository,
+) *WebhookService {
+	return &WebhookService{
+		cfg,
+		logger,
+		webhookRepo,
+		&http.Client{
+			Timeout: cfg.WebhookTimeout,
+			// Redirects aren't followed, the target URL must be the final one.
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+func (s *WebhookService) CreateWebhook(ctx context.Context, dto repository.CreateWebhookDTO) (*CreatedWebhook, error) {
+	if dto.Secret == "" {
+		secret := make([]byte, 32)
+		if _, err := io.ReadFull(rand.Reader, secret); err != nil {
+			return nil, err
+		}
+		dto.Secret = "whsec_" + hex.EncodeToString(secret)
+	}
+
+	webhook, err := s.webhookRepo.CreateWebhook(ctx, dto)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CreatedWebhook{Webhook: *webhook, Secret: webhook.Secret}, nil
+}
+
+func (s *WebhookService) Dispatch(ctx context.Context, e event.Event) error {
+	webhooks, err := s.webhookRepo.ListEnabledWebhooks(ctx)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for i := range webhooks {
+		webhook := &webhooks[i]
+		if !webhook.Subscribes(e.Type) {
+			continue
+		}
+
+		attempts, succeeded, err := s.webhookRepo.CountDeliveries(ctx, webhook.ID, e.ID)
+		if err != nil {
+			return err
+		}
+		if succeeded {
+			continue
+		}
+
+		delivery := s.deliver(ctx, webhook, e.ID, e.Type, body)
+		delivery.AggregateType, delivery.AggregateID = e.AggregateType, e.AggregateID
+		delivery.Attempt = int(attempts) + 1
+
+		if err := s.webhookRepo.RecordDelivery(ctx, delivery, s.cfg.WebhookMaxFailures); err != nil {
+			return err
+		}
+
+		if !delivery.Succeeded {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%w: %d of the webhooks failed", ErrWebhookDelivery, failed)
+	}
+
+	return nil
+}
+
+func (s *WebhookService) Redeliver(ctx context.Context, webhookID string, deliveryID string) (*model.WebhookDelivery, error) {
+	webhook, err := s.webhookRepo.GetWebhookByID(ctx, webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	previous, err := s.webhookRepo.GetDelivery(ctx, webhookID, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if previous.RequestBody == "" {
+		return nil, ErrWebhookDeliveryScrubbed
+	}
+
+	attempts, _, err := s.webhookRepo.CountDeliveries(ctx, webhook.ID, previous.EventID)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery := s.deliver(ctx, webhook, previous.EventID, previous.EventType, []byte(previous.RequestBody))
+	delivery.AggregateType, delivery.AggregateID = previous.AggregateType, previous.AggregateID
+	delivery.Attempt = int(attempts) + 1
+	delivery.Manual = true
+
+	if err := s.webhookRepo.RecordDelivery(ctx, delivery, s.cfg.WebhookMaxFailures); err != nil {
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+// deliver sends the body to the webhook and records the request and the response, it doesn't save the delivery.
+func (s *WebhookService) deliver(ctx context.Context, webhook *model.Webhook, eventID string, eventType string, body []byte) *model.WebhookDelivery {
+	delivery := &model.WebhookDelivery{
+		WebhookID:   webhook.ID,
+		EventID:     eventID,
+		EventType:   eventType,
+		RequestBody: string(body),
+	}
+	delivery.ID = xid.New().String()
+
+	headers := http.Header{}
+	headers.Set("Content-Type", "application/json")
+	headers.Set("User-Agent", webhookUserAgent)
+	headers.Set(WebhookEventHeader, eventType)
+	headers.Set(WebhookEventIDHeader, eventID)
+	headers.Set(WebhookDeliveryHeader, delivery.ID)
+	headers.Set(WebhookSignatureHeader, SignWebhookPayload(webhook.Secret, time.Now(), body))
+
+	if data, err := json.Marshal(headers); err == nil {
+		delivery.RequestHeaders = string(data)
+	}
+
+	start := time.Now()
+	defer func() {
+		delivery.DurationMs = time.Since(start).Milliseconds()
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+	req.Header = headers
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		delivery.Error = truncate(err.Error(), 1024)
+		return delivery
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseMaxSize))
+	delivery.ResponseStatus = resp.StatusCode
+	delivery.ResponseBody = string(respBody)
+	delivery.Succeeded = resp.StatusCode >= 200 && resp.StatusCode < 300
+
+	if !delivery.Succeeded {
+		delivery.Error = fmt.Sprintf("unexpected status %d", resp.StatusCode)
+	}
+
+	return delivery
+}
+
+// SignWebhookPayload signs the timestamp along with the body, so a captured request can't be replayed later.
+// The header looks like "t=1700000000,v1=<hex HMAC-SHA256 of "<t>.<body>">".
+func SignWebhookPayload(secret string, timestamp time.Time, body []byte) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+	return fmt.Sprintf("t=%s,v1=%s", t, webhookHMAC(secret, t, body))
+}
+
+// VerifyWebhookSignature verifies the signature header of a webhook request, requests signed earlier than
+// tolerance are rejected. Receivers can use it as a reference implementation.
+func VerifyWebhookSignature(secret string, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var t, signature string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			t = value
+		case "v1":
+			signature = value
+		}
+	}
+
+	timestamp, err := strconv.ParseInt(t, 10, 64)
+	if err != nil || signature == "" {
+		return ErrInvalidSignature
+	}
+
+	if age := now.Sub(time.Unix(timestamp, 0)); age > tolerance || age < -tolerance {
+		return ErrInvalidSignature
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(webhookHMAC(secret, t, body))) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+func webhookHMAC(secret string, t string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+
+	return s[:max]
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"fliqt/config"
+	"fliqt/internal/event"
+	"fliqt/internal/model"
+)
+
+func TestWebhookServiceDeliver(t *testing.T) {
+	secret := "whsec_0123456789abcdef"
+	received := make(chan *http.Request, 1)
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := VerifyWebhookSignature(secret, r.Header.Get(WebhookSignatureHeader), body, 5*time.Minute, time.Now()); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		received <- r
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	logger := zerolog.New(zerolog.ConsoleWriter{Out: nil})
+	s := NewWebhookService(&config.Config{WebhookTimeout: time.Second}, &logger, nil)
+
+	webhook := &model.Webhook{URL: receiver.URL, Secret: secret}
+	body := []byte(`{"id":"cqanbg8cvavjpljmh7pg","type":"job.created"}`)
+
+	delivery := s.deliver(context.TODO(), webhook, "cqanbg8cvavjpljmh7pg", event.JobCreated, body)
+	if !delivery.Succeeded || delivery.ResponseStatus != http.StatusNoContent {
+		t.Fatalf("expected a successful delivery, got %+v", delivery)
+	}
+
+	r := <-received
+	if r.Header.Get(WebhookEventHeader) != event.JobCreated || r.Header.Get(WebhookDeliveryHeader) != delivery.ID {
+		t.Errorf("unexpected headers %v", r.Header)
+	}
+
+	// A wrong secret fails the signature check of the receiver.
+	webhook.Secret = "whsec_fedcba9876543210"
+	delivery = s.deliver(context.TODO(), webhook, "cqanbg8cvavjpljmh7pg", event.JobCreated, body)
+	if delivery.Succeeded || delivery.ResponseStatus != http.StatusUnauthorized || delivery.Error == "" {
+		t.Errorf("expected a failed delivery, got %+v", delivery)
+	}
+}
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	body := []byte(`{}`)
+	signedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	header := SignWebhookPayload("secret", signedAt, body)
+
+	if err := VerifyWebhookSignature("secret", header, body, 5*time.Minute, signedAt.Add(time.Minute)); err != nil {
+		t.Errorf("expected nil, got %v", err)
+	}
+
+	if err := VerifyWebhookSignature("secret", header, []byte(`{"tampered":true}`), 5*time.Minute, signedAt); err != ErrInvalidSignature {
+		t.Errorf("expected ErrInvalidSignature for a tampered body, got %v", err)
+	}
+
+	if err := VerifyWebhookSignature("secret", header, body, 5*time.Minute, signedAt.Add(time.Hour)); err != ErrInvalidSignature {
+		t.Errorf("expected ErrInvalidSignature for a replayed request, got %v", err)
+	}
+}
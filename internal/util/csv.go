@@ -0,0 +1,31 @@
+package util
+
+import "strings"
+
+// csvFormulaPrefixes are the first characters which make spreadsheets evaluate a cell as a formula.
+const csvFormulaPrefixes = "=+-@\t\r"
+
+// EscapeCSVCell prefixes a cell which a spreadsheet would evaluate as a formula with an apostrophe, so it's shown
+// as text. Cells which start with an apostrophe are prefixed as well, so UnescapeCSVCell gets them back as is.
+func EscapeCSVCell(cell string) string {
+	if cell != "" && strings.ContainsRune(csvFormulaPrefixes+"'", rune(cell[0])) {
+		return "'" + cell
+	}
+
+	return cell
+}
+
+// EscapeCSVRow escapes every cell of a row with EscapeCSVCell.
+func EscapeCSVRow(row []string) []string {
+	escaped := make([]string, len(row))
+	for i, cell := range row {
+		escaped[i] = EscapeCSVCell(cell)
+	}
+
+	return escaped
+}
+
+// UnescapeCSVCell reverts EscapeCSVCell.
+func UnescapeCSVCell(cell string) string {
+	return strings.TrimPrefix(cell, "'")
+}
@@ -0,0 +1,26 @@
+package util
+
+import "testing"
+
+func TestEscapeCSVCell(t *testing.T) {
+	for cell, expected := range map[string]string{
+		"":                       "",
+		"Jane Doe":               "Jane Doe",
+		`=HYPERLINK("http://x")`: `'=HYPERLINK("http://x")`,
+		"+1":                     "'+1",
+		"-1":                     "'-1",
+		"@SUM(A1)":               "'@SUM(A1)",
+		"\tcell":                 "'\tcell",
+		"\rcell":                 "'\rcell",
+		"'quoted":                "''quoted",
+		"a=b":                    "a=b",
+	} {
+		escaped := EscapeCSVCell(cell)
+		if escaped != expected {
+			t.Errorf("expected %q to be escaped as %q, got %q", cell, expected, escaped)
+		}
+		if unescaped := UnescapeCSVCell(escaped); unescaped != cell {
+			t.Errorf("expected %q to be unescaped as %q, got %q", escaped, cell, unescaped)
+		}
+	}
+}
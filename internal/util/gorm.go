@@ -1,11 +1,15 @@
 package util
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"time"
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/trace"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
@@ -16,6 +20,16 @@ import (
 
 var tracer = otel.Tracer(GetFileNameFromCaller())
 
+var (
+	meter = otel.Meter(GetFileNameFromCaller())
+
+	dbOperationDuration = Must(meter.Float64Histogram(
+		"db.client.operation.duration",
+		metric.WithDescription("Duration of database queries"),
+		metric.WithUnit("s"),
+	))
+)
+
 func beforeQuery(db *gorm.DB) {
 	_, span := tracer.Start(db.Statement.Context, "gorm.query")
 	db.InstanceSet("otel:span", span)
@@ -56,6 +70,71 @@ func endTrace(db *gorm.DB, span trace.Span) {
 	span.End()
 }
 
+func beforeMetrics(db *gorm.DB) {
+	db.InstanceSet("metrics:start", time.Now())
+}
+
+func afterMetrics(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		instance, ok := db.InstanceGet("metrics:start")
+		if !ok {
+			return
+		}
+		start, ok := instance.(time.Time)
+		if !ok {
+			return
+		}
+
+		status := "ok"
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			status = "error"
+		}
+
+		dbOperationDuration.Record(db.Statement.Context, time.Since(start).Seconds(), metric.WithAttributes(
+			attribute.String("db.operation", operation),
+			attribute.String("db.sql.table", db.Statement.Table),
+			attribute.String("status", status),
+		))
+	}
+}
+
+// registerPoolMetrics observes stats of the connection pool on every collection.
+func registerPoolMetrics(sqlDB *sql.DB) error {
+	usage, err := meter.Int64ObservableGauge("db.client.connections.usage",
+		metric.WithDescription("Connections of the pool by state"))
+	if err != nil {
+		return err
+	}
+	maxConns, err := meter.Int64ObservableGauge("db.client.connections.max",
+		metric.WithDescription("Maximum open connections of the pool"))
+	if err != nil {
+		return err
+	}
+	waits, err := meter.Int64ObservableCounter("db.client.connections.waits",
+		metric.WithDescription("Times a connection had to be waited for"))
+	if err != nil {
+		return err
+	}
+	waitTime, err := meter.Float64ObservableCounter("db.client.connections.wait_time",
+		metric.WithDescription("Time spent waiting for connections"),
+		metric.WithUnit("s"))
+	if err != nil {
+		return err
+	}
+
+	_, err = meter.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		stats := sqlDB.Stats()
+		o.ObserveInt64(usage, int64(stats.InUse), metric.WithAttributes(attribute.String("state", "used")))
+		o.ObserveInt64(usage, int64(stats.Idle), metric.WithAttributes(attribute.String("state", "idle")))
+		o.ObserveInt64(maxConns, int64(stats.MaxOpenConnections))
+		o.ObserveInt64(waits, stats.WaitCount)
+		o.ObserveFloat64(waitTime, stats.WaitDuration.Seconds())
+		return nil
+	}, usage, maxConns, waits, waitTime)
+
+	return err
+}
+
 func NewGormDB(cfg *config.Config) (*gorm.DB, error) {
 	db, err := gorm.Open(mysql.Open(cfg.GetDBDSN()), &gorm.Config{})
 	if err != nil {
@@ -77,8 +156,11 @@ func NewGormDB(cfg *config.Config) (*gorm.DB, error) {
 	sqlDB.SetMaxOpenConns(cfg.DBMaxConn)
 	sqlDB.SetConnMaxLifetime(time.Duration(cfg.DBMaxLifeTime))
 
+	db.Callback().Create().Before("gorm:create").Register("sqlCapture:before_query", beforeQuery)
 	db.Callback().Create().After("*").Register("sqlCapture:after_query", afterQuery)
+	db.Callback().Delete().Before("gorm:delete").Register("sqlCapture:before_query", beforeQuery)
 	db.Callback().Delete().After("*").Register("sqlCapture:after_query", afterQuery)
+	db.Callback().Update().Before("gorm:update").Register("sqlCapture:before_query", beforeQuery)
 	db.Callback().Update().After("*").Register("sqlCapture:after_query", afterQuery)
 
 	db.Callback().Query().Before("gorm:query").Register("sqlCapture:before_query", beforeQuery)
@@ -87,5 +169,22 @@ func NewGormDB(cfg *config.Config) (*gorm.DB, error) {
 	db.Callback().Row().Before("gorm:row_query").Register("sqlCapture:before_query", beforeQuery)
 	db.Callback().Row().After("gorm:row_query").Register("sqlCapture:after_query", afterQuery)
 
+	db.Callback().Create().Before("gorm:create").Register("metrics:before_query", beforeMetrics)
+	db.Callback().Create().After("gorm:create").Register("metrics:after_query", afterMetrics("create"))
+	db.Callback().Delete().Before("gorm:delete").Register("metrics:before_query", beforeMetrics)
+	db.Callback().Delete().After("gorm:delete").Register("metrics:after_query", afterMetrics("delete"))
+	db.Callback().Update().Before("gorm:update").Register("metrics:before_query", beforeMetrics)
+	db.Callback().Update().After("gorm:update").Register("metrics:after_query", afterMetrics("update"))
+	db.Callback().Query().Before("gorm:query").Register("metrics:before_query", beforeMetrics)
+	db.Callback().Query().After("gorm:query").Register("metrics:after_query", afterMetrics("select"))
+	db.Callback().Row().Before("gorm:row_query").Register("metrics:before_query", beforeMetrics)
+	db.Callback().Row().After("gorm:row_query").Register("metrics:after_query", afterMetrics("select"))
+	db.Callback().Raw().Before("gorm:raw").Register("metrics:before_query", beforeMetrics)
+	db.Callback().Raw().After("gorm:raw").Register("metrics:after_query", afterMetrics("raw"))
+
+	if err := registerPoolMetrics(sqlDB); err != nil {
+		return nil, err
+	}
+
 	return db, nil
 }
@@ -0,0 +1,62 @@
+package util
+
+import (
+	"context"
+	"fliqt/config"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	otelprometheus "go.opentelemetry.io/otel/exporters/prometheus"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+)
+
+// Must panics on the error of creating an instrument, it's only used by package level instruments.
+func Must[T any](v T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// Set global meter provider. Metrics are exposed to Prometheus by the returned handler, and pushed to
+// the OTLP collector as well when METRICS_OTLP_ENDPOINT is set. The provider has to be shut down on exit.
+func InitMeter(cfg *config.Config) (*sdkmetric.MeterProvider, http.Handler, error) {
+	registry := prometheus.NewRegistry()
+	exporter, err := otelprometheus.New(otelprometheus.WithRegisterer(registry))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	options := []sdkmetric.Option{
+		sdkmetric.WithReader(exporter),
+		sdkmetric.WithResource(resource.NewSchemaless(
+			semconv.ServiceNameKey.String(ServiceName),
+		)),
+	}
+
+	if cfg.MetricsOTLPEndpoint != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		otlpExporter, err := otlpmetricgrpc.New(
+			ctx,
+			otlpmetricgrpc.WithInsecure(),
+			otlpmetricgrpc.WithEndpoint(cfg.MetricsOTLPEndpoint),
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		options = append(options, sdkmetric.WithReader(sdkmetric.NewPeriodicReader(otlpExporter)))
+	}
+
+	appMeterProvider := sdkmetric.NewMeterProvider(options...)
+	otel.SetMeterProvider(appMeterProvider)
+
+	return appMeterProvider, promhttp.HandlerFor(registry, promhttp.HandlerOpts{}), nil
+}
@@ -1,16 +1,97 @@
 package util
 
 import (
+	"context"
+	"errors"
+	"time"
+
 	"github.com/go-redis/redis/v8"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 
 	"fliqt/config"
 )
 
+var redisCommandDuration = Must(meter.Float64Histogram(
+	"redis.client.command.duration",
+	metric.WithDescription("Duration of Redis commands"),
+	metric.WithUnit("s"),
+))
+
+type redisStartKey struct{}
+
+// redisHook traces commands and records their latency, a pipeline is a single span and command.
+type redisHook struct{}
+
+func (redisHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return startRedisCommand(ctx, cmd.Name()), nil
+}
+
+func (redisHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	endRedisCommand(ctx, cmd.Name(), cmd.Err())
+	return nil
+}
+
+func (redisHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return startRedisCommand(ctx, "pipeline"), nil
+}
+
+func (redisHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	var err error
+	for _, cmd := range cmds {
+		if cmdErr := cmd.Err(); cmdErr != nil && !errors.Is(cmdErr, redis.Nil) {
+			err = cmdErr
+			break
+		}
+	}
+	endRedisCommand(ctx, "pipeline", err)
+	return nil
+}
+
+func startRedisCommand(ctx context.Context, command string) context.Context {
+	ctx, _ = tracer.Start(ctx, "redis."+command,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "redis"),
+			attribute.String("db.operation", command),
+		),
+	)
+
+	return context.WithValue(ctx, redisStartKey{}, time.Now())
+}
+
+func endRedisCommand(ctx context.Context, command string, err error) {
+	start, ok := ctx.Value(redisStartKey{}).(time.Time)
+	if !ok {
+		return
+	}
+
+	// A missing key isn't a failure
+	status := "ok"
+	span := trace.SpanFromContext(ctx)
+	if err != nil && !errors.Is(err, redis.Nil) {
+		status = "error"
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+
+	redisCommandDuration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(
+		attribute.String("db.operation", command),
+		attribute.String("status", status),
+	))
+}
+
 func NewClient(cfg *config.Config) (*redis.Client, error) {
 	opt, err := redis.ParseURL(cfg.RedisURL)
 	if err != nil {
 		return nil, err
 	}
 
-	return redis.NewClient(opt), nil
+	client := redis.NewClient(opt)
+	client.AddHook(redisHook{})
+
+	return client, nil
 }
@@ -7,11 +7,12 @@ import (
 	s3config "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/aws/aws-sdk-go-v2/otelaws"
 
 	"fliqt/config"
 )
 
-func NewS3PresignClient(cfg *config.Config) (*s3.PresignClient, error) {
+func NewS3Client(cfg *config.Config) (*s3.Client, error) {
 	creds := credentials.NewStaticCredentialsProvider(
 		cfg.S3Key,
 		cfg.S3Secret,
@@ -29,10 +30,17 @@ func NewS3PresignClient(cfg *config.Config) (*s3.PresignClient, error) {
 		return nil, err
 	}
 
+	// Trace every S3 call, presigning doesn't call S3 so it isn't traced
+	otelaws.AppendMiddlewares(&s3cfg.APIOptions)
+
 	client := s3.NewFromConfig(s3cfg, func(o *s3.Options) {
 		o.UsePathStyle = true
 		o.BaseEndpoint = aws.String(cfg.S3Endpoint)
 	})
 
-	return s3.NewPresignClient(client), nil
+	return client, nil
+}
+
+func NewS3PresignClient(client *s3.Client) *s3.PresignClient {
+	return s3.NewPresignClient(client)
 }
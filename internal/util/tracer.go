@@ -10,6 +10,7 @@ import (
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/resource"
 	tracesdk "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
@@ -17,7 +18,8 @@ import (
 	"google.golang.org/grpc/backoff"
 )
 
-const serviceName = "fliqt_API"
+// ServiceName is the name of the service in traces and metrics
+const ServiceName = "fliqt_API"
 
 func getCallerStack(stack int) (string, string, int, bool) {
 	pc, file, line, ok := runtime.Caller(stack + 1)
@@ -42,6 +44,10 @@ func GetFileNameFromCaller() string {
 		// Workaround for containerized environment, we use "src" as the root package
 		relativeFileStartIdx = strings.LastIndex(file, "src")
 	}
+	if relativeFileStartIdx == -1 {
+		// Neither is in the path when the module lives elsewhere, e.g. running tests without -trimpath
+		return file
+	}
 	relativeFilePath := file[relativeFileStartIdx:]
 
 	return relativeFilePath
@@ -69,8 +75,8 @@ func getRootPackageFromCaller() string {
 	return rootPackage
 }
 
-// Set global http trace provider
-func InitTracer(cfg *config.Config) error {
+// Set global http trace provider, the provider has to be shut down on exit to flush batched spans.
+func InitTracer(cfg *config.Config) (*tracesdk.TracerProvider, error) {
 	ctx := context.Background()
 	ctx, cancel := context.WithTimeout(ctx, time.Second)
 	defer cancel()
@@ -91,20 +97,25 @@ func InitTracer(cfg *config.Config) error {
 		),
 	)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	appTracerProvider := tracesdk.NewTracerProvider(
-		// Set the sampling rate based on the parent span to 100%
-		tracesdk.WithSampler(tracesdk.ParentBased(tracesdk.TraceIDRatioBased(1.0))),
+		// Follow the sampling decision of the parent span, root spans are sampled by TRACER_SAMPLE_RATIO
+		tracesdk.WithSampler(tracesdk.ParentBased(tracesdk.TraceIDRatioBased(cfg.TracerSampleRatio))),
 		// Always be sure to batch in production.
 		tracesdk.WithSpanProcessor(tracesdk.NewBatchSpanProcessor(exporter)),
 		// Record information about this application in an Resource.
 		tracesdk.WithResource(resource.NewSchemaless(
-			semconv.ServiceNameKey.String(serviceName),
+			semconv.ServiceNameKey.String(ServiceName),
 			attribute.String("exporter", getRootPackageFromCaller()),
 		)),
 	)
 	otel.SetTracerProvider(appTracerProvider)
+	// Extract and inject W3C trace context and baggage, so traces continue across services
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
 
-	return nil
+	return appTracerProvider, nil
 }
@@ -0,0 +1,134 @@
+// Package xlsx writes Office Open XML spreadsheets of plain text cells, just enough to hand data to
+// spreadsheet applications without pulling in a full library.
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// ContentType is the media type of XLSX files.
+const ContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// maxSheetNameLength is the longest sheet name spreadsheet applications accept.
+const maxSheetNameLength = 31
+
+var ErrInvalidSheetName = errors.New("invalid sheet name")
+
+// Writer writes a workbook sheet by sheet, the workbook is complete once it's closed.
+type Writer struct {
+	archive *zip.Writer
+	sheets  []string
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{archive: zip.NewWriter(w)}
+}
+
+// AddSheet writes a sheet of rows, every cell is written as text.
+func (w *Writer) AddSheet(name string, rows [][]string) error {
+	if name == "" || utf8.RuneCountInString(name) > maxSheetNameLength || strings.ContainsAny(name, `[]:*?/\`) {
+		return fmt.Errorf("%w: %q", ErrInvalidSheetName, name)
+	}
+
+	w.sheets = append(w.sheets, name)
+	entry, err := w.archive.Create(fmt.Sprintf("xl/worksheets/sheet%d.xml", len(w.sheets)))
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	for i, row := range rows {
+		fmt.Fprintf(&b, `<row r="%d">`, i+1)
+		for j, value := range row {
+			fmt.Fprintf(&b, `<c r="%s%d" t="inlineStr"><is><t xml:space="preserve">`, columnName(j), i+1)
+			writeText(&b, value)
+			b.WriteString(`</t></is></c>`)
+		}
+		b.WriteString(`</row>`)
+	}
+	b.WriteString(`</sheetData></worksheet>`)
+
+	_, err = io.WriteString(entry, b.String())
+	return err
+}
+
+// Close writes the parts which refer to the sheets, it doesn't close the underlying writer.
+func (w *Writer) Close() error {
+	var contentTypes, workbook, relationships strings.Builder
+
+	contentTypes.WriteString(xml.Header)
+	contentTypes.WriteString(`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`)
+
+	workbook.WriteString(xml.Header)
+	workbook.WriteString(`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+
+	relationships.WriteString(xml.Header)
+	relationships.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+
+	for i, name := range w.sheets {
+		fmt.Fprintf(&contentTypes, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i+1)
+
+		workbook.WriteString(`<sheet name="`)
+		writeText(&workbook, name)
+		fmt.Fprintf(&workbook, `" sheetId="%d" r:id="rId%d"/>`, i+1, i+1)
+
+		fmt.Fprintf(&relationships, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+	}
+
+	contentTypes.WriteString(`</Types>`)
+	workbook.WriteString(`</sheets></workbook>`)
+	relationships.WriteString(`</Relationships>`)
+
+	for name, content := range map[string]string{
+		"[Content_Types].xml": contentTypes.String(),
+		"_rels/.rels": xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`,
+		"xl/workbook.xml":            workbook.String(),
+		"xl/_rels/workbook.xml.rels": relationships.String(),
+	} {
+		entry, err := w.archive.Create(name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(entry, content); err != nil {
+			return err
+		}
+	}
+
+	return w.archive.Close()
+}
+
+// columnName returns the name of a zero-based column, e.g. A, Z, AA.
+func columnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+
+	return name
+}
+
+// writeText escapes text for XML, characters which XML doesn't allow are dropped.
+func writeText(b *strings.Builder, text string) {
+	text = strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' || r == '\r' || (r >= 0x20 && r != utf8.RuneError && r != 0xFFFE && r != 0xFFFF) {
+			return r
+		}
+		return -1
+	}, text)
+
+	xml.EscapeText(b, []byte(text))
+}
@@ -0,0 +1,87 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestColumnName(t *testing.T) {
+	for i, expected := range map[int]string{0: "A", 25: "Z", 26: "AA", 51: "AZ", 52: "BA", 701: "ZZ", 702: "AAA"} {
+		if name := columnName(i); name != expected {
+			t.Errorf("Expected column %d to be %s, got %s", i, expected, name)
+		}
+	}
+}
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+
+	if err := w.AddSheet("Applications", [][]string{
+		{"name", "note"},
+		{"Alice <alice@example.com>", "a & b\x00"},
+	}); err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if err := w.AddSheet("Status history", nil); err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Error: %s", err)
+	}
+
+	parts := map[string]string{}
+	for _, file := range archive.File {
+		r, err := file.Open()
+		if err != nil {
+			t.Fatalf("Error: %s", err)
+		}
+		data, _ := io.ReadAll(r)
+		r.Close()
+
+		// Every part must be well-formed XML.
+		decoder := xml.NewDecoder(bytes.NewReader(data))
+		for {
+			if _, err := decoder.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s is malformed: %s", file.Name, err)
+			}
+		}
+		parts[file.Name] = string(data)
+	}
+
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml", "xl/worksheets/sheet2.xml"} {
+		if _, ok := parts[name]; !ok {
+			t.Errorf("Expected part %s", name)
+		}
+	}
+
+	if !strings.Contains(parts["xl/workbook.xml"], `<sheet name="Status history" sheetId="2" r:id="rId2"/>`) {
+		t.Errorf("Expected the second sheet in the workbook, got %s", parts["xl/workbook.xml"])
+	}
+
+	if !strings.Contains(parts["xl/worksheets/sheet1.xml"], `<c r="B2" t="inlineStr"><is><t xml:space="preserve">a &amp; b</t></is></c>`) {
+		t.Errorf("Expected escaped text in B2, got %s", parts["xl/worksheets/sheet1.xml"])
+	}
+}
+
+func TestWriterInvalidSheetName(t *testing.T) {
+	w := NewWriter(io.Discard)
+
+	for _, name := range []string{"", "a/b", "[sheet]", strings.Repeat("a", 32)} {
+		if err := w.AddSheet(name, nil); !errors.Is(err, ErrInvalidSheetName) {
+			t.Errorf("Expected %q to be invalid, got %v", name, err)
+		}
+	}
+}